@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+const defaultMetricsWindow = 30 * 24 * time.Hour
+
+// AppointmentMetricsStore reports scheduled-versus-performed counts by site.
+type AppointmentMetricsStore interface {
+	AppointmentMetrics(ctx context.Context, from, to time.Time) ([]entity.SiteAppointmentMetrics, error)
+}
+
+// WithAppointmentMetrics serves GET /appointments/metrics.
+func WithAppointmentMetrics(s AppointmentMetricsStore) Option {
+	return func(a *API) { a.appointments = s }
+}
+
+// handleAppointmentMetrics counts appointments starting in [from, to), given
+// as dates or RFC 3339 timestamps. It defaults to the 30 days before to,
+// which defaults to now.
+func (a *API) handleAppointmentMetrics(w http.ResponseWriter, r *http.Request) {
+	to, ok := queryTime(r, "to")
+	if !ok {
+		respondJSON(w, http.StatusBadRequest, response{Message: "to must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	from, ok := queryTime(r, "from")
+	if !ok {
+		respondJSON(w, http.StatusBadRequest, response{Message: "from must be a date (YYYY-MM-DD) or RFC 3339 timestamp"})
+		return
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultMetricsWindow)
+	}
+	if !from.Before(to) {
+		respondJSON(w, http.StatusBadRequest, response{Message: "from must be before to"})
+		return
+	}
+	metrics, err := a.appointments.AppointmentMetrics(r.Context(), from, to)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting appointment metrics: %v", err)})
+		return
+	}
+	if metrics == nil {
+		metrics = []entity.SiteAppointmentMetrics{}
+	}
+	respondJSON(w, http.StatusOK, metrics)
+}
+
+// queryTime parses the named query parameter; it's the zero time if absent.
+func queryTime(r *http.Request, name string) (time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAppointmentMetrics struct {
+	from, to time.Time
+}
+
+func (m *mockAppointmentMetrics) AppointmentMetrics(ctx context.Context, from, to time.Time) ([]entity.SiteAppointmentMetrics, error) {
+	m.from, m.to = from, to
+	return []entity.SiteAppointmentMetrics{
+		{Site: "MAIN", Scheduled: 4, Performed: 3, NoShows: 1, PerformedRate: 0.75, NoShowRate: 0.25},
+	}, nil
+}
+
+func TestAppointmentMetrics(t *testing.T) {
+	store := new(mockAppointmentMetrics)
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithAppointmentMetrics(store))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/appointments/metrics?from=2025-04-01&to=2025-05-01T00:00:00Z", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got []entity.SiteAppointmentMetrics
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, 0.25, got[0].NoShowRate)
+	require.Equal(t, time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC), store.from)
+	require.Equal(t, time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC), store.to)
+
+	// from defaults to 30 days before to
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/appointments/metrics?to=2025-05-01", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC), store.from)
+
+	for _, q := range []string{"from=yesterday", "to=05/01/2025", "from=2025-05-01&to=2025-04-01"} {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/appointments/metrics?"+q, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+}
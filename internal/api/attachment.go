@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+)
+
+type AttachmentStore interface {
+	ReportAttachments(ctx context.Context, reportID int64) ([]entity.Attachment, error)
+	ReportAttachment(ctx context.Context, id int64) (entity.Attachment, error)
+}
+
+// WithAttachments lists a report's attachments at GET /reports/{id}/attachments
+// and serves their contents at GET /attachments/{id}.
+func WithAttachments(s AttachmentStore) Option {
+	return func(a *API) { a.attachments = s }
+}
+
+// mimeTypes maps ED-3/RP-4 data subtypes (HL7 table 0291, and what senders
+// use in practice) to media types.
+var mimeTypes = map[string]string{
+	"PDF":          "application/pdf",
+	"RTF":          "application/rtf",
+	"HTML":         "text/html",
+	"XML":          "application/xml",
+	"TEXT":         "text/plain",
+	"PLAIN":        "text/plain",
+	"JPEG":         "image/jpeg",
+	"JPG":          "image/jpeg",
+	"PNG":          "image/png",
+	"GIF":          "image/gif",
+	"TIFF":         "image/tiff",
+	"DICOM":        "application/dicom",
+	"OCTET-STREAM": "application/octet-stream",
+}
+
+// mimeType is the media type for an ED or RP's type of data and subtype,
+// e.g. Application^PDF. Unknown subtypes are application/octet-stream,
+// unless there's no subtype and the data is TEXT.
+func mimeType(typeOfData, subtype string) string {
+	if t, ok := mimeTypes[strings.ToUpper(subtype)]; ok {
+		return t
+	}
+	if subtype == "" && strings.EqualFold(typeOfData, "TEXT") {
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// decodeED decodes an ED's data (ED-5) by its encoding (ED-4, HL7 table
+// 0299). Its \Xhh...\ escapes are replaced first, and base64 and hex have
+// any line breaks they added stripped.
+func decodeED(encoding, data string) ([]byte, error) {
+	data = string(hl7.UnescapeHex(data))
+	switch strings.ToUpper(encoding) {
+	case "BASE64":
+		data = strings.Join(strings.Fields(data), "")
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		}
+		return b, nil
+	case "HEX":
+		return hex.DecodeString(strings.Join(strings.Fields(data), ""))
+	case "A", "":
+		return []byte(data), nil
+	}
+	return nil, errors.New("unknown ED encoding " + strconv.Quote(encoding))
+}
+
+// attachment reads an ED or RP OBX as an attachment. One that doesn't decode
+// is left as text, so nothing is lost.
+func (o Report) attachment() (entity.Attachment, bool) {
+	switch o.ValueType {
+	case entity.EncapsulatedData:
+		data, err := decodeED(o.Encapsulated.Encoding, o.Encapsulated.Data)
+		if err != nil || len(data) == 0 {
+			return entity.Attachment{}, false
+		}
+		return entity.Attachment{
+			ValueType: entity.EncapsulatedData,
+			MIMEType:  mimeType(o.Encapsulated.TypeOfData, o.Encapsulated.Subtype),
+			Size:      int64(len(data)),
+			Data:      data,
+		}, true
+	case entity.ReferencePointer:
+		if o.Pointer.Pointer == "" {
+			return entity.Attachment{}, false
+		}
+		return entity.Attachment{
+			ValueType: entity.ReferencePointer,
+			MIMEType:  mimeType(o.Pointer.TypeOfData, o.Pointer.Subtype),
+			Reference: o.Pointer.Pointer,
+		}, true
+	}
+	return entity.Attachment{}, false
+}
+
+func (a *API) handleListAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	attachments, err := a.attachments.ReportAttachments(r.Context(), id)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error listing attachments: %v", err)})
+		return
+	}
+	if attachments == nil {
+		attachments = []entity.Attachment{}
+	}
+	respondJSON(w, http.StatusOK, attachments)
+}
+
+// handleGetAttachment serves an encapsulated document as a download with
+// its media type. A reference pointer has nothing to serve.
+func (a *API) handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	att, err := a.attachments.ReportAttachment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusNotFound, response{Message: "attachment not found"})
+			return
+		}
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting attachment: %v", err)})
+		return
+	}
+	if att.ValueType == entity.ReferencePointer {
+		respondJSON(w, http.StatusNotFound, response{Message: p.Format("attachment is stored elsewhere: %s", att.Reference)})
+		return
+	}
+	w.Header().Set("Content-Type", att.MIMEType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(att.Data)))
+	w.Header().Set("Content-Disposition", p.Format(`attachment; filename="report-%d-%d"`, att.ReportID, att.Sequence))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(att.ContentHash))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(att.Data)
+}
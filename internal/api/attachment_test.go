@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReport_Attachments(t *testing.T) {
+	pdf := "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"
+	msg := "MSH|^~\\&|PS|MHS|STRIC|MHS|20250501120000||ORU^R01|A1|P|2.3\r" +
+		"OBR|1|A1||CT1^CT\r" +
+		"OBX|1|ED|PDF||PS^Application^PDF^Base64^" + base64.StdEncoding.EncodeToString([]byte(pdf)) + "||||||F|||20250501120000\r" +
+		"OBX|2|TX|&IMP||No acute disease.||||||F|||20250501120000\r" +
+		"OBX|3|ED|RTF||PS^TEXT^RTF^A^{\\E\\rtf1 Normal.}\\X0D0A\\||||||F\r" +
+		"OBX|4|RP|IMG||https://pacs/wado?uid=1.2.3^PACS^IM^JPEG||||||F\r" +
+		"OBX|5|ED|BAD||PS^Application^PDF^Base64^not base64!||||||F"
+	var obx []Report
+	require.NoError(t, hl7.NewDecoder([]byte(msg)).Decode(&obx))
+	report := GetReport(obx)
+
+	require.Equal(t, "No acute disease.", report.Impression)
+	// one that doesn't decode is kept as text
+	require.Equal(t, "No acute disease.\nPS^Application^PDF^Base64^not base64!\n", report.Body)
+	require.Len(t, report.Attachments, 3)
+
+	require.Equal(t, entity.EncapsulatedData, report.Attachments[0].ValueType)
+	require.Equal(t, "application/pdf", report.Attachments[0].MIMEType)
+	require.Equal(t, pdf, string(report.Attachments[0].Data))
+
+	require.Equal(t, "application/rtf", report.Attachments[1].MIMEType)
+	require.Equal(t, "{\\rtf1 Normal.}\r\n", string(report.Attachments[1].Data))
+
+	require.Equal(t, entity.ReferencePointer, report.Attachments[2].ValueType)
+	require.Equal(t, "image/jpeg", report.Attachments[2].MIMEType)
+	require.Equal(t, "https://pacs/wado?uid=1.2.3", report.Attachments[2].Reference)
+	require.Empty(t, report.Attachments[2].Data)
+}
+
+func TestGetReport_HexEscapeInText(t *testing.T) {
+	// \X..\ is only decoded in ED data: in text it would be Latin-1 or a NUL,
+	// which Postgres won't store
+	msg := "MSH|^~\\&|PS|MHS|STRIC|MHS|20250501120000||ORU^R01|A1|P|2.3\r" +
+		"OBR|1|A1||CT1^CT\r" +
+		"OBX|1|TX|&IMP||Caf\\XE9\\ sign.\\X00\\||||||F\r" +
+		"OBX|2|ED|RTF||PS^TEXT^RTF^A^caf\\XC3A9\\||||||F"
+	var obx []Report
+	require.NoError(t, hl7.NewDecoder([]byte(msg)).Decode(&obx))
+	report := GetReport(obx)
+
+	require.Equal(t, "Caf\\XE9\\ sign.\\X00\\", report.Impression)
+	require.True(t, utf8.ValidString(report.Body))
+	require.NotContains(t, report.Body, "\x00")
+	require.Len(t, report.Attachments, 1)
+	require.Equal(t, "caf\u00e9", string(report.Attachments[0].Data))
+}
+
+func TestDecodeED(t *testing.T) {
+	b, err := decodeED("Base64", "Tm9y\r\nbWFs")
+	require.NoError(t, err)
+	require.Equal(t, "Normal", string(b))
+	b, err = decodeED("Base64", "Tm9ybWFsLg")
+	require.NoError(t, err)
+	require.Equal(t, "Normal.", string(b))
+	b, err = decodeED("Hex", "4E6F726D616C")
+	require.NoError(t, err)
+	require.Equal(t, "Normal", string(b))
+	_, err = decodeED("UU", "begin 644")
+	require.Error(t, err)
+
+	require.Equal(t, "application/pdf", mimeType("Application", "pdf"))
+	require.Equal(t, "text/plain", mimeType("TEXT", ""))
+	require.Equal(t, "application/octet-stream", mimeType("AP", "x-unknown"))
+}
+
+type mockAttachments map[int64]entity.Attachment
+
+func (m mockAttachments) ReportAttachments(ctx context.Context, reportID int64) ([]entity.Attachment, error) {
+	var out []entity.Attachment
+	for _, a := range m {
+		if a.ReportID == reportID {
+			a.Data = nil
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (m mockAttachments) ReportAttachment(ctx context.Context, id int64) (entity.Attachment, error) {
+	a, ok := m[id]
+	if !ok {
+		return entity.Attachment{}, pgx.ErrNoRows
+	}
+	return a, nil
+}
+
+func TestAttachments(t *testing.T) {
+	attachments := mockAttachments{
+		1: {ID: 1, ReportID: 7, Sequence: 1, ValueType: "ED", MIMEType: "application/pdf", Size: 8, ContentHash: "abc", Data: []byte("%PDF-1.4")},
+		2: {ID: 2, ReportID: 8, Sequence: 1, ValueType: "RP", MIMEType: "image/jpeg", Reference: "https://pacs/wado?uid=1.2.3"},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithAttachments(attachments))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/attachments/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="report-7-1"`, w.Header().Get("Content-Disposition"))
+	require.Equal(t, `"abc"`, w.Header().Get("ETag"))
+	require.Equal(t, "%PDF-1.4", w.Body.String())
+
+	for target, code := range map[string]int{
+		"/attachments/2": http.StatusNotFound,
+		"/attachments/3": http.StatusNotFound,
+		"/attachments/x": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, code, w.Code, target)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/7/attachments", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got []entity.Attachment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, "application/pdf", got[0].MIMEType)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/9/attachments", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, "[]", w.Body.String())
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/auth"
+)
+
+type TokenVerifier interface {
+	Verify(context.Context, string) (*auth.Claims, error)
+}
+
+// claimsKey is the context key for the claims of a request's verified token.
+type claimsKey struct{}
+
+// requirePushAuth rejects a request without a valid bearer token, if a
+// verifier is set, and passes the token's claims on in the context.
+func (a *API) requirePushAuth(next http.HandlerFunc) http.HandlerFunc {
+	if a.verifier == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondJSON(w, http.StatusUnauthorized, response{Message: "missing bearer token"})
+			return
+		}
+		claims, err := a.verifier.Verify(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondJSON(w, http.StatusUnauthorized, response{Message: "invalid bearer token"})
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// verifiedIdentity is who the request's verified token was issued to, its
+// email or else its subject, or "" if the request wasn't verified.
+func verifiedIdentity(ctx context.Context) string {
+	claims, ok := ctx.Value(claimsKey{}).(*auth.Claims)
+	if !ok || claims == nil {
+		return ""
+	}
+	return p.Coalesce(claims.Email, claims.Subject)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/pkg/hl7"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultBatchWorkers = 8
+	maxBatchItems       = 1000
+	maxBatchBytes       = 32 << 20
+)
+
+// WithBatchWorkers bounds how many messages from one POST /batch request are
+// processed at once.
+func WithBatchWorkers(n int) Option {
+	return func(a *API) { a.batchWorkers = n }
+}
+
+// batchItem is either a Healthcare API store path or a raw HL7 message. In
+// JSON it's a bare string (a store path) or {"path": ...} / {"hl7": ...}.
+type batchItem struct {
+	Path string `json:"path,omitempty"`
+	HL7  string `json:"hl7,omitempty"`
+}
+
+func (b *batchItem) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &b.Path)
+	}
+	type plain batchItem
+	if err := json.Unmarshal(data, (*plain)(b)); err != nil {
+		return err
+	}
+	if (b.Path == "") == (b.HL7 == "") {
+		return fmt.Errorf("batch item needs exactly one of path or hl7")
+	}
+	return nil
+}
+
+type batchResult struct {
+	Index  int `json:"index"`
+	Status int `json:"status"`
+	response
+}
+
+type batchResponse struct {
+	Saved    int           `json:"saved"`
+	Rejected int           `json:"rejected"`
+	Failed   int           `json:"failed"`
+	Spooled  int           `json:"spooled,omitempty"`
+	Results  []batchResult `json:"results"`
+}
+
+func (a *API) handleBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	if err != nil {
+		respondJSON(w, http.StatusRequestEntityTooLarge, response{Message: p.Format("error reading request body: %v", err)})
+		return
+	}
+	items, err := parseBatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, response{Message: err.Error()})
+		return
+	}
+	if len(items) == 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "empty batch"})
+		return
+	}
+	if len(items) > maxBatchItems {
+		respondJSON(w, http.StatusRequestEntityTooLarge, response{Message: p.Format("batch has %d messages; the limit is %d", len(items), maxBatchItems)})
+		return
+	}
+
+	workers := a.batchWorkers
+	if workers <= 0 {
+		workers = defaultBatchWorkers
+	}
+	results := make([]batchResult, len(items))
+	ctx := r.Context()
+	var g errgroup.Group
+	g.SetLimit(workers)
+	for i, item := range items {
+		g.Go(func() error {
+			d := &delivery{}
+			resp := response{HL7Path: item.Path}
+			var raw []byte
+			if item.Path != "" {
+				d.keys = append(d.keys, "resource:"+item.Path)
+			} else {
+				raw = []byte(item.HL7)
+			}
+			o := a.process(ctx, d, resp, raw)
+			results[i] = batchResult{Index: i, Status: o.code, response: o.resp}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	out := batchResponse{Results: results}
+	for _, res := range results {
+		switch {
+		case res.Message == msgSpooled:
+			out.Spooled++
+		case res.Status < 300 && res.ErrorCategory == "":
+			out.Saved++
+		case ErrorCategory(res.ErrorCategory).Permanent():
+			out.Rejected++
+		default:
+			out.Failed++
+		}
+	}
+	respondJSON(w, http.StatusOK, out)
+}
+
+// parseBatch reads a JSON array, newline-delimited JSON, or an HL7 batch
+// file, going by Content-Type and falling back to sniffing the body.
+func parseBatch(contentType string, body []byte) ([]batchItem, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case mediaType == "application/json",
+		mediaType == "" && bytes.HasPrefix(trimmed, []byte("[")):
+		var items []batchItem
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON batch: %v", err)
+		}
+		return items, nil
+	case mediaType == "application/x-ndjson", mediaType == "application/jsonl",
+		mediaType == "" && (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte(`"`))):
+		var items []batchItem
+		sc := bufio.NewScanner(bytes.NewReader(trimmed))
+		sc.Buffer(nil, maxBatchBytes)
+		for line := 1; sc.Scan(); line++ {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			var item batchItem
+			if err := json.Unmarshal(sc.Bytes(), &item); err != nil {
+				return nil, fmt.Errorf("invalid NDJSON on line %d: %v", line, err)
+			}
+			items = append(items, item)
+		}
+		return items, sc.Err()
+	default:
+		msgs, err := hl7.SplitBatch(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HL7 batch: %v", err)
+		}
+		items := make([]batchItem, len(msgs))
+		for i, m := range msgs {
+			items[i] = batchItem{HL7: string(m)}
+		}
+		return items, nil
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	json "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func postBatch(t *testing.T, h http.Handler, contentType, body string) (int, batchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var out batchResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	}
+	return w.Code, out
+}
+
+func TestHandleBatch(t *testing.T) {
+	handler := New(new(mockHL7Store), &mockHealthcareClient{message: mockORM}, false, WithBatchWorkers(2))
+	oru := strings.Replace(string(mockORM), "ORM^R01|MSGID123", "ORU^R01|MSGID999", 1)
+	unsupported := strings.Replace(string(mockORM), "ORM^R01|MSGID123", "MFN^M02|MSGID456", 1)
+
+	t.Run("json paths", func(t *testing.T) {
+		code, out := postBatch(t, handler, "application/json", `["path/1.hl7", {"path": "path/2.hl7"}]`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, 2, out.Saved)
+		require.Equal(t, "path/2.hl7", out.Results[1].HL7Path)
+		require.Equal(t, "MSGID123", out.Results[1].ControlID)
+		require.Equal(t, http.StatusCreated, out.Results[1].Status)
+	})
+
+	t.Run("ndjson raw", func(t *testing.T) {
+		lines := []string{}
+		for _, m := range []string{string(mockORM), unsupported} {
+			b, err := json.Marshal(map[string]string{"hl7": m})
+			require.NoError(t, err)
+			lines = append(lines, string(b))
+		}
+		code, out := postBatch(t, handler, "application/x-ndjson", strings.Join(lines, "\n"))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, 1, out.Saved)
+		require.Equal(t, 1, out.Rejected)
+		require.Equal(t, string(CategoryUnsupportedType), out.Results[1].ErrorCategory)
+		require.Equal(t, "MSGID456", out.Results[1].ControlID)
+	})
+
+	t.Run("hl7 batch file", func(t *testing.T) {
+		body := "FHS|^~\\&\nBHS|^~\\&\n" + strings.ReplaceAll(string(mockORM), "\r", "\n") + "\n" + strings.ReplaceAll(oru, "\r", "\n") + "\nBTS|2\nFTS|1\n"
+		code, out := postBatch(t, handler, HL7ContentType, body)
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, out.Results, 2)
+		require.Equal(t, "MSGID999", out.Results[1].ControlID)
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		code, _ := postBatch(t, handler, "application/json", `[{"path": "a", "hl7": "b"}]`)
+		require.Equal(t, http.StatusBadRequest, code)
+		code, _ = postBatch(t, handler, "application/json", `[]`)
+		require.Equal(t, http.StatusBadRequest, code)
+		code, _ = postBatch(t, handler, "", "PID|1\rMSH|^~\\&")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
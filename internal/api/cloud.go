@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"slices"
+	"time"
 
 	json "github.com/json-iterator/go"
 	"google.golang.org/api/healthcare/v1"
@@ -18,8 +19,11 @@ type pubSubMessage struct {
 }
 
 type message struct {
-	Data       []byte     `json:"data,omitempty"`
-	Attributes attributes `json:"attributes"`
+	ID          string     `json:"messageId,omitempty"`
+	PublishTime time.Time  `json:"publishTime"`
+	OrderingKey string     `json:"orderingKey,omitempty"`
+	Data        []byte     `json:"data,omitempty"`
+	Attributes  attributes `json:"attributes"`
 }
 
 type attributes struct {
@@ -40,7 +44,7 @@ func NewPubSubMessage(body io.Reader) (*pubSubMessage, error) {
 	if len(m.Message.Data) == 0 {
 		return nil, fmt.Errorf("empty message data")
 	}
-	if !slices.Contains([]string{"ORM", "ORU", "ADT"}, m.Message.Attributes.Type) {
+	if !slices.Contains([]string{"ORM", "ORU", "ADT", "SIU", "OMI", "MDM"}, m.Message.Attributes.Type) {
 		return nil, fmt.Errorf("invalid message type; %s", m.Message.Attributes.Type)
 	}
 
@@ -67,3 +71,28 @@ func (h *Hl7Client) GetHL7V2Message(messagePath string) ([]byte, error) {
 
 	return base64.StdEncoding.DecodeString(msg.Data)
 }
+
+// ListHL7V2Messages returns one page of message resource names from an HL7v2
+// store, oldest first. filter uses the Healthcare API's messages.list syntax.
+func (h *Hl7Client) ListHL7V2Messages(ctx context.Context, storePath, filter, pageToken string, pageSize int64) ([]string, string, error) {
+	messagesSvc := h.Projects.Locations.Datasets.Hl7V2Stores.Messages
+	call := messagesSvc.List(storePath).OrderBy("sendTime").Context(ctx)
+	if filter != "" {
+		call = call.Filter(filter)
+	}
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	if pageSize > 0 {
+		call = call.PageSize(pageSize)
+	}
+	resp, err := call.Do()
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing HL7 messages: %w", err)
+	}
+	names := make([]string, len(resp.Hl7V2Messages))
+	for i, m := range resp.Hl7V2Messages {
+		names[i] = m.Name
+	}
+	return names, resp.NextPageToken, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+const defaultCriticalPageSize = 100
+
+// CriticalResultStore lists critical results and records their
+// acknowledgment.
+type CriticalResultStore interface {
+	ListCriticalResults(ctx context.Context, status string, cursorID int64, limit int32) ([]entity.CriticalResult, error)
+	AcknowledgeCriticalResult(ctx context.Context, id int64, by string) (entity.CriticalResult, error)
+}
+
+// WithCriticalResults serves GET /critical-results and
+// POST /critical-results/{id}/ack.
+func WithCriticalResults(s CriticalResultStore) Option {
+	return func(a *API) { a.criticalResults = s }
+}
+
+func (a *API) handleListCriticalResults(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "open" && status != "acknowledged" {
+		respondJSON(w, http.StatusBadRequest, response{Message: "status must be open or acknowledged"})
+		return
+	}
+	cursorID, limit, errMsg := pageParams(r, defaultCriticalPageSize)
+	if errMsg != "" {
+		respondJSON(w, http.StatusBadRequest, response{Message: errMsg})
+		return
+	}
+	results, err := a.criticalResults.ListCriticalResults(r.Context(), status, cursorID, limit)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error listing critical results: %v", err)})
+		return
+	}
+	if results == nil {
+		results = []entity.CriticalResult{}
+	}
+	respondJSON(w, http.StatusOK, results)
+}
+
+type acknowledgeRequest struct {
+	AcknowledgedBy string `json:"acknowledged_by"`
+}
+
+// handleAcknowledgeCriticalResult records who acknowledged a critical
+// result: whoever the request's token was issued to, or, if tokens aren't
+// verified, acknowledged_by from the body. Acknowledging it again is a
+// conflict, so the first acknowledgment is the one kept.
+func (a *API) handleAcknowledgeCriticalResult(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	by := verifiedIdentity(r.Context())
+	if by == "" {
+		var req acknowledgeRequest
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<16))
+		if err == nil {
+			err = json.Unmarshal(body, &req)
+		}
+		if err != nil {
+			respondJSON(w, http.StatusBadRequest, response{Message: p.Format("could not decode request body: %v", err)})
+			return
+		}
+		by = strings.TrimSpace(req.AcknowledgedBy)
+		if by == "" {
+			respondJSON(w, http.StatusBadRequest, response{Message: "acknowledged_by is required"})
+			return
+		}
+	}
+	c, err := a.criticalResults.AcknowledgeCriticalResult(r.Context(), id, by)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		respondJSON(w, http.StatusNotFound, response{Message: "critical result not found"})
+	case errors.Is(err, entity.ErrAlreadyAcknowledged):
+		respondJSON(w, http.StatusConflict, response{Message: p.Format("critical result already acknowledged by %s", c.AcknowledgedBy)})
+	case err != nil:
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error acknowledging critical result: %v", err)})
+	default:
+		respondJSON(w, http.StatusOK, c)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReport_AbnormalFlags(t *testing.T) {
+	msg := "MSH|^~\\&|PS|MHS|STRIC|MHS|20250501120000||ORU^R01|A1|P|2.3\r" +
+		"OBR|1|A1||CT1^CT\r" +
+		"OBX|1|TX|&IMP||Large right pneumothorax.|||AA~A|||F|||20250501120000"
+	var obx []Report
+	require.NoError(t, hl7.NewDecoder([]byte(msg)).Decode(&obx))
+	report := GetReport(obx)
+	require.Len(t, report.Lines, 1)
+	require.Equal(t, []string{"AA", "A"}, report.Lines[0].AbnormalFlags)
+}
+
+type mockCriticalResults map[int64]entity.CriticalResult
+
+func (m mockCriticalResults) ListCriticalResults(ctx context.Context, status string, cursorID int64, limit int32) ([]entity.CriticalResult, error) {
+	var out []entity.CriticalResult
+	for id := cursorID + 1; id <= cursorID+int64(limit); id++ {
+		c, ok := m[id]
+		if !ok || (status == "open" && c.AcknowledgedAt != nil) || (status == "acknowledged" && c.AcknowledgedAt == nil) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (m mockCriticalResults) AcknowledgeCriticalResult(ctx context.Context, id int64, by string) (entity.CriticalResult, error) {
+	c, ok := m[id]
+	switch {
+	case !ok:
+		return c, pgx.ErrNoRows
+	case c.AcknowledgedAt != nil:
+		return c, entity.ErrAlreadyAcknowledged
+	}
+	c.AcknowledgedAt, c.AcknowledgedBy = new(time.Time), by
+	m[id] = c
+	return c, nil
+}
+
+func TestCriticalResults(t *testing.T) {
+	results := mockCriticalResults{
+		1: {ID: 1, ExamID: 7, Accession: "A1", Rule: "pneumothorax", Matched: "Large right pneumothorax."},
+		2: {ID: 2, ExamID: 8, Accession: "A2", Rule: "free_air", AcknowledgedAt: new(time.Time), AcknowledgedBy: "dr.smith"},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithCriticalResults(results))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/critical-results?status=open", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got []entity.CriticalResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, "pneumothorax", got[0].Rule)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/critical-results?status=closed", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	tests := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/critical-results/1/ack", `{}`, http.StatusBadRequest},
+		{"/critical-results/1/ack", ``, http.StatusBadRequest},
+		{"/critical-results/x/ack", `{"acknowledged_by": "dr.jones"}`, http.StatusBadRequest},
+		{"/critical-results/3/ack", `{"acknowledged_by": "dr.jones"}`, http.StatusNotFound},
+		{"/critical-results/1/ack", `{"acknowledged_by": "dr.jones"}`, http.StatusOK},
+		{"/critical-results/1/ack", `{"acknowledged_by": "dr.lee"}`, http.StatusConflict},
+		{"/critical-results/2/ack", `{"acknowledged_by": "dr.jones"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		require.Equal(t, tt.want, w.Code, tt.path+" "+tt.body)
+	}
+	require.Equal(t, "dr.jones", results[1].AcknowledgedBy)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/critical-results?status=acknowledged&cursor_id=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, "dr.smith", got[0].AcknowledgedBy)
+}
+
+func TestCriticalResults_AckAuth(t *testing.T) {
+	results := mockCriticalResults{
+		1: {ID: 1, ExamID: 7, Accession: "A1", Rule: "pneumothorax"},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false,
+		WithCriticalResults(results),
+		WithVerifier(&mockVerifier{token: "good"}),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/critical-results/1/ack", strings.NewReader(`{"acknowledged_by": "dr.jones"}`)))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Empty(t, results[1].AcknowledgedBy)
+
+	// the token says who acknowledged it, whatever the body claims
+	req := httptest.NewRequest(http.MethodPost, "/critical-results/1/ack", strings.NewReader(`{"acknowledged_by": "dr.jones"}`))
+	req.Header.Set("Authorization", "Bearer good")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "push@example.com", results[1].AcknowledgedBy)
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+const defaultFailedPageSize = 100
+
+var ErrNotPending = errors.New("failed message is not pending")
+
+type DeadLetterStore interface {
+	RecordFailure(context.Context, entity.FailedMessage) (int64, error)
+	GetFailedMessage(context.Context, int64) (entity.FailedMessage, error)
+	ListFailedMessages(ctx context.Context, status string, cursorID int64, limit int32) ([]entity.FailedMessage, error)
+	SetFailedMessageStatus(ctx context.Context, id int64, status string) error
+	DiscardFailedMessage(ctx context.Context, id int64) (bool, error)
+	ResolveFailedMessage(ctx context.Context, raw []byte) (bool, error)
+}
+
+// WithDeadLetters records messages that fail to save and exposes the admin
+// endpoints for inspecting, replaying and discarding them.
+func WithDeadLetters(dl DeadLetterStore) Option {
+	return func(a *API) { a.deadLetters = dl }
+}
+
+func (a *API) registerDeadLetterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/failed-messages", a.handleListFailed)
+	mux.HandleFunc("GET /admin/failed-messages/{id}", a.handleGetFailed)
+	mux.HandleFunc("POST /admin/failed-messages/{id}/replay", a.requirePushAuth(a.handleReplayFailed))
+	mux.HandleFunc("DELETE /admin/failed-messages/{id}", a.requirePushAuth(a.handleDiscardFailed))
+}
+
+// recordFailure dead-letters raw so it isn't lost once the push is acked (or
+// redelivery gives up). Errors here are only logged; the original failure is
+// what the caller reports.
+func (a *API) recordFailure(ctx context.Context, storePath, controlID string, raw []byte, err error) {
+	if a.deadLetters == nil {
+		return
+	}
+	_, dlErr := a.deadLetters.RecordFailure(ctx, entity.FailedMessage{
+		StorePath:     storePath,
+		Raw:           raw,
+		ControlID:     controlID,
+		ErrorCategory: string(Classify(err)),
+		ErrorMessage:  err.Error(),
+	})
+	if dlErr != nil {
+		log.Error().Err(dlErr).Str("hl7_path", storePath).Str("control_id", controlID).Msg("couldn't record failed message")
+	}
+}
+
+// resolveFailure marks raw's pending dead-letter entry replayed once a
+// redelivery has saved it, so that a failure Pub/Sub retried on its own isn't
+// left pending and replayed again. Errors here are only logged.
+func (a *API) resolveFailure(ctx context.Context, storePath string, raw []byte) {
+	if a.deadLetters == nil {
+		return
+	}
+	resolved, err := a.deadLetters.ResolveFailedMessage(ctx, raw)
+	if err != nil {
+		log.Error().Err(err).Str("hl7_path", storePath).Msg("couldn't resolve failed message")
+		return
+	}
+	if resolved {
+		log.Info().Str("hl7_path", storePath).Msg("failed message saved on redelivery")
+	}
+}
+
+// Replay runs a dead-lettered message through the handlers again. On success
+// the entry is marked replayed; on failure its attempt count and last error
+// are updated and the error is returned.
+func Replay(ctx context.Context, store HL7Store, dl DeadLetterStore, id int64) (entity.FailedMessage, error) {
+	f, err := dl.GetFailedMessage(ctx, id)
+	if err != nil {
+		return f, err
+	}
+	if f.Status != entity.FailedPending {
+		return f, fmt.Errorf("%w: %d is %s", ErrNotPending, id, f.Status)
+	}
+	if _, _, err := HandleByMsgType(store, f.Raw); err != nil {
+		f.ErrorCategory = string(Classify(err))
+		f.ErrorMessage = err.Error()
+		f.Attempts++
+		if _, dlErr := dl.RecordFailure(ctx, f); dlErr != nil {
+			return f, errors.Join(err, dlErr)
+		}
+		return f, err
+	}
+	if err := dl.SetFailedMessageStatus(ctx, id, entity.FailedReplayed); err != nil {
+		return f, err
+	}
+	f.Status = entity.FailedReplayed
+	return f, nil
+}
+
+type failedMessageResponse struct {
+	entity.FailedMessage
+	Raw string `json:"raw,omitempty"`
+}
+
+func (a *API) handleListFailed(w http.ResponseWriter, r *http.Request) {
+	resp := response{}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = entity.FailedPending
+	}
+	cursorID, limit, errMsg := pageParams(r, defaultFailedPageSize)
+	if errMsg != "" {
+		resp.Message = errMsg
+		respondJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	msgs, err := a.deadLetters.ListFailedMessages(r.Context(), status, cursorID, limit)
+	if err != nil {
+		resp.Message = p.Format("error listing failed messages: %v", err)
+		respondJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	if msgs == nil {
+		msgs = []entity.FailedMessage{}
+	}
+	respondJSON(w, http.StatusOK, msgs)
+}
+
+// pageParams reads the cursor_id and limit query params used for keyset
+// paging. errMsg is set if either is invalid.
+func pageParams(r *http.Request, defaultLimit int32) (cursorID int64, limit int32, errMsg string) {
+	if c := r.URL.Query().Get("cursor_id"); c != "" {
+		id, err := strconv.ParseInt(c, 10, 64)
+		if err != nil || id < 0 {
+			return 0, 0, "cursor_id must be a non-negative integer"
+		}
+		cursorID = id
+	}
+	limit = defaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.ParseInt(l, 10, 32)
+		if err != nil || n <= 0 {
+			return 0, 0, "limit must be a positive integer"
+		}
+		limit = int32(n)
+	}
+	return cursorID, limit, ""
+}
+
+func (a *API) handleGetFailed(w http.ResponseWriter, r *http.Request) {
+	id, ok := failedID(w, r)
+	if !ok {
+		return
+	}
+	f, err := a.deadLetters.GetFailedMessage(r.Context(), id)
+	if err != nil {
+		respondFailedLookupErr(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, failedMessageResponse{FailedMessage: f, Raw: string(f.Raw)})
+}
+
+func (a *API) handleReplayFailed(w http.ResponseWriter, r *http.Request) {
+	id, ok := failedID(w, r)
+	if !ok {
+		return
+	}
+	f, err := Replay(r.Context(), a.Store, a.deadLetters, id)
+	switch {
+	case err != nil && f.ID == 0:
+		respondFailedLookupErr(w, err)
+	case errors.Is(err, ErrNotPending):
+		respondJSON(w, http.StatusConflict, response{Message: err.Error()})
+	case err != nil:
+		category := Classify(err)
+		respondJSON(w, http.StatusUnprocessableEntity, response{
+			Message:       "replay failed",
+			ControlID:     f.ControlID,
+			VoltaError:    err.Error(),
+			ErrorCategory: string(category),
+		})
+	default:
+		log.Info().Int64("failed_message_id", id).Str("by", verifiedIdentity(r.Context())).Msg("failed message replayed")
+		respondJSON(w, http.StatusOK, f)
+	}
+}
+
+func (a *API) handleDiscardFailed(w http.ResponseWriter, r *http.Request) {
+	id, ok := failedID(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	f, err := a.deadLetters.GetFailedMessage(ctx, id)
+	if err != nil {
+		respondFailedLookupErr(w, err)
+		return
+	}
+	discarded, err := a.deadLetters.DiscardFailedMessage(ctx, id)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error discarding failed message: %v", err)})
+		return
+	}
+	if !discarded {
+		respondJSON(w, http.StatusConflict, response{Message: p.Format("%v: %s", ErrNotPending, f.Status)})
+		return
+	}
+	f.Status = entity.FailedDiscarded
+	log.Info().Int64("failed_message_id", id).Str("by", verifiedIdentity(ctx)).Msg("failed message discarded")
+	respondJSON(w, http.StatusOK, f)
+}
+
+func failedID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondFailedLookupErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		respondJSON(w, http.StatusNotFound, response{Message: "failed message not found"})
+		return
+	}
+	respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting failed message: %v", err)})
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDeadLetters struct {
+	msgs map[int64]*entity.FailedMessage
+}
+
+func newMockDeadLetters() *mockDeadLetters {
+	return &mockDeadLetters{msgs: map[int64]*entity.FailedMessage{}}
+}
+
+func (m *mockDeadLetters) RecordFailure(ctx context.Context, f entity.FailedMessage) (int64, error) {
+	for id, existing := range m.msgs {
+		if existing.Status == entity.FailedPending && string(existing.Raw) == string(f.Raw) {
+			existing.Attempts++
+			existing.ErrorCategory = f.ErrorCategory
+			existing.ErrorMessage = f.ErrorMessage
+			return id, nil
+		}
+	}
+	id := int64(len(m.msgs) + 1)
+	f.ID = int(id)
+	f.Attempts = 1
+	f.Status = entity.FailedPending
+	m.msgs[id] = &f
+	return id, nil
+}
+
+func (m *mockDeadLetters) GetFailedMessage(ctx context.Context, id int64) (entity.FailedMessage, error) {
+	f, ok := m.msgs[id]
+	if !ok {
+		return entity.FailedMessage{}, pgx.ErrNoRows
+	}
+	return *f, nil
+}
+
+func (m *mockDeadLetters) ListFailedMessages(ctx context.Context, status string, cursorID int64, limit int32) ([]entity.FailedMessage, error) {
+	var out []entity.FailedMessage
+	for id := cursorID + 1; id <= int64(len(m.msgs)) && len(out) < int(limit); id++ {
+		if f := m.msgs[id]; f.Status == status {
+			out = append(out, *f)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockDeadLetters) SetFailedMessageStatus(ctx context.Context, id int64, status string) error {
+	f, ok := m.msgs[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	f.Status = status
+	return nil
+}
+
+func (m *mockDeadLetters) DiscardFailedMessage(ctx context.Context, id int64) (bool, error) {
+	f, ok := m.msgs[id]
+	if !ok || f.Status != entity.FailedPending {
+		return false, nil
+	}
+	f.Status = entity.FailedDiscarded
+	return true, nil
+}
+
+func (m *mockDeadLetters) ResolveFailedMessage(ctx context.Context, raw []byte) (bool, error) {
+	for _, f := range m.msgs {
+		if f.Status == entity.FailedPending && string(f.Raw) == string(raw) {
+			f.Status = entity.FailedReplayed
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestDeadLetters_RecordAndReplay(t *testing.T) {
+	store := &mockHL7Store{saveORMErr: entity.ValidationError{Field: "accession", Reason: "missing"}}
+	dl := newMockDeadLetters()
+	handler := New(store, &mockHealthcareClient{message: mockORM}, false, WithDeadLetters(dl))
+
+	push := func() {
+		body, n := newRequestBody(t, "path/to/msg.hl7")
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Length", strconv.Itoa(n))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+	}
+	push()
+	push()
+
+	require.Len(t, dl.msgs, 1)
+	f := dl.msgs[1]
+	require.Equal(t, "path/to/msg.hl7", f.StorePath)
+	require.Equal(t, "MSGID123", f.ControlID)
+	require.Equal(t, string(CategoryValidation), f.ErrorCategory)
+	require.Equal(t, 2, f.Attempts)
+	require.Equal(t, mockORM, f.Raw)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/failed-messages", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []entity.FailedMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/failed-messages/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var shown failedMessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shown))
+	require.Equal(t, string(mockORM), shown.Raw)
+
+	// still broken: replay fails and bumps the attempt count
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/failed-messages/1/replay", nil))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, 3, dl.msgs[1].Attempts)
+
+	// fixed
+	store.saveORMErr = nil
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/failed-messages/1/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, entity.FailedReplayed, dl.msgs[1].Status)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/failed-messages/1/replay", nil))
+	require.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeadLetters_RedeliveryResolves(t *testing.T) {
+	store := &mockHL7Store{saveORMErr: &pgconn.PgError{Code: "40P01"}}
+	dl := newMockDeadLetters()
+	handler := New(store, &mockHealthcareClient{message: mockORM}, false, WithDeadLetters(dl))
+
+	push := func() int {
+		body, n := newRequestBody(t, "path/to/msg.hl7")
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Length", strconv.Itoa(n))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusServiceUnavailable, push())
+	require.Len(t, dl.msgs, 1)
+	require.Equal(t, entity.FailedPending, dl.msgs[1].Status)
+	require.Equal(t, string(CategoryTransientDB), dl.msgs[1].ErrorCategory)
+
+	// Pub/Sub redelivers once the database is back
+	store.saveORMErr = nil
+	require.Equal(t, http.StatusCreated, push())
+	require.Equal(t, entity.FailedReplayed, dl.msgs[1].Status)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/failed-messages", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []entity.FailedMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Empty(t, listed)
+}
+
+func TestDeadLetters_Discard(t *testing.T) {
+	dl := newMockDeadLetters()
+	_, err := dl.RecordFailure(context.Background(), entity.FailedMessage{Raw: mockORM, ErrorMessage: "boom"})
+	require.NoError(t, err)
+	handler := New(new(mockHL7Store), &mockHealthcareClient{}, false, WithDeadLetters(dl))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/failed-messages/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, entity.FailedDiscarded, dl.msgs[1].Status)
+
+	// only a pending message can be discarded
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/failed-messages/1", nil))
+	require.Equal(t, http.StatusConflict, w.Code)
+	_, err = dl.RecordFailure(context.Background(), entity.FailedMessage{Raw: mockORM, ErrorMessage: "boom"})
+	require.NoError(t, err)
+	dl.msgs[2].Status = entity.FailedReplayed
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/failed-messages/2", nil))
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.Equal(t, entity.FailedReplayed, dl.msgs[2].Status)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/failed-messages/3", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/failed-messages/abc", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeadLetters_RoutesDisabledWithoutStore(t *testing.T) {
+	handler := New(new(mockHL7Store), &mockHealthcareClient{err: errors.New("unused")}, false)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/failed-messages", nil))
+	require.NotEqual(t, http.StatusOK, w.Code)
+}
+
+func TestDeadLetters_ReplayAuth(t *testing.T) {
+	dl := newMockDeadLetters()
+	handler := New(new(mockHL7Store), &mockHealthcareClient{message: mockORM}, false,
+		WithDeadLetters(dl),
+		WithVerifier(&mockVerifier{token: "good"}),
+	)
+	_, err := dl.RecordFailure(context.Background(), entity.FailedMessage{StorePath: "path/to/msg.hl7", Raw: mockORM})
+	require.NoError(t, err)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/admin/failed-messages/1/replay", nil),
+		httptest.NewRequest(http.MethodDelete, "/admin/failed-messages/1", nil),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Code, req.Method)
+	}
+	require.Equal(t, entity.FailedPending, dl.msgs[1].Status)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/failed-messages/1/replay", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, entity.FailedReplayed, dl.msgs[1].Status)
+}
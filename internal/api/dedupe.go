@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+)
+
+// DuplicateHeader is set on responses replayed from an earlier delivery.
+const DuplicateHeader = "Volta-Duplicate"
+
+type Deduper interface {
+	LookupProcessed(ctx context.Context, keys []string, window time.Duration) (entity.ProcessedMessage, bool, error)
+	RecordProcessed(ctx context.Context, keys []string, pm entity.ProcessedMessage) error
+}
+
+// WithDeduper skips messages that were already processed within window. A
+// delivery matches an earlier one if it shares the Pub/Sub message ID, the
+// Healthcare API resource name, or the sending application and MSH-10.
+func WithDeduper(d Deduper, window time.Duration) Option {
+	return func(a *API) {
+		a.deduper = d
+		a.dedupeWindow = window
+	}
+}
+
+// delivery collects the dedupe keys for one push request as they become
+// known: the Pub/Sub and resource keys up front, the MSH key after fetching.
+type delivery struct {
+	pubSubID string
+	keys     []string
+}
+
+func newDelivery(m *pubSubMessage, storePath string) *delivery {
+	d := &delivery{pubSubID: m.Message.ID}
+	if m.Message.ID != "" {
+		d.keys = append(d.keys, "pubsub:"+m.Message.ID)
+	}
+	if storePath != "" {
+		d.keys = append(d.keys, "resource:"+storePath)
+	}
+	return d
+}
+
+// addMSHKey adds sending app + MSH-10 if the header can be read; anything
+// else is left for the handlers to reject.
+func (d *delivery) addMSHKey(raw []byte) []string {
+	msh := &Message{}
+	if err := hl7.NewDecoder(raw).Decode(msh); err != nil || msh.ControlID == "" {
+		return nil
+	}
+	key := "msh:" + msh.SendingApp + "|" + msh.ControlID
+	d.keys = append(d.keys, key)
+	return []string{key}
+}
+
+// lookupDuplicate returns the stored outcome if any of keys was already
+// processed. Lookup errors are logged and treated as a miss.
+func (a *API) lookupDuplicate(ctx context.Context, keys []string) (outcome, bool) {
+	if a.deduper == nil || len(keys) == 0 {
+		return outcome{}, false
+	}
+	pm, ok, err := a.deduper.LookupProcessed(ctx, keys, a.dedupeWindow)
+	if err != nil {
+		log.Warn().Err(err).Strs("keys", keys).Msg("couldn't check for duplicate delivery")
+		return outcome{}, false
+	}
+	if !ok {
+		return outcome{}, false
+	}
+	var resp response
+	if err := json.Unmarshal(pm.Response, &resp); err != nil {
+		log.Warn().Err(err).Strs("keys", keys).Msg("couldn't read stored outcome")
+		return outcome{}, false
+	}
+	log.Info().Strs("keys", keys).Str("control_id", pm.ControlID).Msg("skipping duplicate delivery")
+	return outcome{code: pm.StatusCode, resp: resp, duplicate: true}, true
+}
+
+// recordOutcome stores final outcomes (saved or permanently rejected) so
+// redeliveries get the same answer. Transient failures aren't recorded; they
+// need the retry.
+func (a *API) recordOutcome(ctx context.Context, d *delivery, code int, resp response) {
+	final := code == http.StatusCreated || ErrorCategory(resp.ErrorCategory).Permanent()
+	if a.deduper == nil || !final || len(d.keys) == 0 {
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	pm := entity.ProcessedMessage{
+		PubSubID:      d.pubSubID,
+		StorePath:     resp.HL7Path,
+		ControlID:     resp.ControlID,
+		StatusCode:    code,
+		ErrorCategory: resp.ErrorCategory,
+		Response:      body,
+	}
+	if err := a.deduper.RecordProcessed(ctx, d.keys, pm); err != nil {
+		log.Warn().Err(err).Strs("keys", d.keys).Msg("couldn't record processed message")
+	}
+}
+
+// SaveOnce saves raw, fetched from storePath outside a push (by a backfill,
+// say), with the same dedupe as a push: it's skipped if its resource name,
+// or its sending app and MSH-10, was processed within window, and a final
+// outcome is recorded so that a later push of it is skipped too. It returns
+// the control ID and whether raw was skipped. With a nil d, raw is always
+// saved.
+func SaveOnce(ctx context.Context, store HL7Store, d Deduper, window time.Duration, storePath string, raw []byte) (string, bool, error) {
+	a := &API{Store: store, deduper: d, dedupeWindow: window}
+	dl := newDelivery(&pubSubMessage{}, storePath)
+	dl.addMSHKey(raw)
+	if o, ok := a.lookupDuplicate(ctx, dl.keys); ok {
+		return o.resp.ControlID, true, nil
+	}
+
+	controlID, code, err := HandleByMsgType(store, raw)
+	resp := response{Message: "message saved", HL7Path: storePath, HL7Size: len(raw), ControlID: controlID}
+	if err != nil {
+		category := Classify(err)
+		resp.Message = "server error"
+		if category.Permanent() {
+			resp.Message = "message rejected"
+		}
+		resp.VoltaError = err.Error()
+		resp.ErrorCategory = string(category)
+	}
+	a.recordOutcome(ctx, dl, code, resp)
+	return controlID, false, err
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDeduper struct {
+	processed map[string]entity.ProcessedMessage
+	lookups   int
+}
+
+func (m *mockDeduper) LookupProcessed(ctx context.Context, keys []string, window time.Duration) (entity.ProcessedMessage, bool, error) {
+	m.lookups++
+	for _, k := range keys {
+		if pm, ok := m.processed[k]; ok {
+			return pm, true, nil
+		}
+	}
+	return entity.ProcessedMessage{}, false, nil
+}
+
+func (m *mockDeduper) RecordProcessed(ctx context.Context, keys []string, pm entity.ProcessedMessage) error {
+	for _, k := range keys {
+		m.processed[k] = pm
+	}
+	return nil
+}
+
+type countingStore struct {
+	mockHL7Store
+	saves int
+}
+
+func (c *countingStore) SaveORM(ctx context.Context, order *entity.Order) error {
+	c.saves++
+	return c.saveORMErr
+}
+
+func pushRequest(t *testing.T, messageID, path string) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(pubSubMessage{Message: message{
+		ID:         messageID,
+		Data:       []byte(path),
+		Attributes: attributes{Type: "ORM"},
+	}})
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+}
+
+func TestDedupe_Redelivery(t *testing.T) {
+	store := &countingStore{}
+	dd := &mockDeduper{processed: map[string]entity.ProcessedMessage{}}
+	handler := New(store, &mockHealthcareClient{message: mockORM}, false, WithDeduper(dd, time.Hour))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, pushRequest(t, "111", "path/to/msg.hl7"))
+	require.Equal(t, http.StatusCreated, w.Code)
+	first := w.Body.String()
+	require.Equal(t, 1, store.saves)
+	keys := make([]string, 0, len(dd.processed))
+	for k := range dd.processed {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	require.Equal(t, []string{"msh:SendingApp|MSGID123", "pubsub:111", "resource:path/to/msg.hl7"}, keys)
+
+	tests := []struct {
+		name      string
+		messageID string
+		path      string
+	}{
+		{"same pubsub message", "111", "path/to/other.hl7"},
+		{"same resource", "222", "path/to/msg.hl7"},
+		{"same control ID", "333", "path/to/resent.hl7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, pushRequest(t, tt.messageID, tt.path))
+			require.Equal(t, http.StatusCreated, w.Code)
+			require.Equal(t, "true", w.Header().Get(DuplicateHeader))
+			require.Equal(t, first, w.Body.String())
+			require.Equal(t, 1, store.saves)
+		})
+	}
+}
+
+func TestDedupe_TransientNotRecorded(t *testing.T) {
+	store := &countingStore{mockHL7Store: mockHL7Store{saveORMErr: context.DeadlineExceeded}}
+	dd := &mockDeduper{processed: map[string]entity.ProcessedMessage{}}
+	handler := New(store, &mockHealthcareClient{message: mockORM}, false, WithDeduper(dd, time.Hour))
+
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, pushRequest(t, "111", "path/to/msg.hl7"))
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	}
+	require.Equal(t, 2, store.saves)
+	require.Empty(t, dd.processed)
+}
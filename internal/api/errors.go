@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+)
+
+type ErrorCategory string
+
+const (
+	CategoryParse           ErrorCategory = "parse"
+	CategoryValidation      ErrorCategory = "validation"
+	CategoryUnsupportedType ErrorCategory = "unsupported_type"
+	CategoryTransientDB     ErrorCategory = "transient_db"
+	CategoryUpstreamFetch   ErrorCategory = "upstream_fetch"
+	CategoryInternal        ErrorCategory = "internal"
+)
+
+// Permanent categories won't succeed on redelivery, so they are acknowledged
+// rather than handed back to Pub/Sub.
+func (c ErrorCategory) Permanent() bool {
+	switch c {
+	case CategoryParse, CategoryValidation, CategoryUnsupportedType:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status maps a category to the response code for the push request. Pub/Sub
+// treats any 2xx as an ack, so permanent failures return 202 (the error is in
+// the body) and everything else returns a code that triggers redelivery.
+func (c ErrorCategory) Status() int {
+	switch c {
+	case CategoryParse, CategoryValidation, CategoryUnsupportedType:
+		return http.StatusAccepted
+	case CategoryTransientDB:
+		return http.StatusServiceUnavailable
+	case CategoryUpstreamFetch:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error tags err with the category it was found to be in.
+type Error struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func Classify(err error) ErrorCategory {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Category
+	}
+	var parseErr *hl7.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		return CategoryParse
+	case entity.IsTransient(err):
+		return CategoryTransientDB
+	case entity.IsValidation(err):
+		return CategoryValidation
+	default:
+		return CategoryInternal
+	}
+}
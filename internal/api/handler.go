@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
 	"github.com/s-hammon/p"
 	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/internal/keyqueue"
+	"github.com/s-hammon/volta/internal/spool"
 	"github.com/s-hammon/volta/pkg/hl7"
 )
 
@@ -21,6 +26,9 @@ type HealthcareClient interface {
 type HL7Store interface {
 	SaveORM(context.Context, *entity.Order) error
 	SaveORU(context.Context, *entity.Observation) error
+	SaveADT(context.Context, *entity.Admission) error
+	SaveMerge(context.Context, *entity.Merge) error
+	SaveSIU(context.Context, *entity.Appointment) error
 	// TODO: already, I can see how this will get out of hand
 	// think of another design pattern that decouples the storing logic
 	// but still allows us to conveniently add handlers
@@ -32,23 +40,88 @@ type API struct {
 	Store     HL7Store
 	Client    HealthcareClient
 	debugMode bool
+	verifier  TokenVerifier
+
+	deadLetters     DeadLetterStore
+	deduper         Deduper
+	dedupeWindow    time.Duration
+	rawMessages     RawMessageStore
+	staleUpdates    StaleUpdateStore
+	statusHistory   StatusHistoryStore
+	patientMerges   PatientMergeStore
+	appointments    AppointmentMetricsStore
+	reports         ReportHistoryStore
+	attachments     AttachmentStore
+	criticalResults CriticalResultStore
+	batchWorkers    int
+	spool           *spool.Spool
+	ordering        *keyqueue.Queue
+}
+
+type Option func(a *API)
+
+// WithVerifier requires a valid OIDC bearer token on Pub/Sub push requests
+// and on the admin requests that change data: acknowledging a critical
+// result, undoing a patient merge, and replaying or discarding a failed
+// message.
+func WithVerifier(v TokenVerifier) Option {
+	return func(a *API) { a.verifier = v }
 }
 
-func New(store HL7Store, client HealthcareClient, debugMode bool) http.Handler {
+func New(store HL7Store, client HealthcareClient, debugMode bool, opts ...Option) http.Handler {
 	a := &API{
 		Store:     store,
 		Client:    client,
 		debugMode: debugMode,
+		ordering:  keyqueue.New(),
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("POST /", a.handleMessage)
-	mux.HandleFunc("GET /healthz", handleReadiness)
+	mux.HandleFunc("POST /", a.requirePushAuth(a.handleMessage))
+	mux.HandleFunc("POST /batch", a.requirePushAuth(a.handleBatch))
+	mux.HandleFunc("GET /healthz", a.handleReadiness)
+	mux.HandleFunc("GET /metrics", a.handleMetrics)
 
 	mux.HandleFunc("GET /procedure/specialty", a.handleGetProceduresForSpecialtyUpdate)
 	mux.HandleFunc("PUT /procedure", a.handleUpdateProcedureSpecialty)
 
+	if a.deadLetters != nil {
+		a.registerDeadLetterRoutes(mux)
+	}
+	if a.rawMessages != nil {
+		mux.HandleFunc("GET /messages/{id}/raw", a.handleGetRawMessage)
+	}
+	if a.statusHistory != nil {
+		mux.HandleFunc("GET /exams/{id}/status-history", a.handleExamTimeline)
+		mux.HandleFunc("GET /exams/status-history", a.handleExamTimelineByAccession)
+	}
+	if a.staleUpdates != nil {
+		mux.HandleFunc("GET /admin/stale-exam-updates", a.handleListStaleUpdates)
+	}
+	if a.patientMerges != nil {
+		mux.HandleFunc("GET /admin/patient-merges", a.handleListPatientMerges)
+		mux.HandleFunc("POST /admin/patient-merges/{id}/unmerge", a.requirePushAuth(a.handleUnmergePatients))
+	}
+	if a.appointments != nil {
+		mux.HandleFunc("GET /appointments/metrics", a.handleAppointmentMetrics)
+	}
+	if a.reports != nil {
+		mux.HandleFunc("GET /exams/{id}/reports", a.handleReportHistory)
+		mux.HandleFunc("GET /exams/reports", a.handleReportHistoryByAccession)
+	}
+	if a.attachments != nil {
+		mux.HandleFunc("GET /reports/{id}/attachments", a.handleListAttachments)
+		mux.HandleFunc("GET /attachments/{id}", a.handleGetAttachment)
+	}
+	if a.criticalResults != nil {
+		mux.HandleFunc("GET /critical-results", a.handleListCriticalResults)
+		mux.HandleFunc("POST /critical-results/{id}/ack", a.requirePushAuth(a.handleAcknowledgeCriticalResult))
+	}
+
 	return mux
 }
 
@@ -59,6 +132,7 @@ type response struct {
 	HL7Size              int    `json:"hl7_size,omitempty"`
 	ControlID            string `json:"hl7_control_id,omitempty"`
 	VoltaError           string `json:"volta_error,omitempty"`
+	ErrorCategory        string `json:"error_category,omitempty"`
 }
 
 type updateResult struct {
@@ -159,78 +233,211 @@ func (a *API) handleMessage(w http.ResponseWriter, r *http.Request) {
 
 	hl7Path := string(m.Message.Data)
 	resp.HL7Path = hl7Path
-	msg, err := a.Client.GetHL7V2Message(hl7Path)
+	// messages sharing an ordering key are processed one at a time, in the
+	// order they arrived
+	release, err := a.ordering.Acquire(r.Context(), m.Message.OrderingKey)
 	if err != nil {
-		resp.Message = "server error"
-		resp.VoltaError = err.Error()
-		respondJSON(w, http.StatusInternalServerError, resp)
+		resp.Message = "request cancelled while waiting on ordering key"
+		respondJSON(w, http.StatusServiceUnavailable, resp)
 		return
 	}
-	resp.HL7Size = len(msg)
+	defer release()
+	o := a.process(r.Context(), newDelivery(m, hl7Path), resp, nil)
+	if o.duplicate {
+		w.Header().Set(DuplicateHeader, "true")
+	}
+	respondJSON(w, o.code, o.resp)
+}
+
+type outcome struct {
+	code      int
+	resp      response
+	duplicate bool
+}
+
+// process saves one message: fetching it from the Healthcare API unless raw
+// is given, skipping it if already processed, spooling it if the database is
+// down, and dead-lettering failures.
+func (a *API) process(ctx context.Context, d *delivery, resp response, raw []byte) outcome {
+	if !a.debugMode {
+		if o, ok := a.lookupDuplicate(ctx, d.keys); ok {
+			return o
+		}
+	}
+
+	if raw == nil {
+		msg, err := a.Client.GetHL7V2Message(resp.HL7Path)
+		if err != nil {
+			resp.Message = "couldn't fetch message"
+			resp.VoltaError = err.Error()
+			resp.ErrorCategory = string(CategoryUpstreamFetch)
+			return outcome{code: CategoryUpstreamFetch.Status(), resp: resp}
+		}
+		raw = msg
+	}
+	resp.HL7Size = len(raw)
 
 	if a.debugMode {
 		resp.Message = "message received!"
-		respondJSON(w, http.StatusOK, resp)
-		return
+		return outcome{code: http.StatusOK, resp: resp}
+	}
+	if a.deduper != nil {
+		if o, ok := a.lookupDuplicate(ctx, d.addMSHKey(raw)); ok {
+			return o
+		}
 	}
 
-	controlID, code, err := HandleByMsgType(a.Store, msg)
+	// keep arrival order while there's a backlog
+	if a.spool != nil && a.spool.Depth() > 0 {
+		if o, ok := a.spoolMessage(resp, raw); ok {
+			return o
+		}
+	}
+
+	controlID, code, err := HandleByMsgType(a.Store, raw)
+	resp.ControlID = controlID
 	if err != nil {
-		resp.Message = "server error"
+		category := Classify(err)
+		if category == CategoryTransientDB && a.spool != nil {
+			if o, ok := a.spoolMessage(resp, raw); ok {
+				return o
+			}
+		}
 		resp.VoltaError = err.Error()
-	} else if code != http.StatusCreated {
-		resp.Message = "couldn't save message"
+		resp.ErrorCategory = string(category)
+		a.recordFailure(ctx, resp.HL7Path, controlID, raw, err)
+		if category.Permanent() {
+			resp.Message = "message rejected"
+			log.Warn().
+				Err(err).
+				Str("category", string(category)).
+				Str("hl7_path", resp.HL7Path).
+				Str("control_id", controlID).
+				Msg("acknowledging message that can't be saved")
+		} else {
+			resp.Message = "server error"
+		}
 	} else {
 		resp.Message = "message saved"
+		a.resolveFailure(ctx, resp.HL7Path, raw)
 	}
-	resp.ControlID = controlID
-	respondJSON(w, code, resp)
+	a.recordOutcome(ctx, d, code, resp)
+	return outcome{code: code, resp: resp}
 }
 
+// HandleByMsgType decodes data and saves it according to MSH-9. The returned
+// status code follows the error's category (see ErrorCategory.Status).
 func HandleByMsgType(store HL7Store, data []byte) (string, int, error) {
-	var controlID string
+	controlID, err := handleByMsgType(store, data)
+	if err != nil {
+		return controlID, Classify(err).Status(), err
+	}
+	return controlID, http.StatusCreated, nil
+}
+
+func handleByMsgType(store HL7Store, data []byte) (string, error) {
 	msg := &Message{}
 	d := hl7.NewDecoder(data)
 	if err := d.Decode(msg); err != nil {
-		return "", http.StatusInternalServerError, fmt.Errorf("error unmarshaling HL7: %v", err)
+		return "", &Error{CategoryParse, fmt.Errorf("error unmarshaling HL7: %w", err)}
 	}
-	controlID = msg.ControlID
+	controlID := msg.ControlID
 	ctx := context.Background()
 
 	switch msg.MsgType.Name {
-	case "ORM":
+	case "ORM", "OMI":
 		orm := &ORM{}
 		if err := d.Decode(orm); err != nil {
-			return "", http.StatusInternalServerError, fmt.Errorf("error unmarshaling ORM: %v", err)
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling %s: %w", msg.MsgType.Name, err)}
 		}
-		if err := store.SaveORM(ctx, orm.ToOrder()); err != nil {
-			return "", http.StatusInternalServerError, err
+		exams := []Exam{}
+		if err := d.Decode(&exams); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling exams from %s: %w", msg.MsgType.Name, err)}
+		}
+		order := orm.ToOrder(exams...)
+		if msg.MsgType.Name == "OMI" {
+			if err := applyOMI(d, order.Exams); err != nil {
+				return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling TQ1/IPC from OMI: %w", err)}
+			}
 		}
-		return controlID, http.StatusCreated, nil
+		order.Message.Raw = data
+		return controlID, store.SaveORM(ctx, order)
 	case "ORU":
 		oru := &ORU{}
 		if err := d.Decode(oru); err != nil {
-			return "", http.StatusInternalServerError, fmt.Errorf("error unmarshaling ORU: %v", err)
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling ORU: %w", err)}
 		}
 		exams := []Exam{}
 		if err := d.Decode(&exams); err != nil {
-			return "", http.StatusInternalServerError, fmt.Errorf("error unmarshaling exams from ORU: %v", err)
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling exams from ORU: %w", err)}
 		}
 		report := []Report{}
 		if err := d.Decode(&report); err != nil {
-			return "", http.StatusInternalServerError, fmt.Errorf("error unmarshaling report from OBX: %v", err)
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling report from OBX: %w", err)}
 		}
 		obs := oru.ToObservation(GetReport(report), exams...)
-		if err := store.SaveORU(ctx, obs); err != nil {
-			return "", http.StatusInternalServerError, err
+		obs.Message.Raw = data
+		return controlID, store.SaveORU(ctx, obs)
+	case "MDM":
+		if event := msg.MsgType.TriggerEvent; !slices.Contains(mdmEvents, event) {
+			return controlID, &Error{CategoryUnsupportedType, fmt.Errorf("unsupported MDM event: %s", event)}
+		}
+		oru := &ORU{}
+		if err := d.Decode(oru); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling MDM: %w", err)}
+		}
+		exams := []Exam{}
+		if err := d.Decode(&exams); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling exams from MDM: %w", err)}
+		}
+		report := []Report{}
+		if err := d.Decode(&report); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling report from OBX: %w", err)}
 		}
-		return controlID, http.StatusCreated, nil
+		txa := &TXA{}
+		if err := d.Decode(txa); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling TXA: %w", err)}
+		}
+		obs := oru.ToObservation(GetReport(report), exams...)
+		txa.Apply(obs)
+		obs.Message.Raw = data
+		return controlID, store.SaveORU(ctx, obs)
 	case "ADT":
-		return controlID, http.StatusNotImplemented, fmt.Errorf("ADT message type not implemented")
+		event := msg.MsgType.TriggerEvent
+		if !slices.Contains(adtEvents, event) && !slices.Contains(mergeEvents, event) {
+			return controlID, &Error{CategoryUnsupportedType, fmt.Errorf("unsupported ADT event: %s", event)}
+		}
+		adt := &ADT{}
+		if err := d.Decode(adt); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling ADT: %w", err)}
+		}
+		if slices.Contains(mergeEvents, event) {
+			groups := []MergeGroup{}
+			if err := d.Decode(&groups); err != nil {
+				return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling patients from %s: %w", event, err)}
+			}
+			merge := adt.ToMerge(groups...)
+			merge.Message.Raw = data
+			return controlID, store.SaveMerge(ctx, merge)
+		}
+		admission := adt.ToAdmission()
+		admission.Message.Raw = data
+		return controlID, store.SaveADT(ctx, admission)
+	case "SIU":
+		if event := msg.MsgType.TriggerEvent; !slices.Contains(siuEvents, event) {
+			return controlID, &Error{CategoryUnsupportedType, fmt.Errorf("unsupported SIU event: %s", event)}
+		}
+		siu := &SIU{}
+		if err := d.Decode(siu); err != nil {
+			return controlID, &Error{CategoryParse, fmt.Errorf("error unmarshaling SIU: %w", err)}
+		}
+		appt := siu.ToAppointment()
+		appt.Message.Raw = data
+		return controlID, store.SaveSIU(ctx, appt)
 	case "":
-		return controlID, http.StatusBadRequest, fmt.Errorf("MSH.9.1 is blank--is the HL7 formatted correctly?")
+		return controlID, &Error{CategoryParse, fmt.Errorf("MSH.9.1 is blank--is the HL7 formatted correctly?")}
 	default:
-		return controlID, http.StatusBadRequest, fmt.Errorf("unsupported message type: %s", msg.MsgType.Name)
+		return controlID, &Error{CategoryUnsupportedType, fmt.Errorf("unsupported message type: %s", msg.MsgType.Name)}
 	}
 }
 
@@ -13,7 +13,9 @@ import (
 	"testing"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/auth"
 	"github.com/s-hammon/volta/internal/entity"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,6 +24,8 @@ import (
 type mockHL7Store struct {
 	saveORMErr error
 	saveORUErr error
+	saveADTErr error
+	saveSIUErr error
 }
 
 func (m *mockHL7Store) SaveORM(ctx context.Context, order *entity.Order) error {
@@ -32,6 +36,18 @@ func (m *mockHL7Store) SaveORU(ctx context.Context, obs *entity.Observation) err
 	return m.saveORUErr
 }
 
+func (m *mockHL7Store) SaveADT(ctx context.Context, adt *entity.Admission) error {
+	return m.saveADTErr
+}
+
+func (m *mockHL7Store) SaveMerge(ctx context.Context, merge *entity.Merge) error {
+	return m.saveADTErr
+}
+
+func (m *mockHL7Store) SaveSIU(ctx context.Context, siu *entity.Appointment) error {
+	return m.saveSIUErr
+}
+
 func (m *mockHL7Store) GetProcedures(ctx context.Context, cursorID int32) (ret []byte, error error) {
 	// mock no records
 	if cursorID == 100 {
@@ -255,8 +271,45 @@ func TestHandleMEssage_HL7FetchError(t *testing.T) {
 	handler.ServeHTTP(w, req)
 
 	res := w.Result()
-	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	require.Equal(t, http.StatusBadGateway, res.StatusCode)
+}
 
+func TestHandleMessage_ErrorCategories(t *testing.T) {
+	unsupported := []byte("MSH|^~\\&|SendingApp|SendingFac|ReceivingApp|ReceivingFac|202205271230||MFN^M02|MSGID124|P|2.3")
+	tests := []struct {
+		name     string
+		message  []byte
+		saveErr  error
+		wantCode int
+		wantCat  ErrorCategory
+	}{
+		{"unparseable", []byte("MSH|"), nil, http.StatusAccepted, CategoryParse},
+		{"unsupported type", unsupported, nil, http.StatusAccepted, CategoryUnsupportedType},
+		{"unsupported ADT event", []byte("MSH|^~\\&|SendingApp|SendingFac|ReceivingApp|ReceivingFac|202205271230||ADT^A31|MSGID125|P|2.3"), nil, http.StatusAccepted, CategoryUnsupportedType},
+		{"unsupported SIU event", []byte("MSH|^~\\&|SendingApp|SendingFac|ReceivingApp|ReceivingFac|202205271230||SIU^S17|MSGID126|P|2.3"), nil, http.StatusAccepted, CategoryUnsupportedType},
+		{"validation", mockORM, entity.ValidationError{Field: "accession", Reason: "missing"}, http.StatusAccepted, CategoryValidation},
+		{"transient db", mockORM, &pgconn.PgError{Code: "40P01"}, http.StatusServiceUnavailable, CategoryTransientDB},
+		{"unknown", mockORM, errors.New("boom"), http.StatusInternalServerError, CategoryInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := &mockHL7Store{saveORMErr: tt.saveErr}
+			handler := New(mockStore, &mockHealthcareClient{message: tt.message}, false)
+
+			body, n := newRequestBody(t, "path/to/msg.hl7")
+			req := httptest.NewRequest(http.MethodPost, "/", body)
+			req.Header.Set("Content-Length", strconv.Itoa(n))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			res := w.Result()
+			require.Equal(t, tt.wantCode, res.StatusCode)
+			got := &response{}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(got))
+			require.Equal(t, string(tt.wantCat), got.ErrorCategory)
+			require.NotEmpty(t, got.VoltaError)
+		})
+	}
 }
 
 func TestHandleMessage_BadContentLength(t *testing.T) {
@@ -294,6 +347,51 @@ func TestHandleMessage_InvalidMethodsPaths(t *testing.T) {
 	}
 }
 
+type mockVerifier struct {
+	token string
+}
+
+func (m *mockVerifier) Verify(ctx context.Context, token string) (*auth.Claims, error) {
+	if token != m.token {
+		return nil, auth.ErrInvalidSignature
+	}
+	return &auth.Claims{Email: "push@example.com"}, nil
+}
+
+func TestHandleMessage_PushAuth(t *testing.T) {
+	mockStore := new(mockHL7Store)
+	mockClient := mockHealthcareClient{message: mockORM}
+	handler := New(mockStore, &mockClient, false, WithVerifier(&mockVerifier{token: "good"}))
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic good", http.StatusUnauthorized},
+		{"invalid token", "Bearer bad", http.StatusUnauthorized},
+		{"valid token", "Bearer good", http.StatusCreated},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := newRequestBody(t, "path/to/msg.hl7")
+			req := httptest.NewRequest(http.MethodPost, "/", body)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(t, tt.want, w.Result().StatusCode)
+		})
+	}
+
+	// other routes are unaffected
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
 func newRequestBody(t *testing.T, data string) (*bytes.Buffer, int) {
 	t.Helper()
 
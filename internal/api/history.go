@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+type StatusHistoryStore interface {
+	ExamStatusHistory(ctx context.Context, examID int64) ([]entity.StatusChange, error)
+	ExamID(ctx context.Context, sendingApp, accession string) (int64, error)
+}
+
+// WithStatusHistory serves exam status timelines at GET /exams/{id}/status-history
+// and GET /exams/status-history?sending_app=...&accession=....
+func WithStatusHistory(s StatusHistoryStore) Option {
+	return func(a *API) { a.statusHistory = s }
+}
+
+type timelineResponse struct {
+	ExamID   int64                 `json:"exam_id"`
+	Timeline []entity.StatusChange `json:"timeline"`
+}
+
+func (a *API) handleExamTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	a.respondTimeline(w, r, id)
+}
+
+func (a *API) handleExamTimelineByAccession(w http.ResponseWriter, r *http.Request) {
+	sendingApp := r.URL.Query().Get("sending_app")
+	accession := r.URL.Query().Get("accession")
+	if sendingApp == "" || accession == "" {
+		respondJSON(w, http.StatusBadRequest, response{Message: "must provide sending_app and accession params"})
+		return
+	}
+	id, err := a.statusHistory.ExamID(r.Context(), sendingApp, accession)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusNotFound, response{Message: "exam not found"})
+			return
+		}
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting exam: %v", err)})
+		return
+	}
+	a.respondTimeline(w, r, id)
+}
+
+func (a *API) respondTimeline(w http.ResponseWriter, r *http.Request, examID int64) {
+	changes, err := a.statusHistory.ExamStatusHistory(r.Context(), examID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting status history: %v", err)})
+		return
+	}
+	if len(changes) == 0 {
+		respondJSON(w, http.StatusNotFound, response{Message: "no status history for exam"})
+		return
+	}
+	respondJSON(w, http.StatusOK, timelineResponse{ExamID: examID, Timeline: changes})
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStatusHistory map[int64][]entity.StatusChange
+
+func (m mockStatusHistory) ExamStatusHistory(ctx context.Context, examID int64) ([]entity.StatusChange, error) {
+	return m[examID], nil
+}
+
+func (m mockStatusHistory) ExamID(ctx context.Context, sendingApp, accession string) (int64, error) {
+	if sendingApp == "STRIC" && accession == "29737914" {
+		return 1, nil
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func TestExamTimeline(t *testing.T) {
+	event := time.Date(2025, time.April, 4, 15, 9, 51, 0, time.UTC)
+	history := mockStatusHistory{1: {
+		{ID: 1, ExamID: 1, NewStatus: "SC", EventDT: event, SourceApp: "EPIC"},
+		{ID: 2, ExamID: 1, OldStatus: "SC", NewStatus: "IP", EventDT: event.Add(20 * time.Minute), MessageID: 7, SourceApp: "EPIC"},
+	}}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithStatusHistory(history))
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{"by id", "/exams/1/status-history", http.StatusOK},
+		{"by accession", "/exams/status-history?sending_app=STRIC&accession=29737914", http.StatusOK},
+		{"unknown id", "/exams/2/status-history", http.StatusNotFound},
+		{"unknown accession", "/exams/status-history?sending_app=STRIC&accession=1", http.StatusNotFound},
+		{"bad id", "/exams/abc/status-history", http.StatusBadRequest},
+		{"missing params", "/exams/status-history?accession=29737914", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got timelineResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, int64(1), got.ExamID)
+			require.Len(t, got.Timeline, 2)
+			require.Equal(t, "IP", got.Timeline[1].NewStatus)
+			require.Equal(t, "SC", got.Timeline[1].OldStatus)
+		})
+	}
+}
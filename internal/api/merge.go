@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+const defaultMergePageSize = 100
+
+// PatientMergeStore lists applied patient merges and undoes them.
+type PatientMergeStore interface {
+	ListPatientMerges(ctx context.Context, cursorID int64, limit int32) ([]entity.PatientMerge, error)
+	UnmergePatients(ctx context.Context, id int64) (entity.PatientMerge, error)
+}
+
+// WithPatientMerges serves GET /admin/patient-merges and
+// POST /admin/patient-merges/{id}/unmerge.
+func WithPatientMerges(s PatientMergeStore) Option {
+	return func(a *API) { a.patientMerges = s }
+}
+
+func (a *API) handleListPatientMerges(w http.ResponseWriter, r *http.Request) {
+	cursorID, limit, errMsg := pageParams(r, defaultMergePageSize)
+	if errMsg != "" {
+		respondJSON(w, http.StatusBadRequest, response{Message: errMsg})
+		return
+	}
+	merges, err := a.patientMerges.ListPatientMerges(r.Context(), cursorID, limit)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error listing patient merges: %v", err)})
+		return
+	}
+	if merges == nil {
+		merges = []entity.PatientMerge{}
+	}
+	respondJSON(w, http.StatusOK, merges)
+}
+
+func (a *API) handleUnmergePatients(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	m, err := a.patientMerges.UnmergePatients(r.Context(), id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		respondJSON(w, http.StatusNotFound, response{Message: "patient merge not found"})
+	case errors.Is(err, entity.ErrUnmergeUnsafe):
+		respondJSON(w, http.StatusConflict, response{Message: err.Error()})
+	case err != nil:
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error undoing patient merge: %v", err)})
+	default:
+		log.Info().Int64("patient_merge_id", id).Str("by", verifiedIdentity(r.Context())).Msg("patient merge undone")
+		respondJSON(w, http.StatusOK, m)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPatientMerges map[int64]entity.PatientMerge
+
+func (m mockPatientMerges) ListPatientMerges(ctx context.Context, cursorID int64, limit int32) ([]entity.PatientMerge, error) {
+	var out []entity.PatientMerge
+	for id := cursorID + 1; id <= cursorID+int64(limit); id++ {
+		if merge, ok := m[id]; ok {
+			out = append(out, merge)
+		}
+	}
+	return out, nil
+}
+
+func (m mockPatientMerges) UnmergePatients(ctx context.Context, id int64) (entity.PatientMerge, error) {
+	merge, ok := m[id]
+	switch {
+	case !ok:
+		return merge, pgx.ErrNoRows
+	case merge.UnmergedAt != nil:
+		return merge, fmt.Errorf("%w: merge was already undone", entity.ErrUnmergeUnsafe)
+	}
+	return merge, nil
+}
+
+func TestPatientMerges(t *testing.T) {
+	merges := mockPatientMerges{
+		1: {ID: 1, Event: "A40", SurvivorMRN: "100", PriorMRN: "200", ExamIDs: []int64{7}},
+		2: {ID: 2, Event: "A34", SurvivorMRN: "300", PriorMRN: "400", Renamed: true, UnmergedAt: new(time.Time)},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithPatientMerges(merges))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/patient-merges?limit=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got []entity.PatientMerge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, []int64{7}, got[0].ExamIDs)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/admin/patient-merges/1/unmerge", http.StatusOK},
+		{"/admin/patient-merges/2/unmerge", http.StatusConflict},
+		{"/admin/patient-merges/3/unmerge", http.StatusNotFound},
+		{"/admin/patient-merges/x/unmerge", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		require.Equal(t, tt.want, w.Code, tt.path)
+	}
+}
+
+func TestPatientMerges_UnmergeAuth(t *testing.T) {
+	merges := mockPatientMerges{
+		1: {ID: 1, Event: "A40", SurvivorMRN: "100", PriorMRN: "200"},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false,
+		WithPatientMerges(merges),
+		WithVerifier(&mockVerifier{token: "good"}),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/patient-merges/1/unmerge", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/patient-merges/1/unmerge", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleMetrics writes counters in the Prometheus text exposition format.
+func (a *API) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	if a.spool != nil {
+		stats := a.spool.Stats()
+		writeMetric(&b, "volta_spool_depth", "gauge", "Messages waiting in the local spool.", int64(stats.Depth))
+		writeMetric(&b, "volta_spool_appended_total", "counter", "Messages written to the local spool.", stats.Appended)
+		writeMetric(&b, "volta_spool_drained_total", "counter", "Spooled messages removed from the local spool.", stats.Drained)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func writeMetric(b *strings.Builder, name, kind, help string, v int64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, kind, name, v)
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/s-hammon/volta/internal/entity"
 	"github.com/s-hammon/volta/internal/objects"
+	"github.com/s-hammon/volta/pkg/hl7"
 
 	"github.com/s-hammon/p"
 )
@@ -36,6 +39,11 @@ type Message struct {
 	Version        string `hl7:"MSH.12"`
 }
 
+// ReceivedAt is MSH-7 in UTC, as saved to messages.received_at.
+func (m *Message) ReceivedAt() time.Time {
+	return convertCSTtoUTC(m.DateTime)
+}
+
 type ORM struct {
 	FieldSeparator   string `hl7:"MSH.1"`
 	EncodingChars    string `hl7:"MSH.2"`
@@ -56,17 +64,10 @@ type ORM struct {
 	VisitNo          string `hl7:"PV1.19"`
 	PatientClass     string `hl7:"PV1.2"`
 	AssignedLocation PL     `hl7:"PV1.3"`
-	Accession        string `hl7:"ORC.2"`
-	FillerOrderNo    string `hl7:"ORC.3"`
-	OrderStatus      string `hl7:"ORC.5"`
-	OrderDT          string `hl7:"ORC.9"`
-	OrderingProvider XCN    `hl7:"ORC.12"`
-	Service          CE     `hl7:"OBR.4"`
-	Priority         string `hl7:"OBR.5"`
-	StatusDT         string `hl7:"OBR.22"`
 }
 
-func (o *ORM) ToOrder() *entity.Order {
+// ToOrder builds an order with an exam for each ORC/OBR group.
+func (o *ORM) ToOrder(exams ...Exam) *entity.Order {
 	site := &entity.Site{Code: o.SendingFac}
 	order := &entity.Order{}
 	order.Message = entity.Message{
@@ -105,46 +106,64 @@ func (o *ORM) ToOrder() *entity.Order {
 		},
 		Type: objects.NewPatientType(o.PatientClass),
 	}
-	order.Procedure = entity.Procedure{
-		Site:        *site,
-		Code:        o.Service.Identifier,
-		Description: o.Service.Text,
-	}
-	order.Exam = entity.Exam{
-		Accession: p.Coalesce(o.Accession, o.FillerOrderNo),
-		Priority:  o.Priority,
-		Procedure: entity.Procedure{
-			Site:        *site,
-			Code:        o.Service.Identifier,
-			Description: o.Service.Text,
-		},
-		CurrentStatus: entity.NewExamStatus(o.OrderStatus),
-		Provider: entity.Physician{
-			Name: objects.Name{
-				Last:   o.OrderingProvider.LastName,
-				First:  o.OrderingProvider.FirstName,
-				Middle: o.OrderingProvider.MiddleName,
-				Suffix: o.OrderingProvider.Suffix,
-				Prefix: o.OrderingProvider.Prefix,
-				Degree: o.OrderingProvider.Degree,
-			},
-		},
-		Site: *site,
-	}
-	dt := convertCSTtoUTC(o.OrderDT)
-	switch order.Exam.CurrentStatus {
-	case "SC":
-		order.Exam.Scheduled = dt
-	case "IP":
-		order.Exam.Begin = dt
-	case "CM":
-		order.Exam.End = dt
-	case "CA":
-		order.Exam.Cancelled = dt
+	for _, exam := range exams {
+		order.Exams = append(order.Exams, exam.ToOrderedEntity(*site, order.Message.DateTime))
 	}
 	return order
 }
 
+// OMI is what each order group of a v2.5 OMI^O23 carries beyond an ORM's:
+// the timing segment (TQ1) that replaces ORC-7, and imaging procedure
+// control (IPC).
+type OMI struct {
+	StartDT              string `hl7:"TQ1.7"`
+	Priority             CE     `hl7:"TQ1.9"`
+	Accession            EI     `hl7:"IPC.1"`
+	RequestedProcedureID EI     `hl7:"IPC.2"`
+	StudyInstanceUID     EI     `hl7:"IPC.3"`
+	ScheduledStepID      EI     `hl7:"IPC.4"`
+	Modality             CE     `hl7:"IPC.5"`
+}
+
+// Apply fills in an exam from its group's OMI segments. ORC-2/3 stay the
+// accession so that results for the order find the same exam; IPC-1 is used
+// only if they're blank.
+func (o *OMI) Apply(exam *entity.Exam) {
+	if exam.Accession == "" {
+		exam.Accession = o.Accession.EntityID
+	}
+	if exam.Priority == "" {
+		exam.Priority = o.Priority.Identifier
+	}
+	exam.StudyUID = o.StudyInstanceUID.EntityID
+	start := optionalDTM(o.StartDT)
+	if start.IsZero() {
+		return
+	}
+	switch exam.CurrentStatus {
+	case entity.ExamScheduled, entity.ResultScheduled, entity.ResultOrderReceived:
+		exam.Scheduled = start
+	}
+}
+
+// applyOMI applies each order group's TQ1/IPC to its exam. The segments are
+// optional in each group, so they're read per ORC rather than paired up by
+// position. If the groups don't line up with the exams, nothing is applied.
+func applyOMI(d *hl7.Decoder, exams []entity.Exam) error {
+	groups := d.Groups("ORC")
+	if len(groups) != len(exams) {
+		return nil
+	}
+	for i, g := range groups {
+		omi := &OMI{}
+		if err := g.Decode(omi); err != nil {
+			return err
+		}
+		omi.Apply(&exams[i])
+	}
+	return nil
+}
+
 type ORU struct {
 	FieldSeparator   string  `hl7:"MSH.1"`
 	EncodingChars    string  `hl7:"MSH.2"`
@@ -210,20 +229,111 @@ func (o *ORU) ToObservation(report entity.Report, exams ...Exam) *entity.Observa
 	observation.Report.DictationStart = convertCSTtoUTC(o.DictationTimes.StartDT)
 	observation.Report.DictationEnd = convertCSTtoUTC(o.DictationTimes.EndDT)
 	for _, exam := range exams {
-		observation.Exams = append(observation.Exams, exam.ToEntity(*site))
+		e := exam.ToEntity(*site)
+		e.EventTime = eventTime(exam.OrderDT, observation.Message.DateTime)
+		observation.Exams = append(observation.Exams, e)
 	}
 	return observation
 }
 
+// mdmEvents are the MDM trigger events saved as reports: original document
+// (T02), status change (T04), edit (T08) and replacement (T10), each with
+// content.
+var mdmEvents = []string{"T02", "T04", "T08", "T10"}
+
+// TXA is the transcription document header of an MDM.
+type TXA struct {
+	ActivityDT         string `hl7:"TXA.4"`
+	PrimaryProvider    XCN    `hl7:"TXA.5"`
+	OriginationDT      string `hl7:"TXA.6"`
+	TranscriptionDT    string `hl7:"TXA.7"`
+	Originator         XCN    `hl7:"TXA.9"`
+	DocumentNumber     EI     `hl7:"TXA.12"`
+	ParentNumber       EI     `hl7:"TXA.13"`
+	PlacerOrderNumbers []EI   `hl7:"TXA.14"`
+	FillerOrderNumber  EI     `hl7:"TXA.15"`
+	CompletionStatus   string `hl7:"TXA.17"`
+	AvailabilityStatus string `hl7:"TXA.19"`
+	Authenticators     []PPN  `hl7:"TXA.22"`
+}
+
+// Apply fills in an MDM's observation from its TXA. The report's status
+// comes from the document's (see entity.Document.ReportStatus), its
+// radiologist is the authenticator, else the originator, else the primary
+// provider, and it's submitted when it was authenticated, else at the
+// activity time. Without ORC/OBR groups, the exams are the placer order
+// numbers (TXA-14), else the filler order number (TXA-15), else the
+// document number (TXA-12). Exams without a status take the report's.
+func (t *TXA) Apply(obs *entity.Observation) {
+	r := &obs.Report
+	r.Document = entity.Document{
+		ID:                 t.DocumentNumber.EntityID,
+		ParentID:           t.ParentNumber.EntityID,
+		CompletionStatus:   t.CompletionStatus,
+		AvailabilityStatus: t.AvailabilityStatus,
+	}
+	r.Status = r.Document.ReportStatus(obs.Message.TriggerEvent)
+
+	var authenticator PPN
+	if len(t.Authenticators) > 0 {
+		authenticator = t.Authenticators[0]
+	}
+	if r.Radiologist.Name.Last == "" {
+		for _, x := range []XCN{authenticator.XCN(), t.Originator, t.PrimaryProvider} {
+			if x.LastName != "" {
+				r.Radiologist = x.ToPhysician()
+				break
+			}
+		}
+	}
+	for _, dt := range []string{authenticator.ActionDT, t.ActivityDT, t.TranscriptionDT, t.OriginationDT} {
+		if submitted := optionalDTM(dt); !submitted.IsZero() {
+			r.SubmittedDT = submitted
+			break
+		}
+	}
+	r.DictationStart = optionalDTM(t.OriginationDT)
+	r.DictationEnd = optionalDTM(t.TranscriptionDT)
+
+	if len(obs.Exams) == 0 {
+		var accessions []string
+		for _, placer := range t.PlacerOrderNumbers {
+			if placer.EntityID != "" {
+				accessions = append(accessions, placer.EntityID)
+			}
+		}
+		if len(accessions) == 0 {
+			if accession := p.Coalesce(t.FillerOrderNumber.EntityID, t.DocumentNumber.EntityID); accession != "" {
+				accessions = append(accessions, accession)
+			}
+		}
+		for _, accession := range accessions {
+			obs.Exams = append(obs.Exams, entity.Exam{
+				Accession: accession,
+				Site:      obs.Visit.Site,
+				EventTime: obs.Message.DateTime,
+			})
+		}
+	}
+	for i := range obs.Exams {
+		if obs.Exams[i].ResultStatus == "" {
+			obs.Exams[i].ResultStatus = entity.NewExamStatus(r.Status.String())
+		}
+	}
+}
+
 type Exam struct {
+	OrderControl     string `hl7:"ORC.1"`
 	Accession        string `hl7:"ORC.2"`
 	FillerOrderNo    string `hl7:"ORC.3"`
 	OrderStatus      string `hl7:"ORC.5"`
+	Parent           EIP    `hl7:"ORC.8"`
 	OrderDT          string `hl7:"ORC.9"`
 	OrderingProvider XCN    `hl7:"ORC.12"`
 	Service          CE     `hl7:"OBR.4"`
 	Priority         string `hl7:"OBR.5"`
 	StatusDT         string `hl7:"OBR.22"`
+	ResultStatus     string `hl7:"OBR.25"`
 }
 
 func (e *Exam) ToEntity(site entity.Site) (exam entity.Exam) {
@@ -235,6 +345,7 @@ func (e *Exam) ToEntity(site entity.Site) (exam entity.Exam) {
 		Description: e.Service.Text,
 	}
 	exam.CurrentStatus = entity.NewExamStatus(e.OrderStatus)
+	exam.ResultStatus = entity.NewExamStatus(e.ResultStatus)
 	exam.Provider = entity.Physician{
 		Name: objects.Name{
 			Last:   e.OrderingProvider.LastName,
@@ -249,6 +360,32 @@ func (e *Exam) ToEntity(site entity.Site) (exam entity.Exam) {
 	return exam
 }
 
+// ToOrderedEntity is the exam as an ORM or OMI orders it: the order control
+// code (ORC-1) can override the status, and the transaction time (ORC-9)
+// is stamped on whichever of scheduled, begin, end or cancelled the status
+// calls for.
+func (e *Exam) ToOrderedEntity(site entity.Site, msgTime time.Time) entity.Exam {
+	exam := e.ToEntity(site)
+	exam.Control = entity.OrderControl(e.OrderControl)
+	exam.CurrentStatus = p.Coalesce(exam.Control.Status(), exam.CurrentStatus)
+	if exam.Control.IsReplacement() {
+		exam.Replaces = p.Coalesce(e.Parent.Placer.EntityID, e.Parent.Filler.EntityID)
+	}
+	exam.EventTime = eventTime(e.OrderDT, msgTime)
+	dt := convertCSTtoUTC(e.OrderDT)
+	switch exam.CurrentStatus {
+	case entity.ExamScheduled, entity.ResultScheduled, entity.ResultOrderReceived:
+		exam.Scheduled = dt
+	case entity.ExamInProgress, entity.ResultIncomplete:
+		exam.Begin = dt
+	case entity.ExamComplete:
+		exam.End = dt
+	case entity.ExamCancelled, entity.ExamDiscontinued, entity.ResultCancelled:
+		exam.Cancelled = dt
+	}
+	return exam
+}
+
 type Report struct {
 	Radiologist      CM_NDL `hl7:"OBR.32"`
 	SetID            string `hl7:"OBX.1"`
@@ -256,10 +393,16 @@ type Report struct {
 	Service          CE     `hl7:"OBX.3"`
 	ObservationSubID string `hl7:"OBX.4"`
 	ObservationValue string `hl7:"OBX.5"`
-	ResultStatus     string `hl7:"OBX.11"`
-	ObservationDT    string `hl7:"OBX.14"`
+	// OBX-5 again, for OBX-2 ED and RP
+	Encapsulated  ED       `hl7:"OBX.5"`
+	Pointer       RP       `hl7:"OBX.5"`
+	AbnormalFlags []string `hl7:"OBX.8"`
+	ResultStatus  string   `hl7:"OBX.11"`
+	ObservationDT string   `hl7:"OBX.14"`
 }
 
+// GetReport reads a report from its OBX segments. ED and RP segments are
+// attachments rather than text; the impression is the first of the others.
 func GetReport(obx []Report) (r entity.Report) {
 	if len(obx) > 0 {
 		rad := obx[0].Radiologist.ObservingPractitioner
@@ -273,18 +416,274 @@ func GetReport(obx []Report) (r entity.Report) {
 				rad.Degree,
 			),
 		}
-		r.Impression = obx[0].ObservationValue
 		r.SubmittedDT = convertCSTtoUTC(obx[0].ObservationDT)
 		r.Status = objects.ReportStatus(obx[0].ResultStatus)
 	}
+	text := false
 	for _, o := range obx {
+		if a, ok := o.attachment(); ok {
+			r.Attachments = append(r.Attachments, a)
+			continue
+		}
+		if !text {
+			r.Impression, text = o.ObservationValue, true
+		}
 		if o.ObservationValue != "" {
 			r.Body += o.ObservationValue + "\n"
+			r.Lines = append(r.Lines, entity.ReportLine{
+				ObservationID: o.Service.Identifier,
+				Text:          o.ObservationValue,
+				AbnormalFlags: o.AbnormalFlags,
+			})
 		}
 	}
 	return r
 }
 
+type ADT struct {
+	FieldSeparator   string `hl7:"MSH.1"`
+	EncodingChars    string `hl7:"MSH.2"`
+	SendingApp       string `hl7:"MSH.3"`
+	SendingFac       string `hl7:"MSH.4"`
+	ReceivingApp     string `hl7:"MSH.5"`
+	ReceivingFac     string `hl7:"MSH.6"`
+	DateTime         string `hl7:"MSH.7"`
+	MsgType          CM_MSG `hl7:"MSH.9"`
+	ControlID        string `hl7:"MSH.10"`
+	ProcessingID     string `hl7:"MSH.11"`
+	Version          string `hl7:"MSH.12"`
+	EventDT          string `hl7:"EVN.2"`
+	MRN              CX     `hl7:"PID.3"`
+	PatientName      XPN    `hl7:"PID.5"`
+	DOB              string `hl7:"PID.7"`
+	Sex              string `hl7:"PID.8"`
+	HomePhone        XTN    `hl7:"PID.13"`
+	WorkPhone        XTN    `hl7:"PID.14"`
+	SSN              string `hl7:"PID.19"`
+	PatientClass     string `hl7:"PV1.2"`
+	AssignedLocation PL     `hl7:"PV1.3"`
+	Attending        XCN    `hl7:"PV1.7"`
+	Referring        XCN    `hl7:"PV1.8"`
+	VisitNo          string `hl7:"PV1.19"`
+	AdmitDT          string `hl7:"PV1.44"`
+	DischargeDT      string `hl7:"PV1.45"`
+}
+
+// adtEvents are the ADT trigger events SaveADT handles.
+var adtEvents = []string{"A01", "A03", "A04", "A08"}
+
+// mergeEvents are the ADT merge and link events SaveMerge handles. A24 links
+// the patients in its two PIDs, which is only recorded; the others merge
+// MRG-1 into PID-3.
+var mergeEvents = []string{"A18", "A24", "A34", "A40"}
+
+func (o *ADT) message() entity.Message {
+	return entity.Message{
+		FieldSeparator: o.FieldSeparator,
+		EncodingChars:  o.EncodingChars,
+		SendingApp:     o.SendingApp,
+		SendingFac:     o.SendingFac,
+		ReceivingApp:   o.ReceivingApp,
+		ReceivingFac:   o.ReceivingFac,
+		DateTime:       convertCSTtoUTC(o.DateTime),
+		Type:           o.MsgType.Name,
+		TriggerEvent:   o.MsgType.TriggerEvent,
+		ControlID:      o.ControlID,
+		ProcessingID:   o.ProcessingID,
+		Version:        o.Version,
+	}
+}
+
+func (o *ADT) ToAdmission() *entity.Admission {
+	site := &entity.Site{Code: o.SendingFac}
+	adt := &entity.Admission{Message: o.message()}
+	adt.Patient = entity.Patient{
+		Name: objects.Name{
+			Last:   o.PatientName.LastName,
+			First:  o.PatientName.FirstName,
+			Middle: o.PatientName.MiddleName,
+			Suffix: o.PatientName.Suffix,
+			Prefix: o.PatientName.Prefix,
+			Degree: o.PatientName.Degree,
+		},
+		Sex:       o.Sex,
+		SSN:       objects.NewSSN(o.SSN),
+		HomePhone: tryParsePhone(o.HomePhone.Number),
+		WorkPhone: tryParsePhone(o.WorkPhone.Number),
+	}
+	// a blank DOB leaves the stored one alone rather than becoming today
+	if o.DOB != "" {
+		adt.Patient.DOB = tryParseDOB(o.DOB)
+	}
+	adt.Visit = entity.Visit{
+		VisitNo: o.VisitNo,
+		Site:    *site,
+		MRN: entity.MRN{
+			Value:              o.MRN.ID,
+			AssigningAuthority: o.MRN.AssigningAuthority,
+		},
+		Type:  objects.NewPatientType(o.PatientClass),
+		Class: o.PatientClass,
+		Location: entity.Location{
+			PointOfCare: o.AssignedLocation.PointOfCare,
+			Room:        o.AssignedLocation.Room,
+			Bed:         o.AssignedLocation.Bed,
+		},
+		Admitted:   optionalDTM(o.AdmitDT),
+		Discharged: optionalDTM(o.DischargeDT),
+	}
+	// admits and discharges without PV1-44/45 happened when the event did
+	evn := eventTime(o.EventDT, adt.Message.DateTime)
+	switch o.MsgType.TriggerEvent {
+	case "A01", "A04":
+		if adt.Visit.Admitted.IsZero() {
+			adt.Visit.Admitted = evn
+		}
+	case "A03":
+		if adt.Visit.Discharged.IsZero() {
+			adt.Visit.Discharged = evn
+		}
+	}
+	adt.Attending = o.Attending.ToPhysician()
+	adt.Referring = o.Referring.ToPhysician()
+	return adt
+}
+
+// MergeGroup is one PID (and MRG) of a merge or link event.
+type MergeGroup struct {
+	MRN      CX `hl7:"PID.3"`
+	PriorMRN CX `hl7:"MRG.1"`
+}
+
+func (o *ADT) ToMerge(groups ...MergeGroup) *entity.Merge {
+	merge := &entity.Merge{
+		Message: o.message(),
+		Site:    entity.Site{Code: o.SendingFac},
+	}
+	if o.MsgType.TriggerEvent == entity.LinkEvent {
+		if len(groups) >= 2 {
+			merge.Pairs = append(merge.Pairs, entity.MergePair{
+				Survivor: entity.MRN{Value: groups[0].MRN.ID, AssigningAuthority: groups[0].MRN.AssigningAuthority},
+				Prior:    entity.MRN{Value: groups[1].MRN.ID, AssigningAuthority: groups[1].MRN.AssigningAuthority},
+			})
+		}
+		return merge
+	}
+	for _, g := range groups {
+		if g.PriorMRN.ID == "" {
+			continue
+		}
+		merge.Pairs = append(merge.Pairs, entity.MergePair{
+			Survivor: entity.MRN{Value: g.MRN.ID, AssigningAuthority: g.MRN.AssigningAuthority},
+			Prior:    entity.MRN{Value: g.PriorMRN.ID, AssigningAuthority: g.PriorMRN.AssigningAuthority},
+		})
+	}
+	return merge
+}
+
+type SIU struct {
+	FieldSeparator       string `hl7:"MSH.1"`
+	EncodingChars        string `hl7:"MSH.2"`
+	SendingApp           string `hl7:"MSH.3"`
+	SendingFac           string `hl7:"MSH.4"`
+	ReceivingApp         string `hl7:"MSH.5"`
+	ReceivingFac         string `hl7:"MSH.6"`
+	DateTime             string `hl7:"MSH.7"`
+	MsgType              CM_MSG `hl7:"MSH.9"`
+	ControlID            string `hl7:"MSH.10"`
+	ProcessingID         string `hl7:"MSH.11"`
+	Version              string `hl7:"MSH.12"`
+	PlacerApptID         EI     `hl7:"SCH.1"`
+	FillerApptID         EI     `hl7:"SCH.2"`
+	Duration             string `hl7:"SCH.9"`
+	DurationUnits        CE     `hl7:"SCH.10"`
+	Timing               TQ     `hl7:"SCH.11"`
+	FillerStatus         CE     `hl7:"SCH.25"`
+	PlacerOrderNo        EI     `hl7:"SCH.26"`
+	FillerOrderNo        EI     `hl7:"SCH.27"`
+	MRN                  CX     `hl7:"PID.3"`
+	PatientName          XPN    `hl7:"PID.5"`
+	DOB                  string `hl7:"PID.7"`
+	Sex                  string `hl7:"PID.8"`
+	HomePhone            XTN    `hl7:"PID.13"`
+	WorkPhone            XTN    `hl7:"PID.14"`
+	SSN                  string `hl7:"PID.19"`
+	PatientClass         string `hl7:"PV1.2"`
+	VisitNo              string `hl7:"PV1.19"`
+	Service              CE     `hl7:"AIS.3"`
+	ServiceStart         string `hl7:"AIS.4"`
+	ServiceDuration      string `hl7:"AIS.7"`
+	ServiceDurationUnits CE     `hl7:"AIS.8"`
+	Resource             CE     `hl7:"AIG.3"`
+	Location             PL     `hl7:"AIL.3"`
+}
+
+// siuEvents are the SIU trigger events SaveSIU handles: new, rescheduled,
+// modified, cancelled and no-show.
+var siuEvents = []string{"S12", "S13", "S14", "S15", "S26"}
+
+func (o *SIU) message() entity.Message {
+	return entity.Message{
+		FieldSeparator: o.FieldSeparator,
+		EncodingChars:  o.EncodingChars,
+		SendingApp:     o.SendingApp,
+		SendingFac:     o.SendingFac,
+		ReceivingApp:   o.ReceivingApp,
+		ReceivingFac:   o.ReceivingFac,
+		DateTime:       convertCSTtoUTC(o.DateTime),
+		Type:           o.MsgType.Name,
+		TriggerEvent:   o.MsgType.TriggerEvent,
+		ControlID:      o.ControlID,
+		ProcessingID:   o.ProcessingID,
+		Version:        o.Version,
+	}
+}
+
+func (o *SIU) ToAppointment() *entity.Appointment {
+	appt := &entity.Appointment{
+		Message:   o.message(),
+		ID:        p.Coalesce(o.FillerApptID.EntityID, o.PlacerApptID.EntityID),
+		Accession: p.Coalesce(o.PlacerOrderNo.EntityID, o.FillerOrderNo.EntityID),
+		Start:     optionalDTM(p.Coalesce(o.Timing.Start, o.ServiceStart)),
+		Resource:  p.Coalesce(o.Resource.Identifier, o.Service.Identifier),
+		Room:      p.Coalesce(o.Location.Room, o.Location.PointOfCare),
+	}
+	appt.Status = entity.AppointmentStatusFrom(o.MsgType.TriggerEvent, o.FillerStatus.Identifier)
+	appt.EventTime = appt.Message.DateTime
+	appt.Duration = parseDuration(o.Duration, o.DurationUnits.Identifier)
+	if appt.Duration == 0 {
+		appt.Duration = parseDuration(o.ServiceDuration, o.ServiceDurationUnits.Identifier)
+	}
+	appt.Patient = entity.Patient{
+		Name: objects.Name{
+			Last:   o.PatientName.LastName,
+			First:  o.PatientName.FirstName,
+			Middle: o.PatientName.MiddleName,
+			Suffix: o.PatientName.Suffix,
+			Prefix: o.PatientName.Prefix,
+			Degree: o.PatientName.Degree,
+		},
+		Sex:       o.Sex,
+		SSN:       objects.NewSSN(o.SSN),
+		HomePhone: tryParsePhone(o.HomePhone.Number),
+		WorkPhone: tryParsePhone(o.WorkPhone.Number),
+	}
+	if o.DOB != "" {
+		appt.Patient.DOB = tryParseDOB(o.DOB)
+	}
+	appt.Visit = entity.Visit{
+		VisitNo: o.VisitNo,
+		Site:    entity.Site{Code: o.SendingFac},
+		MRN: entity.MRN{
+			Value:              o.MRN.ID,
+			AssigningAuthority: o.MRN.AssigningAuthority,
+		},
+		Type:  objects.NewPatientType(o.PatientClass),
+		Class: o.PatientClass,
+	}
+	return appt
+}
+
 // Messate Type
 type CM_MSG struct {
 	Name         string `hl7:"1"`
@@ -300,6 +699,18 @@ type CX struct {
 	IdentifierTypeCode string `hl7:"5"`
 }
 
+// Entity Identifier
+type EI struct {
+	EntityID    string `hl7:"1"`
+	NamespaceID string `hl7:"2"`
+}
+
+// Entity Identifier Pair
+type EIP struct {
+	Placer EI `hl7:"1"`
+	Filler EI `hl7:"2"`
+}
+
 // Extended Person Name
 type XPN struct {
 	LastName   string `hl7:"1"`
@@ -334,6 +745,58 @@ type XCN struct {
 	Degree     string `hl7:"7"`
 }
 
+func (x XCN) ToPhysician() entity.Physician {
+	return entity.Physician{
+		Name: objects.Name{
+			Last:   x.LastName,
+			First:  x.FirstName,
+			Middle: x.MiddleName,
+			Suffix: x.Suffix,
+			Prefix: x.Prefix,
+			Degree: x.Degree,
+		},
+		AppCode: x.IDNumber,
+	}
+}
+
+// Performing Person Time Stamp
+type PPN struct {
+	IDNumber   string `hl7:"1"`
+	LastName   string `hl7:"2"`
+	FirstName  string `hl7:"3"`
+	MiddleName string `hl7:"4"`
+	Suffix     string `hl7:"5"`
+	Prefix     string `hl7:"6"`
+	Degree     string `hl7:"7"`
+	ActionDT   string `hl7:"15"`
+}
+
+func (x PPN) XCN() XCN {
+	return XCN{
+		IDNumber:   x.IDNumber,
+		LastName:   x.LastName,
+		FirstName:  x.FirstName,
+		MiddleName: x.MiddleName,
+		Suffix:     x.Suffix,
+		Prefix:     x.Prefix,
+		Degree:     x.Degree,
+	}
+}
+
+// Extended Telecommunication Number
+type XTN struct {
+	Number string `hl7:"1"`
+}
+
+// Timing/Quantity
+type TQ struct {
+	Quantity string `hl7:"1"`
+	Interval string `hl7:"2"`
+	Duration string `hl7:"3"`
+	Start    string `hl7:"4"`
+	End      string `hl7:"5"`
+}
+
 // Coded Element
 type CE struct {
 	Identifier      string `hl7:"1"`
@@ -344,6 +807,23 @@ type CE struct {
 	AltCodingSystem string `hl7:"6"`
 }
 
+// Encapsulated Data
+type ED struct {
+	SourceApp  string `hl7:"1"`
+	TypeOfData string `hl7:"2"`
+	Subtype    string `hl7:"3"`
+	Encoding   string `hl7:"4"`
+	Data       string `hl7:"5"`
+}
+
+// Reference Pointer
+type RP struct {
+	Pointer       string `hl7:"1"`
+	ApplicationID string `hl7:"2"`
+	TypeOfData    string `hl7:"3"`
+	Subtype       string `hl7:"4"`
+}
+
 // Observing Practitioner (i.e. radiologist)
 type CM_NDL struct {
 	ObservingPractitioner XCN    `hl7:"1"`
@@ -370,14 +850,74 @@ func tryParseDOB(dob string) time.Time {
 	return time.Now()
 }
 
+// tryParsePhone keeps the digits of the first phone number in s, e.g.
+// "(555)123-4567~(555)765-4321". Anything that still doesn't parse is
+// dropped.
+func tryParsePhone(s string) objects.PhoneNumber {
+	s, _, _ = strings.Cut(s, "~")
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	ph, err := objects.NewPhoneNumber(digits)
+	if err != nil {
+		return 0
+	}
+	return ph
+}
+
+// parseDuration reads an appointment duration (SCH-9/10, AIS-7/8) in
+// seconds, hours or days, and otherwise minutes. It's 0 if the amount is
+// blank or not a number.
+func parseDuration(amount, units string) time.Duration {
+	n, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	unit := time.Minute
+	switch strings.ToLower(units) {
+	case "s", "sec", "secs", "second", "seconds":
+		unit = time.Second
+	case "h", "hr", "hrs", "hour", "hours":
+		unit = time.Hour
+	case "d", "day", "days":
+		unit = 24 * time.Hour
+	}
+	return time.Duration(n * float64(unit))
+}
+
 func convertCSTtoUTC(stringDT string) time.Time {
+	if dt, ok := parseDTM(stringDT); ok {
+		return dt.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// eventTime is the transaction time (ORC-9) if it parses, else the message
+// time.
+func eventTime(orderDT string, msgTime time.Time) time.Time {
+	if dt, ok := parseDTM(orderDT); ok {
+		return dt.UTC()
+	}
+	return msgTime
+}
+
+// optionalDTM is stringDT in UTC, or the zero time if it's blank or doesn't
+// parse.
+func optionalDTM(stringDT string) time.Time {
+	if dt, ok := parseDTM(stringDT); ok {
+		return dt.UTC()
+	}
+	return time.Time{}
+}
+
+// parseDTM parses an HL7 timestamp sent in CST.
+func parseDTM(stringDT string) (time.Time, bool) {
 	dt, err := time.ParseInLocation("20060102150405", stringDT, cst)
 	if err != nil {
-		dt, err = time.Parse("20060102150405", stringDT)
-		if err != nil {
-			dt = time.Now()
-		}
+		return time.Time{}, false
 	}
-
-	return dt.UTC()
+	return dt, true
 }
@@ -1,52 +1,422 @@
 package api
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/internal/objects"
+	"github.com/s-hammon/volta/pkg/hl7"
 	"github.com/stretchr/testify/require"
 )
 
 func TestORM_ToOrderStatusDT(t *testing.T) {
-	orm := &ORM{
+	order := (&ORM{}).ToOrder(Exam{
 		OrderDT:     "20250501120000",
 		OrderStatus: "SC",
-	}
-	order := orm.ToOrder()
-	require.Equal(t, "SC", order.Exam.CurrentStatus.String())
-	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exam.Scheduled)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Begin)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.End)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Cancelled)
-
-	orm = &ORM{
+	})
+	require.Equal(t, "SC", order.Exams[0].CurrentStatus.String())
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].Scheduled)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Begin)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].End)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Cancelled)
+
+	order = (&ORM{}).ToOrder(Exam{
 		OrderDT:     "20250501120000",
 		OrderStatus: "IP",
-	}
-	order = orm.ToOrder()
-	require.Equal(t, "IP", order.Exam.CurrentStatus.String())
-	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exam.Begin)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Scheduled)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.End)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Cancelled)
-	orm = &ORM{
+	})
+	require.Equal(t, "IP", order.Exams[0].CurrentStatus.String())
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].Begin)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Scheduled)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].End)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Cancelled)
+	order = (&ORM{}).ToOrder(Exam{
 		OrderDT:     "20250501120000",
 		OrderStatus: "CM",
-	}
-	order = orm.ToOrder()
-	require.Equal(t, "CM", order.Exam.CurrentStatus.String())
-	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exam.End)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Begin)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Scheduled)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Cancelled)
-	orm = &ORM{
+	})
+	require.Equal(t, "CM", order.Exams[0].CurrentStatus.String())
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].End)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Begin)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Scheduled)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Cancelled)
+	order = (&ORM{}).ToOrder(Exam{
 		OrderDT:     "20250501120000",
 		OrderStatus: "CA",
+	})
+	require.Equal(t, "CA", order.Exams[0].CurrentStatus.String())
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].Cancelled)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Begin)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].End)
+	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exams[0].Scheduled)
+}
+
+func TestEventTime(t *testing.T) {
+	orm := &ORM{DateTime: "20250501130000"}
+	order := orm.ToOrder(Exam{OrderDT: "20250501120000", OrderStatus: "CM"}, Exam{OrderStatus: "CM"})
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].EventTime)
+	// without ORC-9, the message time is used
+	require.Equal(t, time.Date(2025, time.May, 1, 18, 0, 0, 0, time.UTC), order.Exams[1].EventTime)
+
+	oru := &ORU{DateTime: "20250501130000"}
+	obs := oru.ToObservation(entity.Report{}, Exam{Accession: "1", OrderDT: "20250501120000"}, Exam{Accession: "2"})
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), obs.Exams[0].EventTime)
+	require.Equal(t, time.Date(2025, time.May, 1, 18, 0, 0, 0, time.UTC), obs.Exams[1].EventTime)
+}
+
+func TestResultStatus(t *testing.T) {
+	// OBR-25 is kept apart from ORC-5
+	orm := &ORM{}
+	order := orm.ToOrder(Exam{OrderDT: "20250501120000", OrderStatus: "CM", ResultStatus: "P"})
+	require.Equal(t, entity.ExamComplete, order.Exams[0].CurrentStatus)
+	require.Equal(t, entity.ResultPreliminary, order.Exams[0].ResultStatus)
+
+	order = orm.ToOrder(Exam{OrderDT: "20250501120000", OrderStatus: "DC"})
+	require.Equal(t, entity.ExamDiscontinued, order.Exams[0].CurrentStatus)
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exams[0].Cancelled)
+
+	// unknown codes leave the status blank
+	order = orm.ToOrder(Exam{OrderDT: "20250501120000", OrderStatus: "ZZ"})
+	require.Equal(t, entity.ExamStatus(""), order.Exams[0].CurrentStatus)
+
+	oru := &ORU{DateTime: "20250501130000"}
+	obs := oru.ToObservation(entity.Report{}, Exam{Accession: "1", OrderStatus: "CM", ResultStatus: "F"})
+	require.Equal(t, entity.ExamComplete, obs.Exams[0].CurrentStatus)
+	require.Equal(t, entity.ResultFinal, obs.Exams[0].ResultStatus)
+}
+
+func TestADT_ToAdmission(t *testing.T) {
+	data, err := hl7.HL7.ReadFile("test_hl7/4.hl7")
+	require.NoError(t, err)
+	adt := &ADT{}
+	require.NoError(t, hl7.NewDecoder(data).Decode(adt))
+
+	a := adt.ToAdmission()
+	require.Equal(t, "A01", a.Message.TriggerEvent)
+	require.Equal(t, "Doe", a.Patient.Name.Last)
+	require.Equal(t, "John", a.Patient.Name.First)
+	require.Equal(t, time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC), a.Patient.DOB)
+	require.Equal(t, objects.PhoneNumber(5551234567), a.Patient.HomePhone)
+	require.Equal(t, "123456", a.Visit.MRN.Value)
+	require.Equal(t, "MAIN", a.Visit.Site.Code)
+	require.Equal(t, "I", a.Visit.Class)
+	require.Equal(t, objects.InPatient, a.Visit.Type)
+	require.Equal(t, entity.Location{PointOfCare: "2000", Room: "2012", Bed: "01"}, a.Visit.Location)
+	require.Equal(t, "Doctor", a.Attending.Name.Last)
+	require.Equal(t, "1234", a.Attending.AppCode)
+	require.Equal(t, "Nurse", a.Referring.Name.Last)
+	require.Equal(t, time.Date(2025, time.April, 2, 16, 59, 0, 0, time.UTC), a.Visit.Admitted)
+	require.True(t, a.Visit.Discharged.IsZero())
+}
+
+func TestADT_DischargeFallsBackToEventTime(t *testing.T) {
+	adt := &ADT{
+		DateTime: "20250402130000",
+		EventDT:  "20250402120000",
+		MsgType:  CM_MSG{Name: "ADT", TriggerEvent: "A03"},
+		MRN:      CX{ID: "123456"},
 	}
-	order = orm.ToOrder()
-	require.Equal(t, "CA", order.Exam.CurrentStatus.String())
-	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), order.Exam.Cancelled)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Begin)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.End)
-	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), order.Exam.Scheduled)
+	a := adt.ToAdmission()
+	require.Equal(t, time.Date(2025, time.April, 2, 17, 0, 0, 0, time.UTC), a.Visit.Discharged)
+	require.True(t, a.Visit.Admitted.IsZero())
+	// no PID-7, so the stored DOB isn't touched
+	require.True(t, a.Patient.DOB.IsZero())
+}
+
+func TestADT_ToMerge(t *testing.T) {
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ADT^A40|M1|P|2.3\r" +
+		"EVN|A40|20250402115900\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"MRG|200^^^MHS^MR\r" +
+		"PID|1||300^^^MHS^MR||Doe^Jane\r" +
+		"MRG|400^^^MHS^MR")
+	d := hl7.NewDecoder(data)
+	adt := &ADT{}
+	require.NoError(t, d.Decode(adt))
+	groups := []MergeGroup{}
+	require.NoError(t, d.Decode(&groups))
+
+	m := adt.ToMerge(groups...)
+	require.Equal(t, "MHS", m.Site.Code)
+	require.Equal(t, "A40", m.Message.TriggerEvent)
+	require.Len(t, m.Pairs, 2)
+	require.Equal(t, "100", m.Pairs[0].Survivor.Value)
+	require.Equal(t, "200", m.Pairs[0].Prior.Value)
+	require.Equal(t, "400", m.Pairs[1].Prior.Value)
+
+	// A24 links the second PID to the first
+	adt.MsgType.TriggerEvent = "A24"
+	m = adt.ToMerge(MergeGroup{MRN: CX{ID: "100"}}, MergeGroup{MRN: CX{ID: "500"}})
+	require.Equal(t, []entity.MergePair{{Survivor: entity.MRN{Value: "100"}, Prior: entity.MRN{Value: "500"}}}, m.Pairs)
+}
+
+func TestSIU_ToAppointment(t *testing.T) {
+	data, err := hl7.HL7.ReadFile("test_hl7/10.hl7")
+	require.NoError(t, err)
+	siu := &SIU{}
+	require.NoError(t, hl7.NewDecoder(data).Decode(siu))
+
+	a := siu.ToAppointment()
+	require.Equal(t, "S12", a.Message.TriggerEvent)
+	require.Equal(t, "A29737914", a.ID)
+	require.Equal(t, "29737914", a.Accession)
+	require.Equal(t, entity.AppointmentBooked, a.Status)
+	require.False(t, a.NoShow())
+	require.Equal(t, time.Date(2025, time.April, 4, 21, 50, 0, 0, time.UTC), a.Start)
+	require.Equal(t, 20*time.Minute, a.Duration)
+	require.Equal(t, "BMCNEMAM1", a.Resource)
+	require.Equal(t, "MAM1", a.Room)
+	require.Equal(t, "002207830", a.Visit.MRN.Value)
+	require.Equal(t, "BMCNE", a.Visit.Site.Code)
+	require.Equal(t, "SMITH", a.Patient.Name.Last)
+	require.Equal(t, a.Message.DateTime, a.EventTime)
+}
+
+func TestSIU_NoShowFallsBackToAIS(t *testing.T) {
+	siu := &SIU{
+		MsgType:              CM_MSG{Name: "SIU", TriggerEvent: "S26"},
+		PlacerApptID:         EI{EntityID: "P1"},
+		FillerStatus:         CE{Identifier: "Booked"},
+		FillerOrderNo:        EI{EntityID: "A1"},
+		Service:              CE{Identifier: "CT"},
+		ServiceStart:         "20250501080000",
+		ServiceDuration:      "1",
+		ServiceDurationUnits: CE{Identifier: "h"},
+	}
+	a := siu.ToAppointment()
+	require.Equal(t, "P1", a.ID)
+	require.Equal(t, "A1", a.Accession)
+	require.Equal(t, entity.AppointmentNoShow, a.Status)
+	require.True(t, a.NoShow())
+	require.Equal(t, "CT", a.Resource)
+	require.Equal(t, time.Date(2025, time.May, 1, 13, 0, 0, 0, time.UTC), a.Start)
+	require.Equal(t, time.Hour, a.Duration)
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		amount, units string
+		want          time.Duration
+	}{
+		{"30", "MIN", 30 * time.Minute},
+		{"30", "", 30 * time.Minute},
+		{"90", "s", 90 * time.Second},
+		{"1.5", "HRS", 90 * time.Minute},
+		{"", "MIN", 0},
+		{"abc", "MIN", 0},
+		{"-5", "MIN", 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, parseDuration(tt.amount, tt.units), "%s %s", tt.amount, tt.units)
+	}
+}
+
+func TestORM_OrderControl(t *testing.T) {
+	tests := []struct {
+		control, status string
+		want            entity.ExamStatus
+	}{
+		{"NW", "SC", entity.ExamScheduled},
+		{"SC", "IP", entity.ExamInProgress},
+		{"CA", "SC", entity.ExamCancelled},
+		{"OC", "", entity.ExamCancelled},
+		{"HD", "SC", entity.ExamOnHold},
+	}
+	for _, tt := range tests {
+		order := (&ORM{}).ToOrder(Exam{OrderControl: tt.control, OrderStatus: tt.status, OrderDT: "20250501120000", Accession: "A1"})
+		require.Equal(t, entity.OrderControl(tt.control), order.Exams[0].Control)
+		require.Equal(t, tt.want, order.Exams[0].CurrentStatus, tt.control)
+		require.Empty(t, order.Exams[0].Replaces)
+	}
+
+	cancel := (&ORM{}).ToOrder(Exam{OrderControl: "CA", OrderStatus: "SC", OrderDT: "20250501120000"})
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 0, 0, 0, time.UTC), cancel.Exams[0].Cancelled)
+	require.True(t, cancel.Exams[0].Scheduled.IsZero())
+}
+
+func TestORM_Replacement(t *testing.T) {
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||ORM^O01|M1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|RP|A2||||||A1^F1|20250501120000\r" +
+		"OBR|1|A2||CT123^CT Head")
+	d := hl7.NewDecoder(data)
+	orm := &ORM{}
+	require.NoError(t, d.Decode(orm))
+	exams := []Exam{}
+	require.NoError(t, d.Decode(&exams))
+	order := orm.ToOrder(exams...)
+	require.Equal(t, entity.OrderReplace, order.Exams[0].Control)
+	require.Equal(t, "A2", order.Exams[0].Accession)
+	require.Equal(t, "A1", order.Exams[0].Replaces)
+
+	// only RP and RU carry a replaced order
+	exams[0].OrderControl = "NW"
+	require.Empty(t, orm.ToOrder(exams...).Exams[0].Replaces)
+}
+
+func TestORM_MultipleOrders(t *testing.T) {
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||ORM^O01|M1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"PV1|1|O|||||||||||||||||V1\r" +
+		"ORC|NW|A1||||||||||1234^Smith^Jane\r" +
+		"OBR|1|A1||CT100^CT Chest\r" +
+		"ORC|NW|A2||||||||||1234^Smith^Jane\r" +
+		"OBR|2|A2||CT200^CT Abdomen\r" +
+		"ORC|NW|A3||||||||||1234^Smith^Jane\r" +
+		"OBR|3|A3||CT300^CT Pelvis")
+	d := hl7.NewDecoder(data)
+	orm := &ORM{}
+	require.NoError(t, d.Decode(orm))
+	exams := []Exam{}
+	require.NoError(t, d.Decode(&exams))
+
+	order := orm.ToOrder(exams...)
+	require.Len(t, order.Exams, 3)
+	for i, want := range []struct{ accession, code string }{
+		{"A1", "CT100"},
+		{"A2", "CT200"},
+		{"A3", "CT300"},
+	} {
+		require.Equal(t, want.accession, order.Exams[i].Accession)
+		require.Equal(t, want.code, order.Exams[i].Procedure.Code)
+		require.Equal(t, "Smith", order.Exams[i].Provider.Name.Last)
+	}
+	require.Equal(t, "V1", order.Visit.VisitNo)
+}
+
+func TestOMI_Apply(t *testing.T) {
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||OMI^O23^OMI_O23|M1|P|2.5\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|NW||||||||20250501120000\r" +
+		"TQ1|1||||||20250502083000||S\r" +
+		"OBR|1|||CT123^CT Head\r" +
+		"IPC|ACC9^RIS|RP1|1.2.840.99.1^DCM|SPS1|CT")
+	d := hl7.NewDecoder(data)
+	orm := &ORM{}
+	require.NoError(t, d.Decode(orm))
+	exams := []Exam{}
+	require.NoError(t, d.Decode(&exams))
+	omis := []OMI{}
+	require.NoError(t, d.Decode(&omis))
+	require.Len(t, omis, 1)
+
+	exam := orm.ToOrder(exams...).Exams[0]
+	exam.CurrentStatus = entity.ExamScheduled
+	omis[0].Apply(&exam)
+	require.Equal(t, "ACC9", exam.Accession)
+	require.Equal(t, "S", exam.Priority)
+	require.Equal(t, "1.2.840.99.1", exam.StudyUID)
+	require.Equal(t, time.Date(2025, time.May, 2, 13, 30, 0, 0, time.UTC), exam.Scheduled)
+
+	// ORC-2 wins over IPC-1 so that ORUs for the order find the same exam
+	exams[0].Accession = "P5"
+	exam = orm.ToOrder(exams...).Exams[0]
+	omis[0].Apply(&exam)
+	require.Equal(t, "P5", exam.Accession)
+}
+
+func TestApplyOMI_Groups(t *testing.T) {
+	// only the second order group has TQ1/IPC
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||OMI^O23^OMI_O23|M1|P|2.5\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|NW|A1|||||||20250501120000\r" +
+		"OBR|1|||CT100^CT Chest\r" +
+		"ORC|NW|A2|||||||20250501120000\r" +
+		"TQ1|1||||||20250502083000||S\r" +
+		"OBR|2|||CT200^CT Abdomen\r" +
+		"IPC|ACC9^RIS|RP1|1.2.840.99.2^DCM|SPS1|CT")
+	d := hl7.NewDecoder(data)
+	orm := &ORM{}
+	require.NoError(t, d.Decode(orm))
+	exams := []Exam{}
+	require.NoError(t, d.Decode(&exams))
+	order := orm.ToOrder(exams...)
+	require.NoError(t, applyOMI(d, order.Exams))
+
+	require.Empty(t, order.Exams[0].StudyUID)
+	require.Empty(t, order.Exams[0].Priority)
+	require.Equal(t, "1.2.840.99.2", order.Exams[1].StudyUID)
+	require.Equal(t, "S", order.Exams[1].Priority)
+
+	// groups that don't line up with the exams aren't applied at all
+	order = orm.ToOrder(exams[1:]...)
+	require.NoError(t, applyOMI(d, order.Exams))
+	require.Empty(t, order.Exams[0].StudyUID)
+}
+
+func TestGetReport_Lines(t *testing.T) {
+	report := GetReport([]Report{
+		{Service: CE{Identifier: "&GDT"}, ObservationValue: "FINDINGS: Lungs are clear."},
+		{Service: CE{Identifier: "&GDT"}},
+		{Service: CE{Identifier: "&IMP"}, ObservationValue: "No acute disease.", ResultStatus: "F"},
+	})
+	require.Equal(t, "FINDINGS: Lungs are clear.\nNo acute disease.\n", report.Body)
+	require.Equal(t, []entity.ReportLine{
+		{ObservationID: "&GDT", Text: "FINDINGS: Lungs are clear."},
+		{ObservationID: "&IMP", Text: "No acute disease."},
+	}, report.Lines)
+}
+
+type capturingStore struct {
+	mockHL7Store
+	obs *entity.Observation
+}
+
+func (s *capturingStore) SaveORU(ctx context.Context, obs *entity.Observation) error {
+	s.obs = obs
+	return nil
+}
+
+func mdmMessage(event, txa string) string {
+	return "MSH|^~\\&|DICT|MHS|STRIC|MHS|20250501120000||MDM^" + event + "|M1|P|2.3\r" +
+		"EVN|" + event + "|20250501120000\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"PV1|1|O\r" +
+		"TXA|1|RAD|TX|20250501115500|1234^Rad^Ray|20250501110000|20250501113000|||||" + txa + "\r" +
+		"OBX|1|TX|&GDT||FINDINGS: Lungs are clear.||||||F\r" +
+		"OBX|2|TX|&IMP||No acute disease.||||||F"
+}
+
+func TestTXA_Apply(t *testing.T) {
+	store := &capturingStore{}
+	// TXA-12 through TXA-22
+	_, err := handleByMsgType(store, []byte(mdmMessage("T02", "DOC1||A1~A2|||AU||AV|||5678^Auth^Anne^^^^^^^^^^^^20250501120500")))
+	require.NoError(t, err)
+	obs := store.obs
+	require.Equal(t, "MDM", obs.Message.Type)
+	require.Equal(t, entity.Document{ID: "DOC1", CompletionStatus: "AU", AvailabilityStatus: "AV"}, obs.Report.Document)
+	require.Equal(t, objects.Final, obs.Report.Status)
+	require.Equal(t, "Auth", obs.Report.Radiologist.Name.Last)
+	require.Equal(t, time.Date(2025, time.May, 1, 17, 5, 0, 0, time.UTC), obs.Report.SubmittedDT)
+	require.Equal(t, time.Date(2025, time.May, 1, 16, 0, 0, 0, time.UTC), obs.Report.DictationStart)
+	require.Equal(t, time.Date(2025, time.May, 1, 16, 30, 0, 0, time.UTC), obs.Report.DictationEnd)
+	require.Equal(t, "FINDINGS: Lungs are clear.\nNo acute disease.\n", obs.Report.Body)
+	require.Len(t, obs.Exams, 2)
+	require.Equal(t, "A1", obs.Exams[0].Accession)
+	require.Equal(t, "A2", obs.Exams[1].Accession)
+	require.Equal(t, entity.ResultFinal, obs.Exams[0].ResultStatus)
+
+	// a dictated replacement is a prelim, linked by the filler order number
+	_, err = handleByMsgType(store, []byte(mdmMessage("T10", "DOC2|DOC1||F1||DI")))
+	require.NoError(t, err)
+	obs = store.obs
+	require.Equal(t, objects.Pending, obs.Report.Status)
+	require.Equal(t, "DOC1", obs.Report.Document.ParentID)
+	require.Equal(t, "Rad", obs.Report.Radiologist.Name.Last)
+	require.Equal(t, time.Date(2025, time.May, 1, 16, 55, 0, 0, time.UTC), obs.Report.SubmittedDT)
+	require.Len(t, obs.Exams, 1)
+	require.Equal(t, "F1", obs.Exams[0].Accession)
+
+	// and an authenticated one is a correction
+	_, err = handleByMsgType(store, []byte(mdmMessage("T10", "DOC3|DOC1||||LA")))
+	require.NoError(t, err)
+	require.Equal(t, objects.Corrected, store.obs.Report.Status)
+	require.Equal(t, "DOC3", store.obs.Exams[0].Accession, "falls back to the document number")
+
+	_, err = handleByMsgType(store, []byte(mdmMessage("T04", "DOC1|||||AU||OB")))
+	require.NoError(t, err)
+	require.True(t, store.obs.Report.Document.Obsolete())
+
+	_, err = handleByMsgType(store, []byte(mdmMessage("T01", "DOC1")))
+	require.Equal(t, CategoryUnsupportedType, Classify(err))
 }
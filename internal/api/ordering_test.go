@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingStore holds each save until released, tracking how many are in
+// flight at once.
+type blockingStore struct {
+	mockHL7Store
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+	proceed  chan struct{}
+}
+
+func (b *blockingStore) SaveORM(ctx context.Context, order *entity.Order) error {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxSeen = max(b.maxSeen, b.inFlight)
+	b.mu.Unlock()
+	<-b.proceed
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	return nil
+}
+
+func orderedPushRequest(t *testing.T, messageID, orderingKey string) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(pubSubMessage{Message: message{
+		ID:          messageID,
+		OrderingKey: orderingKey,
+		Data:        []byte("path/to/" + messageID),
+		Attributes:  attributes{Type: "ORM"},
+	}})
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+}
+
+func TestHandleMessage_OrderingKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		wantMax int
+	}{
+		{"same key serialized", []string{"acc-1", "acc-1", "acc-1"}, 1},
+		{"different keys concurrent", []string{"acc-1", "acc-2", "acc-3"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &blockingStore{proceed: make(chan struct{})}
+			handler := New(store, &mockHealthcareClient{message: mockORM}, false)
+
+			var wg sync.WaitGroup
+			for i, key := range tt.keys {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, orderedPushRequest(t, string(rune('a'+i)), key))
+					require.Equal(t, http.StatusCreated, w.Code)
+				}()
+			}
+			require.Eventually(t, func() bool {
+				store.mu.Lock()
+				defer store.mu.Unlock()
+				return store.inFlight == tt.wantMax
+			}, time.Second, time.Millisecond)
+			for range tt.keys {
+				store.proceed <- struct{}{}
+			}
+			wg.Wait()
+			require.Equal(t, tt.wantMax, store.maxSeen)
+		})
+	}
+}
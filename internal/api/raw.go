@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+// HL7ContentType is the media type for pipe-delimited (ER7) HL7 v2.
+const HL7ContentType = "x-application/hl7-v2+er7"
+
+type RawMessageStore interface {
+	GetRawMessage(ctx context.Context, messageID int64) (entity.RawMessage, error)
+}
+
+// WithRawMessages serves archived messages at GET /messages/{id}/raw.
+func WithRawMessages(rs RawMessageStore) Option {
+	return func(a *API) { a.rawMessages = rs }
+}
+
+func (a *API) handleGetRawMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	raw, err := a.rawMessages.GetRawMessage(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusNotFound, response{Message: "no raw message archived for this message"})
+			return
+		}
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting raw message: %v", err)})
+		return
+	}
+	w.Header().Set("Content-Type", HL7ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(raw.Data)))
+	w.Header().Set("ETag", strconv.Quote(raw.ContentHash))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(raw.Data)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRawStore map[int64][]byte
+
+func (m mockRawStore) GetRawMessage(ctx context.Context, id int64) (entity.RawMessage, error) {
+	data, ok := m[id]
+	if !ok {
+		return entity.RawMessage{}, pgx.ErrNoRows
+	}
+	return entity.RawMessage{MessageID: id, ContentHash: entity.ContentHash(data), Data: data}, nil
+}
+
+func TestHandleGetRawMessage(t *testing.T) {
+	handler := New(new(mockHL7Store), &mockHealthcareClient{}, false, WithRawMessages(mockRawStore{1: mockORM}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages/1/raw", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, HL7ContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, `"`+entity.ContentHash(mockORM)+`"`, w.Header().Get("ETag"))
+	require.Equal(t, mockORM, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages/2/raw", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages/x/raw", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -2,10 +2,16 @@ package api
 
 import "net/http"
 
-func handleReadiness(w http.ResponseWriter, r *http.Request) {
+func (a *API) handleReadiness(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Status string `json:"status"`
+		Status     string `json:"status"`
+		SpoolDepth *int   `json:"spool_depth,omitempty"`
 	}
 
-	respondJSON(w, http.StatusOK, &response{Status: "ok"})
+	resp := &response{Status: "ok"}
+	if a.spool != nil {
+		depth := a.spool.Depth()
+		resp.SpoolDepth = &depth
+	}
+	respondJSON(w, http.StatusOK, resp)
 }
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+type ReportHistoryStore interface {
+	ReportHistory(ctx context.Context, examID int64) ([]entity.ReportVersion, error)
+	ExamID(ctx context.Context, sendingApp, accession string) (int64, error)
+}
+
+// WithReportHistory serves an exam's report versions at GET /exams/{id}/reports
+// and GET /exams/reports?sending_app=...&accession=....
+func WithReportHistory(s ReportHistoryStore) Option {
+	return func(a *API) { a.reports = s }
+}
+
+type reportHistoryResponse struct {
+	ExamID  int64                  `json:"exam_id"`
+	Reports []entity.ReportVersion `json:"reports"`
+}
+
+func (a *API) handleReportHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondJSON(w, http.StatusBadRequest, response{Message: "id must be a positive integer"})
+		return
+	}
+	a.respondReportHistory(w, r, id)
+}
+
+func (a *API) handleReportHistoryByAccession(w http.ResponseWriter, r *http.Request) {
+	sendingApp := r.URL.Query().Get("sending_app")
+	accession := r.URL.Query().Get("accession")
+	if sendingApp == "" || accession == "" {
+		respondJSON(w, http.StatusBadRequest, response{Message: "must provide sending_app and accession params"})
+		return
+	}
+	id, err := a.reports.ExamID(r.Context(), sendingApp, accession)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusNotFound, response{Message: "exam not found"})
+			return
+		}
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting exam: %v", err)})
+		return
+	}
+	a.respondReportHistory(w, r, id)
+}
+
+func (a *API) respondReportHistory(w http.ResponseWriter, r *http.Request, examID int64) {
+	versions, err := a.reports.ReportHistory(r.Context(), examID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error getting report history: %v", err)})
+		return
+	}
+	if len(versions) == 0 {
+		respondJSON(w, http.StatusNotFound, response{Message: "no reports for exam"})
+		return
+	}
+	respondJSON(w, http.StatusOK, reportHistoryResponse{ExamID: examID, Reports: versions})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockReportHistory map[int64][]entity.ReportVersion
+
+func (m mockReportHistory) ReportHistory(ctx context.Context, examID int64) ([]entity.ReportVersion, error) {
+	return m[examID], nil
+}
+
+func (m mockReportHistory) ExamID(ctx context.Context, sendingApp, accession string) (int64, error) {
+	if sendingApp == "STRIC" && accession == "29737914" {
+		return 1, nil
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func TestReportHistory(t *testing.T) {
+	submitted := time.Date(2025, time.April, 4, 20, 25, 35, 0, time.UTC)
+	history := mockReportHistory{1: {
+		{ID: 1, Status: "P", Impression: "No acute findings.", SubmittedDT: submitted},
+		{ID: 2, PreviousID: 1, Status: "F", Impression: "No acute findings.", SubmittedDT: submitted.Add(time.Hour)},
+		{ID: 3, PreviousID: 2, Status: "C", Impression: "Small nodule, right upper lobe.", SubmittedDT: submitted.Add(2 * time.Hour), Current: true},
+	}}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithReportHistory(history))
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{"by id", "/exams/1/reports", http.StatusOK},
+		{"by accession", "/exams/reports?sending_app=STRIC&accession=29737914", http.StatusOK},
+		{"no reports", "/exams/2/reports", http.StatusNotFound},
+		{"unknown accession", "/exams/reports?sending_app=STRIC&accession=1", http.StatusNotFound},
+		{"bad id", "/exams/0/reports", http.StatusBadRequest},
+		{"missing params", "/exams/reports?sending_app=STRIC", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got reportHistoryResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Equal(t, int64(1), got.ExamID)
+			require.Len(t, got.Reports, 3)
+			require.Equal(t, int64(2), got.Reports[2].PreviousID)
+			require.True(t, got.Reports[2].Current)
+			require.False(t, got.Reports[1].Current)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/spool"
+)
+
+const msgSpooled = "message spooled"
+
+// WithSpool writes messages to s instead of failing them when the database is
+// unavailable. Run s.Drain with SaveSpooled to save them once it's back.
+func WithSpool(s *spool.Spool) Option {
+	return func(a *API) { a.spool = s }
+}
+
+// spoolMessage appends raw to the spool and acks it. If the spool can't be
+// written the message is left to fail normally so Pub/Sub redelivers it.
+func (a *API) spoolMessage(resp response, raw []byte) (outcome, bool) {
+	if err := a.spool.Append(spool.Entry{HL7Path: resp.HL7Path, Raw: raw}); err != nil {
+		log.Error().Err(err).Str("hl7_path", resp.HL7Path).Msg("couldn't spool message")
+		return outcome{}, false
+	}
+	resp.Message = msgSpooled
+	resp.VoltaError = ""
+	resp.ErrorCategory = ""
+	return outcome{code: http.StatusAccepted, resp: resp}, true
+}
+
+// SaveSpooled returns the function for spool.Drain. Only transient database
+// errors keep an entry in the spool; anything else won't get better by
+// waiting, so it goes to dl (if set) and the entry is dropped.
+func SaveSpooled(store HL7Store, dl DeadLetterStore) func(context.Context, spool.Entry) error {
+	a := &API{Store: store, deadLetters: dl}
+	return func(ctx context.Context, e spool.Entry) error {
+		controlID, _, err := HandleByMsgType(a.Store, e.Raw)
+		if err == nil {
+			a.resolveFailure(ctx, e.HL7Path, e.Raw)
+			return nil
+		}
+		if Classify(err) == CategoryTransientDB {
+			return err
+		}
+		a.recordFailure(ctx, e.HL7Path, controlID, e.Raw, err)
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/s-hammon/volta/internal/spool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool_TransientFailureIsSpooled(t *testing.T) {
+	sp, err := spool.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sp.Close() })
+
+	store := &countingStore{mockHL7Store: mockHL7Store{saveORMErr: context.DeadlineExceeded}}
+	handler := New(store, &mockHealthcareClient{message: mockORM}, false, WithSpool(sp))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, pushRequest(t, "111", "path/to/msg.hl7"))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Contains(t, w.Body.String(), msgSpooled)
+	require.Equal(t, 1, sp.Depth())
+	require.Equal(t, 1, store.saves)
+
+	// with a backlog, new messages queue behind it without trying the database
+	store.saveORMErr = nil
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, pushRequest(t, "222", "path/to/next.hl7"))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Equal(t, 2, sp.Depth())
+	require.Equal(t, 1, store.saves)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.JSONEq(t, `{"status":"ok","spool_depth":2}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "\nvolta_spool_depth 2\n")
+	require.Contains(t, w.Body.String(), "\nvolta_spool_appended_total 2\n")
+}
+
+func TestSpool_PermanentFailureNotSpooled(t *testing.T) {
+	sp, err := spool.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sp.Close() })
+
+	unsupported := strings.Replace(string(mockORM), "ORM^R01", "MFN^M02", 1)
+	handler := New(new(mockHL7Store), &mockHealthcareClient{message: []byte(unsupported)}, false, WithSpool(sp))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, pushRequest(t, "111", "path/to/msg.hl7"))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Contains(t, w.Body.String(), string(CategoryUnsupportedType))
+	require.Zero(t, sp.Depth())
+}
+
+func TestSaveSpooled(t *testing.T) {
+	dl := newMockDeadLetters()
+	store := &countingStore{mockHL7Store: mockHL7Store{saveORMErr: context.DeadlineExceeded}}
+	save := SaveSpooled(store, dl)
+	ctx := context.Background()
+
+	require.Error(t, save(ctx, spool.Entry{Raw: mockORM}))
+	require.Empty(t, dl.msgs)
+
+	store.saveORMErr = nil
+	require.NoError(t, save(ctx, spool.Entry{Raw: mockORM}))
+	require.Equal(t, 2, store.saves)
+
+	// permanent failures are dead-lettered and dropped from the spool
+	require.NoError(t, save(ctx, spool.Entry{HL7Path: "path/to/bad.hl7", Raw: []byte("garbage")}))
+	require.Len(t, dl.msgs, 1)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+const defaultStalePageSize = 100
+
+// StaleUpdateStore lists exam updates that were skipped for arriving after a
+// newer one.
+type StaleUpdateStore interface {
+	ListStaleExamUpdates(ctx context.Context, cursorID int64, limit int32) ([]entity.StaleExamUpdate, error)
+}
+
+// WithStaleUpdates serves GET /admin/stale-exam-updates.
+func WithStaleUpdates(s StaleUpdateStore) Option {
+	return func(a *API) { a.staleUpdates = s }
+}
+
+func (a *API) handleListStaleUpdates(w http.ResponseWriter, r *http.Request) {
+	cursorID, limit, errMsg := pageParams(r, defaultStalePageSize)
+	if errMsg != "" {
+		respondJSON(w, http.StatusBadRequest, response{Message: errMsg})
+		return
+	}
+	updates, err := a.staleUpdates.ListStaleExamUpdates(r.Context(), cursorID, limit)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, response{Message: p.Format("error listing stale exam updates: %v", err)})
+		return
+	}
+	if updates == nil {
+		updates = []entity.StaleExamUpdate{}
+	}
+	respondJSON(w, http.StatusOK, updates)
+}
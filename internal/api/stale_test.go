@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStaleUpdates []entity.StaleExamUpdate
+
+func (m mockStaleUpdates) ListStaleExamUpdates(ctx context.Context, cursorID int64, limit int32) ([]entity.StaleExamUpdate, error) {
+	var out []entity.StaleExamUpdate
+	for _, u := range m {
+		if u.ID > cursorID && len(out) < int(limit) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func TestListStaleUpdates(t *testing.T) {
+	event := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	updates := mockStaleUpdates{
+		{ID: 1, ExamID: 10, IncomingStatus: "SC", IncomingEventDT: event, CurrentStatus: "CM", LastEventDT: event.Add(time.Hour)},
+		{ID: 2, ExamID: 11, IncomingStatus: "IP", IncomingEventDT: event, CurrentStatus: "CA", LastEventDT: event.Add(time.Hour)},
+	}
+	handler := New(new(mockHL7Store), new(mockHealthcareClient), false, WithStaleUpdates(updates))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stale-exam-updates?cursor_id=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got []entity.StaleExamUpdate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	require.Equal(t, int64(11), got[0].ExamID)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stale-exam-updates?limit=0", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stale-exam-updates?cursor_id=5", nil))
+	require.JSONEq(t, `[]`, w.Body.String())
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Google's signing keys for the OIDC tokens that Pub/Sub push attaches.
+const GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+const (
+	defaultKeyTTL     = time.Hour
+	minRefreshBackoff = time.Minute
+	maxJWKSSize       = 1 << 20
+)
+
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys maps a key ID to its public key. Useful in tests or wherever
+// the signing keys are known ahead of time.
+type StaticKeys map[string]crypto.PublicKey
+
+func (s StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %v", err)
+	}
+	keys := make(StaticKeys, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := k.rsaKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %v", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %v", err)
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+		return nil, fmt.Errorf("exponent out of range")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+// NewFileKeySource reads a JWKS document from disk once.
+func NewFileKeySource(path string) (StaticKeys, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from operator config
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWKS file: %v", err)
+	}
+	return ParseJWKS(data)
+}
+
+// RemoteKeySource fetches a JWKS document over HTTP and caches it. An unknown
+// key ID triggers a refetch (rate limited) so that key rotation is picked up.
+type RemoteKeySource struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      StaticKeys
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySource(url string, client *http.Client) *RemoteKeySource {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeySource{
+		url:    url,
+		client: client,
+		ttl:    defaultKeyTTL,
+		now:    time.Now,
+	}
+}
+
+func (r *RemoteKeySource) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	age := r.now().Sub(r.fetchedAt)
+	if r.keys == nil || age > r.ttl {
+		if err := r.refresh(ctx); err != nil {
+			return nil, err
+		}
+	} else if _, ok := r.keys[kid]; !ok && age > minRefreshBackoff {
+		if err := r.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return r.keys.Key(ctx, kid)
+}
+
+func (r *RemoteKeySource) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return fmt.Errorf("error building JWKS request: %v", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching JWKS: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching JWKS: %s returned %d", r.url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return fmt.Errorf("error reading JWKS: %v", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+	r.keys = keys
+	r.fetchedAt = r.now()
+	return nil
+}
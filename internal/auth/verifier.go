@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Issuers Google uses for OIDC tokens attached to Pub/Sub push requests.
+var GoogleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+const clockSkew = time.Minute
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrExpired          = errors.New("token expired")
+	ErrNotYetValid      = errors.New("token not yet valid")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrEmailNotAllowed  = errors.New("token email not allowed")
+)
+
+type Config struct {
+	// Audience must match the token's aud claim, i.e. the audience configured
+	// on the push subscription (usually the service URL).
+	Audience string
+	Issuers  []string
+	// AllowedEmails restricts which service accounts may call; empty allows
+	// any verified email.
+	AllowedEmails []string
+}
+
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	NotBefore     int64    `json:"nbf,omitempty"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or array of strings")
+	}
+	*a = list
+	return nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type Verifier struct {
+	cfg  Config
+	keys KeySource
+	now  func() time.Time
+}
+
+func NewVerifier(keys KeySource, cfg Config) *Verifier {
+	if len(cfg.Issuers) == 0 {
+		cfg.Issuers = GoogleIssuers
+	}
+	return &Verifier{cfg: cfg, keys: keys, now: time.Now}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, err
+	}
+	if hdr.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, hdr.Alg)
+	}
+	key, err := v.keys.Key(ctx, hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyRS256(key, parts[0]+"."+parts[1], parts[2]); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) validate(c *Claims) error {
+	now := v.now()
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return ErrExpired
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrNotYetValid
+	}
+	if !slices.Contains(v.cfg.Issuers, c.Issuer) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, c.Issuer)
+	}
+	if v.cfg.Audience == "" || !slices.Contains(c.Audience, v.cfg.Audience) {
+		return ErrInvalidAudience
+	}
+	if len(v.cfg.AllowedEmails) > 0 {
+		if !c.EmailVerified || !slices.Contains(v.cfg.AllowedEmails, c.Email) {
+			return fmt.Errorf("%w: %q", ErrEmailNotAllowed, c.Email)
+		}
+	}
+	return nil
+}
+
+func verifyRS256(key crypto.PublicKey, signed, sig string) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: key is not RSA", ErrUnsupportedAlg)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	sum := sha256.Sum256([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, sum[:], raw); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAudience = "https://volta.example.com"
+	testEmail    = "pubsub-push@project.iam.gserviceaccount.com"
+)
+
+var testNow = time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+func TestVerifier_Verify(t *testing.T) {
+	key := genRSAKey(t)
+	keys := StaticKeys{"kid-1": &key.PublicKey}
+	v := NewVerifier(keys, Config{Audience: testAudience, AllowedEmails: []string{testEmail}})
+	v.now = func() time.Time { return testNow }
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":            "https://accounts.google.com",
+			"aud":            testAudience,
+			"exp":            testNow.Add(time.Hour).Unix(),
+			"iat":            testNow.Unix(),
+			"email":          testEmail,
+			"email_verified": true,
+		}
+	}
+
+	claims, err := v.Verify(context.Background(), signToken(t, key, "kid-1", valid()))
+	require.NoError(t, err)
+	require.Equal(t, testEmail, claims.Email)
+
+	tests := []struct {
+		name   string
+		kid    string
+		mutate func(map[string]any)
+		want   error
+	}{
+		{"expired", "kid-1", func(c map[string]any) { c["exp"] = testNow.Add(-time.Hour).Unix() }, ErrExpired},
+		{"wrong audience", "kid-1", func(c map[string]any) { c["aud"] = "https://other.example.com" }, ErrInvalidAudience},
+		{"audience list", "kid-1", func(c map[string]any) { c["aud"] = []string{"x", testAudience} }, nil},
+		{"wrong issuer", "kid-1", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, ErrInvalidIssuer},
+		{"email not allowed", "kid-1", func(c map[string]any) { c["email"] = "someone@example.com" }, ErrEmailNotAllowed},
+		{"email unverified", "kid-1", func(c map[string]any) { c["email_verified"] = false }, ErrEmailNotAllowed},
+		{"not yet valid", "kid-1", func(c map[string]any) { c["nbf"] = testNow.Add(time.Hour).Unix() }, ErrNotYetValid},
+		{"unknown key", "kid-2", func(c map[string]any) {}, ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.mutate(c)
+			_, err := v.Verify(context.Background(), signToken(t, key, tt.kid, c))
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	other := genRSAKey(t)
+	_, err = v.Verify(context.Background(), signToken(t, other, "kid-1", valid()))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.Verify(context.Background(), "not-a-token")
+	require.ErrorIs(t, err, ErrMalformedToken)
+}
+
+func TestJWKSKeySources(t *testing.T) {
+	key := genRSAKey(t)
+	doc := jwksDocument(t, "kid-1", &key.PublicKey)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, doc, 0o600))
+	fileKeys, err := NewFileKeySource(path)
+	require.NoError(t, err)
+	got, err := fileKeys.Key(context.Background(), "kid-1")
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(got))
+
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_, _ = w.Write(doc)
+	}))
+	t.Cleanup(srv.Close)
+
+	remote := NewRemoteKeySource(srv.URL, srv.Client())
+	now := testNow
+	remote.now = func() time.Time { return now }
+	got, err = remote.Key(context.Background(), "kid-1")
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(got))
+
+	// cached
+	_, err = remote.Key(context.Background(), "kid-1")
+	require.NoError(t, err)
+	require.Equal(t, 1, fetches)
+
+	// unknown kid refetches only after the backoff
+	_, err = remote.Key(context.Background(), "kid-2")
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.Equal(t, 1, fetches)
+	now = now.Add(2 * minRefreshBackoff)
+	_, err = remote.Key(context.Background(), "kid-2")
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.Equal(t, 2, fetches)
+}
+
+func genRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	hdr, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	body, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(hdr) + "." + base64.RawURLEncoding.EncodeToString(body)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwksDocument(t *testing.T, kid string, pub *rsa.PublicKey) []byte {
+	t.Helper()
+	doc, err := json.Marshal(jwks{Keys: []jwk{{
+		Kid: kid,
+		Kty: "RSA",
+		Alg: "RS256",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	return doc
+}
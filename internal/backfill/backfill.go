@@ -0,0 +1,241 @@
+// Package backfill processes messages that are already in a Healthcare API
+// HL7v2 store, for new deployments or recovery after an outage, without
+// republishing Pub/Sub notifications.
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/entity"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultPageSize    = 100
+	defaultConcurrency = 4
+)
+
+type Client interface {
+	ListHL7V2Messages(ctx context.Context, storePath, filter, pageToken string, pageSize int64) ([]string, string, error)
+	GetHL7V2Message(string) ([]byte, error)
+}
+
+type Config struct {
+	// Store is the HL7v2 store path: projects/.../hl7V2Stores/X.
+	Store string
+	// Filter is passed to messages.list as-is and combined with Since.
+	Filter string
+	Since  time.Time
+	// PageSize is the number of messages listed (and checkpointed) at a time.
+	PageSize int64
+	// Concurrency bounds how many messages are fetched and saved at once.
+	// Messages for the same exam may be saved out of order unless it is 1.
+	Concurrency int
+	// CheckpointPath, if set, records progress after each page so an
+	// interrupted run can pick up where it left off.
+	CheckpointPath string
+	// DeadLetters, if set, records messages that fail to save permanently.
+	DeadLetters api.DeadLetterStore
+	// Deduper, if set, skips messages already processed within
+	// DedupeWindow, whether by a push or an earlier run, and records those
+	// this run processes.
+	Deduper      api.Deduper
+	DedupeWindow time.Duration
+}
+
+// Checkpoint is the progress of a backfill, written as JSON.
+type Checkpoint struct {
+	Store     string    `json:"store"`
+	Filter    string    `json:"filter"`
+	PageToken string    `json:"page_token"`
+	Processed int       `json:"processed"`
+	Skipped   int       `json:"skipped"`
+	Failed    int       `json:"failed"`
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListFilter combines a user filter with a lower bound on sendTime.
+func ListFilter(filter string, since time.Time) string {
+	var clauses []string
+	if f := strings.TrimSpace(filter); f != "" {
+		clauses = append(clauses, f)
+	}
+	if !since.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("sendTime >= %q", since.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// Run lists the store page by page, fetching and saving each message. A page
+// is checkpointed only once all of its messages are done, so resuming may
+// redo part of a page but never skips one; with a Deduper, the messages
+// already saved are skipped when it's redone. A message that fails permanently
+// is done: it's counted as failed and dead-lettered. One that can be retried
+// (a fetch or database error) stops the run before its page is checkpointed.
+func Run(ctx context.Context, client Client, store api.HL7Store, cfg Config) (Checkpoint, error) {
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = defaultPageSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	filter := ListFilter(cfg.Filter, cfg.Since)
+
+	cp, err := loadCheckpoint(cfg.CheckpointPath)
+	if err != nil {
+		return cp, err
+	}
+	switch {
+	case cp.Store == "":
+		cp = Checkpoint{Store: cfg.Store, Filter: filter}
+	case cp.Store != cfg.Store || cp.Filter != filter:
+		return cp, fmt.Errorf("checkpoint %s is for store %q with filter %q; remove it or use another path", cfg.CheckpointPath, cp.Store, cp.Filter)
+	case cp.Done:
+		log.Info().Str("checkpoint", cfg.CheckpointPath).Msg("backfill already complete")
+		return cp, nil
+	default:
+		log.Info().Int("processed", cp.Processed).Msg("resuming backfill from checkpoint")
+	}
+
+	for {
+		names, next, err := client.ListHL7V2Messages(ctx, cfg.Store, filter, cp.PageToken, cfg.PageSize)
+		if err != nil {
+			return cp, err
+		}
+		processed, skipped, failed, retry := processPage(ctx, client, store, cfg, names)
+		if err := ctx.Err(); err != nil {
+			return cp, err
+		}
+		if retry > 0 {
+			return cp, fmt.Errorf("%d message(s) failed with a retryable error; run again to resume from the last checkpoint", retry)
+		}
+		cp.Processed += processed
+		cp.Skipped += skipped
+		cp.Failed += failed
+		cp.PageToken = next
+		cp.Done = next == ""
+		if err := saveCheckpoint(cfg.CheckpointPath, &cp); err != nil {
+			return cp, err
+		}
+		log.Info().Int("processed", cp.Processed).Int("skipped", cp.Skipped).Int("failed", cp.Failed).Msg("backfill page done")
+		if cp.Done {
+			return cp, nil
+		}
+	}
+}
+
+func processPage(ctx context.Context, client Client, store api.HL7Store, cfg Config, names []string) (processed, skipped, failed, retry int) {
+	var mu sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.Concurrency)
+	for _, name := range names {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			duplicate, err := processOne(ctx, client, store, cfg, name)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case duplicate:
+				skipped++
+			case err == nil:
+				processed++
+			case api.Classify(err).Permanent():
+				failed++
+			default:
+				retry++
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return processed, skipped, failed, retry
+}
+
+// processOne fetches and saves a message, unless it was already processed.
+// A permanent failure is dead-lettered; anything else is left to the next
+// run.
+func processOne(ctx context.Context, client Client, store api.HL7Store, cfg Config, name string) (bool, error) {
+	raw, err := client.GetHL7V2Message(name)
+	if err != nil {
+		log.Warn().Err(err).Str("hl7_path", name).Msg("couldn't fetch message")
+		return false, &api.Error{Category: api.CategoryUpstreamFetch, Err: err}
+	}
+	controlID, duplicate, err := api.SaveOnce(ctx, store, cfg.Deduper, cfg.DedupeWindow, name, raw)
+	if duplicate {
+		log.Debug().Str("hl7_path", name).Str("control_id", controlID).Msg("skipping message already processed")
+	}
+	if err == nil {
+		return duplicate, nil
+	}
+	category := api.Classify(err)
+	log.Warn().Err(err).Str("hl7_path", name).Str("control_id", controlID).Str("category", string(category)).Msg("couldn't save message")
+	if category.Permanent() && cfg.DeadLetters != nil {
+		if _, dlErr := cfg.DeadLetters.RecordFailure(ctx, entity.FailedMessage{
+			StorePath:     name,
+			Raw:           raw,
+			ControlID:     controlID,
+			ErrorCategory: string(category),
+			ErrorMessage:  err.Error(),
+		}); dlErr != nil {
+			log.Error().Err(dlErr).Str("hl7_path", name).Msg("couldn't record failed message")
+		}
+	}
+	return false, err
+}
+
+func loadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	if path == "" {
+		return cp, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from operator config
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes through a temp file so a crash mid-write can't leave
+// a truncated checkpoint behind.
+func saveCheckpoint(path string, cp *Checkpoint) error {
+	if path == "" {
+		return nil
+	}
+	cp.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
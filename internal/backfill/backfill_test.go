@@ -0,0 +1,286 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/internal/testing/fakehealthcare"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+const testStore = "projects/p/locations/l/datasets/d/hl7V2Stores/s"
+
+type syncStore struct {
+	mu         sync.Mutex
+	controlIDs []string
+	// errs fails the save of a control ID, once
+	errs map[string]error
+}
+
+func (s *syncStore) save(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err, ok := s.errs[id]; ok {
+		delete(s.errs, id)
+		return err
+	}
+	s.controlIDs = append(s.controlIDs, id)
+	return nil
+}
+
+func (s *syncStore) SaveORM(ctx context.Context, o *entity.Order) error {
+	return s.save(o.Message.ControlID)
+}
+
+func (s *syncStore) SaveORU(ctx context.Context, o *entity.Observation) error {
+	return s.save(o.Message.ControlID)
+}
+
+func (s *syncStore) SaveADT(ctx context.Context, a *entity.Admission) error {
+	return s.save(a.Message.ControlID)
+}
+
+func (s *syncStore) SaveMerge(ctx context.Context, m *entity.Merge) error {
+	return s.save(m.Message.ControlID)
+}
+
+func (s *syncStore) SaveSIU(ctx context.Context, a *entity.Appointment) error {
+	return s.save(a.Message.ControlID)
+}
+
+func (s *syncStore) GetProcedures(context.Context, int32) ([]byte, error) { return nil, nil }
+
+func (s *syncStore) UpdateProcedures(context.Context, []byte) (int, int, error) { return 0, 0, nil }
+
+func (s *syncStore) sorted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := slices.Clone(s.controlIDs)
+	slices.Sort(out)
+	return out
+}
+
+func newFake(t *testing.T) (*fakehealthcare.Server, *api.Hl7Client) {
+	t.Helper()
+	fake := fakehealthcare.New()
+	t.Cleanup(fake.Close)
+	base := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	for i, typ := range []string{"ORM", "ORU", "ORM", "ORU", "ORU"} {
+		id := string(rune('a' + i))
+		fake.Add(testStore, fakehealthcare.Message{
+			Data:        []byte("MSH|^~\\&|RIS|FAC|||20250401000000||" + typ + "^R01|" + id + "|P|2.3\rPID|1||MRN\rORC|RE|ACC" + id + "\rOBR|1|ACC" + id + "|"),
+			MessageType: typ,
+			SendTime:    base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	client, err := api.NewHl7Client(context.Background(), option.WithEndpoint(fake.URL()), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return fake, client
+}
+
+func TestRun_FilterAndConcurrency(t *testing.T) {
+	fake, client := newFake(t)
+	store := &syncStore{}
+	cp, err := Run(context.Background(), client, store, Config{
+		Store:       testStore,
+		Filter:      `messageType = "ORU"`,
+		Since:       time.Date(2025, time.April, 1, 2, 0, 0, 0, time.UTC),
+		PageSize:    1,
+		Concurrency: 3,
+	})
+	require.NoError(t, err)
+	require.True(t, cp.Done)
+	require.Equal(t, 2, cp.Processed)
+	require.Equal(t, []string{"d", "e"}, store.sorted())
+	require.Equal(t, 0, fake.Gets(testStore+"/messages/2"))
+}
+
+func TestRun_ResumesFromCheckpoint(t *testing.T) {
+	fake, client := newFake(t)
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	data, err := json.Marshal(Checkpoint{Store: testStore, PageToken: "2", Processed: 2})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	store := &syncStore{}
+	cfg := Config{Store: testStore, PageSize: 2, CheckpointPath: path}
+	cp, err := Run(context.Background(), client, store, cfg)
+	require.NoError(t, err)
+	require.True(t, cp.Done)
+	require.Equal(t, 5, cp.Processed)
+	require.Equal(t, []string{"c", "d", "e"}, store.sorted())
+	require.Equal(t, 0, fake.Gets(testStore+"/messages/1"))
+
+	saved, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	require.True(t, saved.Done)
+	require.Equal(t, 5, saved.Processed)
+
+	// a finished checkpoint is a no-op
+	_, err = Run(context.Background(), client, store, cfg)
+	require.NoError(t, err)
+	require.Len(t, store.sorted(), 3)
+
+	// and one for another store is refused
+	cfg.Store = testStore + "2"
+	_, err = Run(context.Background(), client, store, cfg)
+	require.Error(t, err)
+}
+
+// flakyClient fails to fetch a message, once.
+type flakyClient struct {
+	Client
+	mu   sync.Mutex
+	fail string
+}
+
+func (c *flakyClient) GetHL7V2Message(name string) ([]byte, error) {
+	c.mu.Lock()
+	failed := name == c.fail
+	if failed {
+		c.fail = ""
+	}
+	c.mu.Unlock()
+	if failed {
+		return nil, errors.New("503 Service Unavailable")
+	}
+	return c.Client.GetHL7V2Message(name)
+}
+
+type recordingDeadLetters struct {
+	api.DeadLetterStore
+	mu         sync.Mutex
+	controlIDs []string
+}
+
+func (d *recordingDeadLetters) RecordFailure(ctx context.Context, f entity.FailedMessage) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.controlIDs = append(d.controlIDs, f.ControlID)
+	return int64(len(d.controlIDs)), nil
+}
+
+// mapDeduper remembers every key it's given, for good.
+type mapDeduper struct {
+	mu   sync.Mutex
+	seen map[string]entity.ProcessedMessage
+}
+
+func (d *mapDeduper) LookupProcessed(ctx context.Context, keys []string, window time.Duration) (entity.ProcessedMessage, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, k := range keys {
+		if pm, ok := d.seen[k]; ok {
+			return pm, true, nil
+		}
+	}
+	return entity.ProcessedMessage{}, false, nil
+}
+
+func (d *mapDeduper) RecordProcessed(ctx context.Context, keys []string, pm entity.ProcessedMessage) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = map[string]entity.ProcessedMessage{}
+	}
+	for _, k := range keys {
+		d.seen[k] = pm
+	}
+	return nil
+}
+
+func TestRun_FetchFailureIsRetried(t *testing.T) {
+	_, hc := newFake(t)
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	client := &flakyClient{Client: hc, fail: testStore + "/messages/4"}
+	store := &syncStore{}
+	dl := &recordingDeadLetters{}
+	cfg := Config{Store: testStore, PageSize: 2, CheckpointPath: path, DeadLetters: dl, Deduper: &mapDeduper{}, DedupeWindow: time.Hour}
+
+	_, err := Run(context.Background(), client, store, cfg)
+	require.Error(t, err)
+	saved, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	require.False(t, saved.Done)
+	require.Equal(t, 2, saved.Processed, "the page with the failed fetch isn't checkpointed")
+	require.Empty(t, dl.controlIDs)
+
+	// c was saved before the page failed, so it's skipped when the page is
+	// redone
+	cp, err := Run(context.Background(), client, store, cfg)
+	require.NoError(t, err)
+	require.True(t, cp.Done)
+	require.Equal(t, 4, cp.Processed)
+	require.Equal(t, 1, cp.Skipped)
+	require.Equal(t, 0, cp.Failed)
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, store.sorted())
+}
+
+func TestRun_TransientSaveErrorIsRetried(t *testing.T) {
+	_, client := newFake(t)
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	store := &syncStore{errs: map[string]error{
+		"b": entity.ValidationError{Field: "accession", Reason: "missing"},
+		"d": &api.Error{Category: api.CategoryTransientDB, Err: errors.New("connection refused")},
+	}}
+	dl := &recordingDeadLetters{}
+	cfg := Config{Store: testStore, PageSize: 2, CheckpointPath: path, DeadLetters: dl, Deduper: &mapDeduper{}, DedupeWindow: time.Hour}
+
+	_, err := Run(context.Background(), client, store, cfg)
+	require.Error(t, err)
+	saved, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	require.False(t, saved.Done)
+	require.Equal(t, 1, saved.Processed)
+	// the invalid message is done with; the transient one isn't
+	require.Equal(t, 1, saved.Failed)
+	require.Equal(t, []string{"b"}, dl.controlIDs)
+
+	cp, err := Run(context.Background(), client, store, cfg)
+	require.NoError(t, err)
+	require.True(t, cp.Done)
+	require.Equal(t, 3, cp.Processed)
+	require.Equal(t, 1, cp.Skipped)
+	require.Equal(t, 1, cp.Failed)
+	require.Equal(t, []string{"a", "c", "d", "e"}, store.sorted())
+	require.Equal(t, []string{"b"}, dl.controlIDs)
+}
+
+func TestRun_SkipsPushedMessages(t *testing.T) {
+	_, client := newFake(t)
+	store := &syncStore{}
+	dd := &mapDeduper{}
+	// b was already delivered by Pub/Sub
+	require.NoError(t, dd.RecordProcessed(context.Background(), []string{"resource:" + testStore + "/messages/2"}, entity.ProcessedMessage{
+		ControlID:  "b",
+		StatusCode: 201,
+		Response:   []byte(`{"message": "message saved"}`),
+	}))
+
+	cp, err := Run(context.Background(), client, store, Config{Store: testStore, Deduper: dd, DedupeWindow: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, 4, cp.Processed)
+	require.Equal(t, 1, cp.Skipped)
+	require.Equal(t, []string{"a", "c", "d", "e"}, store.sorted())
+	// and a later push of any of them would be skipped
+	_, ok, err := dd.LookupProcessed(context.Background(), []string{"msh:RIS|e"}, time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestListFilter(t *testing.T) {
+	since := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "", ListFilter(" ", time.Time{}))
+	require.Equal(t, `messageType = "ORU"`, ListFilter(`messageType = "ORU"`, time.Time{}))
+	require.Equal(t, `messageType = "ORU" AND sendTime >= "2025-04-01T00:00:00Z"`, ListFilter(`messageType = "ORU"`, since))
+}
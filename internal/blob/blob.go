@@ -0,0 +1,92 @@
+// Package blob stores report attachments outside the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key. Keys are the hex SHA-256 of the contents, so
+// putting the same key twice stores the same bytes.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Dir is a Store on local disk. Each blob is a file named by its key, under
+// a subdirectory named by the key's first two characters.
+type Dir struct {
+	dir string
+}
+
+// NewDir opens (or creates) a blob store in dir.
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating blob dir: %w", err)
+	}
+	return &Dir{dir: dir}, nil
+}
+
+func (d *Dir) path(key string) (string, error) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(d.dir, key[:2], key), nil
+}
+
+// Put writes data to a temp file and renames it into place, so a reader
+// never sees part of a blob.
+func (d *Dir) Put(_ context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error creating blob dir: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	// gone after the rename, unless something failed
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("error writing blob: %w", err)
+	}
+	return nil
+}
+
+func (d *Dir) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- key is checked by path
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading blob: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,42 @@
+package blob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir_PutGet(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	d, err := NewDir(dir)
+	require.NoError(t, err)
+
+	key := "d4b23841275cee2e2db729fab75031305e57691ebadde93f085e2fcea1bf307f"
+	_, err = d.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, d.Put(ctx, key, []byte("Normal.\n")))
+	// putting the same key again is a no-op
+	require.NoError(t, d.Put(ctx, key, []byte("Normal.\n")))
+	data, err := d.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, "Normal.\n", string(data))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "d4"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temp files are cleaned up")
+}
+
+func TestDir_InvalidKey(t *testing.T) {
+	d, err := NewDir(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"", "ab", "../etc/passwd", "ab/cd", `ab\cd`} {
+		require.Error(t, d.Put(context.Background(), key, nil), key)
+		_, err := d.Get(context.Background(), key)
+		require.Error(t, err, key)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/backfill"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/option"
+)
+
+var (
+	backfillStore       string
+	backfillSince       string
+	backfillFilter      string
+	backfillPageSize    int64
+	backfillConcurrency int
+	backfillCheckpoint  string
+	backfillEndpoint    string
+	backfillArchive     string
+)
+
+func init() {
+	f := backfillCmd.Flags()
+	f.StringVarP(&dbURL, "db-url", "d", "", "database URL (required unless DATABASE_URL env var is set)")
+	f.StringVar(&backfillStore, "store", "", "HL7v2 store path (projects/.../locations/.../datasets/.../hl7V2Stores/...)")
+	f.StringVar(&backfillSince, "since", "", "only messages sent at or after this time (RFC 3339 or YYYY-MM-DD)")
+	f.StringVar(&backfillFilter, "filter", "", `messages.list filter, e.g. 'messageType="ORU"'`)
+	f.Int64Var(&backfillPageSize, "page-size", 100, "messages to list (and checkpoint) per page")
+	f.IntVar(&backfillConcurrency, "concurrency", 4, "messages to fetch and save at once; 1 keeps send order")
+	f.StringVar(&backfillCheckpoint, "checkpoint", "", "file to record progress in and resume from")
+	f.StringVar(&backfillEndpoint, "endpoint", "", "Healthcare API endpoint override, e.g. a local fake (disables authentication)")
+	f.StringVar(&backfillArchive, "archive-raw", string(entity.ArchiveGzip), "archive raw HL7 with each message: off, plain or gzip")
+	f.DurationVar(&dedupeWindow, "dedupe-window", 24*time.Hour, "skip messages already processed within this window (0 disables)")
+	_ = backfillCmd.MarkFlagRequired("store")
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Process messages already in a Healthcare API HL7v2 store",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseFlagTime(backfillSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		archive, err := entity.NewArchiveMode(backfillArchive)
+		if err != nil {
+			return err
+		}
+
+		var clientOpts []option.ClientOption
+		if backfillEndpoint != "" {
+			clientOpts = append(clientOpts, option.WithEndpoint(backfillEndpoint), option.WithoutAuthentication())
+		}
+		client, err := api.NewHl7Client(cmd.Context(), clientOpts...)
+		if err != nil {
+			return err
+		}
+		repo, err := connectRepo(cmd)
+		if err != nil {
+			return err
+		}
+		store := entity.NewRepo(repo.DB, entity.WithRawArchive(archive))
+		var deduper api.Deduper
+		if dedupeWindow > 0 {
+			deduper = store
+		}
+
+		cp, err := backfill.Run(cmd.Context(), client, store, backfill.Config{
+			Store:          backfillStore,
+			Filter:         backfillFilter,
+			Since:          since,
+			PageSize:       backfillPageSize,
+			Concurrency:    backfillConcurrency,
+			CheckpointPath: backfillCheckpoint,
+			DeadLetters:    store,
+			Deduper:        deduper,
+			DedupeWindow:   dedupeWindow,
+		})
+		fmt.Fprintf(cmd.OutOrStdout(), "processed %d, skipped %d, failed %d\n", cp.Processed, cp.Skipped, cp.Failed)
+		if err != nil {
+			if backfillCheckpoint != "" {
+				log.Info().Str("checkpoint", backfillCheckpoint).Msg("rerun with the same flags to resume")
+			}
+			return err
+		}
+		return nil
+	},
+}
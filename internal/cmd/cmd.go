@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -12,7 +13,11 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/auth"
+	"github.com/s-hammon/volta/internal/blob"
 	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/internal/notify"
+	"github.com/s-hammon/volta/internal/spool"
 	"github.com/spf13/cobra"
 
 	"github.com/s-hammon/p"
@@ -25,6 +30,25 @@ var (
 	port      string
 	debugMode bool
 
+	authAudience string
+	authIssuers  []string
+	authEmails   []string
+	authJWKSFile string
+	authJWKSURL  string
+
+	dedupeWindow time.Duration
+	archiveRaw   string
+	batchWorkers int
+	spoolDir     string
+	lockScope    string
+	transitions  string
+	sections     string
+	blobDir      string
+	critical     string
+	webhook      string
+	notifyRetry  time.Duration
+	spoolRetry   time.Duration
+
 	db *pgxpool.Pool
 
 	ctx    context.Context
@@ -39,6 +63,23 @@ func init() {
 	serveCmd.PersistentFlags().StringVarP(&port, "port", "p", "8080", "port to listen on (default: 8080)")
 	serveCmd.PersistentFlags().StringVarP(&dbURL, "db-url", "d", "", "database URL (required unless DATABASE_URL env var is set or using debug mode)")
 	serveCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "D", false, "enable debug mode; results are just logged to stdout, not written to the database (cannot use with -d)")
+	serveCmd.PersistentFlags().StringVar(&authAudience, "auth-audience", "", "require Pub/Sub push OIDC tokens with this audience (disabled if empty)")
+	serveCmd.PersistentFlags().StringSliceVar(&authIssuers, "auth-issuer", auth.GoogleIssuers, "accepted OIDC token issuers")
+	serveCmd.PersistentFlags().StringSliceVar(&authEmails, "auth-email", nil, "service account emails allowed to push messages (default: any)")
+	serveCmd.PersistentFlags().StringVar(&authJWKSFile, "auth-jwks-file", "", "read token signing keys from a local JWKS file instead of fetching them")
+	serveCmd.PersistentFlags().StringVar(&authJWKSURL, "auth-jwks-url", auth.GoogleJWKSURL, "URL to fetch token signing keys from")
+	serveCmd.PersistentFlags().StringVar(&archiveRaw, "archive-raw", "", "store raw HL7 with each message: off, plain or gzip (default from VOLTA_ARCHIVE_RAW, else gzip)")
+	serveCmd.PersistentFlags().IntVar(&batchWorkers, "batch-workers", 8, "messages from one POST /batch request to process at once")
+	serveCmd.PersistentFlags().StringVar(&lockScope, "lock-scope", string(entity.LockAccession), "serialize saves per accession, per patient, or off")
+	serveCmd.PersistentFlags().StringVar(&transitions, "status-transitions", "", "JSON file of allowed exam status transitions (default: built-in graph)")
+	serveCmd.PersistentFlags().StringVar(&sections, "report-sections", "", "JSON file of rules for parsing report sections (default: built-in headers and &IMP)")
+	serveCmd.PersistentFlags().StringVar(&blobDir, "attachment-dir", "", "store report attachment contents in this directory instead of the database")
+	serveCmd.PersistentFlags().StringVar(&critical, "critical-rules", "", "JSON file of rules for detecting critical findings in reports (default: built-in findings and critical OBX-8 flags)")
+	serveCmd.PersistentFlags().StringVar(&webhook, "critical-webhook", "", "POST each new critical result as JSON to this URL (critical results are always logged)")
+	serveCmd.PersistentFlags().DurationVar(&notifyRetry, "critical-retry", entity.DefaultCriticalRetry, "how often to retry sending critical results that failed to send")
+	serveCmd.PersistentFlags().StringVar(&spoolDir, "spool-dir", "", "spool messages to this directory while the database is unavailable (disabled if empty)")
+	serveCmd.PersistentFlags().DurationVar(&spoolRetry, "spool-retry", 5*time.Second, "how often to retry saving spooled messages")
+	serveCmd.PersistentFlags().DurationVar(&dedupeWindow, "dedupe-window", 24*time.Hour, "skip messages already processed within this window (0 disables)")
 }
 
 func Execute(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
@@ -46,7 +87,7 @@ func Execute(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		Use:          "volta",
 		SilenceUsage: true,
 	}
-	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(serveCmd, replayCmd, reprocessCmd, backfillCmd)
 
 	rootCmd.SetArgs(args)
 	rootCmd.SetIn(stdin)
@@ -82,6 +123,10 @@ var serveCmd = &cobra.Command{
 			return cmd.Usage()
 		}
 
+		if notifyRetry <= 0 {
+			return fmt.Errorf("--critical-retry must be positive")
+		}
+
 		log.Info().Str("host", host).Str("port", port).Msg("service configuration")
 
 		client, err := api.NewHl7Client(cmd.Context())
@@ -97,18 +142,122 @@ var serveCmd = &cobra.Command{
 				return err
 			}
 			if err := db.Ping(ctx); err != nil {
-				log.Info().Err(err).Msg("couldn't reach database")
-				return err
+				if spoolDir == "" {
+					log.Info().Err(err).Msg("couldn't reach database")
+					return err
+				}
+				log.Warn().Err(err).Msg("couldn't reach database; spooling messages until it's available")
+			} else {
+				log.Info().Msg("connected to database")
 			}
-			log.Info().Msg("connected to database")
 		} else {
 			log.Info().Msg("debug mode enabled; printing messages to stdout")
 		}
 
-		store := entity.NewRepo(db)
+		opts := []api.Option{api.WithBatchWorkers(batchWorkers)}
+		if authAudience != "" {
+			verifier, err := newVerifier()
+			if err != nil {
+				log.Info().Err(err).Msg("failed to configure push authentication")
+				return err
+			}
+			opts = append(opts, api.WithVerifier(verifier))
+			log.Info().Str("audience", authAudience).Strs("emails", authEmails).Msg("push authentication enabled")
+		}
+
+		if archiveRaw == "" {
+			archiveRaw = p.Coalesce(os.Getenv("VOLTA_ARCHIVE_RAW"), string(entity.ArchiveGzip))
+		}
+		archive, err := entity.NewArchiveMode(archiveRaw)
+		if err != nil {
+			return err
+		}
+
+		scope, err := entity.NewLockScope(lockScope)
+		if err != nil {
+			return err
+		}
+
+		repoOpts := []entity.RepoOption{entity.WithRawArchive(archive), entity.WithLockScope(scope)}
+		if transitions != "" {
+			data, err := os.ReadFile(transitions) // #nosec G304 -- path from operator flag
+			if err != nil {
+				return err
+			}
+			t, err := entity.ParseTransitions(data)
+			if err != nil {
+				return err
+			}
+			repoOpts = append(repoOpts, entity.WithTransitions(t))
+		}
+		if sections != "" {
+			data, err := os.ReadFile(sections) // #nosec G304 -- path from operator flag
+			if err != nil {
+				return err
+			}
+			r, err := entity.ParseSectionRules(data)
+			if err != nil {
+				return err
+			}
+			repoOpts = append(repoOpts, entity.WithSectionRules(r))
+		}
+		if blobDir != "" {
+			blobs, err := blob.NewDir(blobDir)
+			if err != nil {
+				return err
+			}
+			repoOpts = append(repoOpts, entity.WithBlobStore(blobs))
+		}
+		if critical != "" {
+			data, err := os.ReadFile(critical) // #nosec G304 -- path from operator flag
+			if err != nil {
+				return err
+			}
+			r, err := entity.ParseCriticalRules(data)
+			if err != nil {
+				return err
+			}
+			repoOpts = append(repoOpts, entity.WithCriticalRules(r))
+		}
+		notifiers := []entity.CriticalNotifier{notify.Log{}}
+		if webhook != "" {
+			notifiers = append(notifiers, notify.NewWebhook(webhook, nil))
+		}
+		repoOpts = append(repoOpts, entity.WithCriticalNotifiers(notifiers...))
+
+		store := entity.NewRepo(db, repoOpts...)
+		if !debugMode {
+			opts = append(opts,
+				api.WithDeadLetters(store),
+				api.WithRawMessages(store),
+				api.WithStaleUpdates(store),
+				api.WithStatusHistory(store),
+				api.WithPatientMerges(store),
+				api.WithAppointmentMetrics(store),
+				api.WithReportHistory(store),
+				api.WithAttachments(store),
+				api.WithCriticalResults(store),
+			)
+			go store.NotifyCritical(ctx, notifyRetry)
+			if dedupeWindow > 0 {
+				opts = append(opts, api.WithDeduper(store, dedupeWindow))
+				go pruneProcessed(ctx, store, dedupeWindow)
+			}
+			if spoolDir != "" {
+				sp, err := spool.Open(spoolDir)
+				if err != nil {
+					log.Info().Err(err).Msg("failed to open spool")
+					return err
+				}
+				defer sp.Close()
+				opts = append(opts, api.WithSpool(sp))
+				go sp.Drain(ctx, spoolRetry, api.SaveSpooled(store, store))
+				log.Info().Str("dir", spoolDir).Int("depth", sp.Depth()).Msg("spool enabled")
+			}
+		}
 		srv := &http.Server{
 			Addr:              net.JoinHostPort(host, port),
-			Handler:           api.New(store, client, debugMode),
+			Handler:           api.New(store, client, debugMode, opts...),
 			ReadHeaderTimeout: 3 * time.Second,
 		}
 
@@ -117,6 +266,41 @@ var serveCmd = &cobra.Command{
 	},
 }
 
+func newVerifier() (*auth.Verifier, error) {
+	cfg := auth.Config{
+		Audience:      authAudience,
+		Issuers:       authIssuers,
+		AllowedEmails: authEmails,
+	}
+	if authJWKSFile != "" {
+		keys, err := auth.NewFileKeySource(authJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewVerifier(keys, cfg), nil
+	}
+	return auth.NewVerifier(auth.NewRemoteKeySource(authJWKSURL, nil), cfg), nil
+}
+
+// pruneProcessed drops dedupe records once they fall out of the window.
+func pruneProcessed(ctx context.Context, store *entity.HL7Repo, window time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.PruneProcessed(ctx, window)
+			if err != nil {
+				log.Warn().Err(err).Msg("couldn't prune processed messages")
+				continue
+			}
+			log.Debug().Int64("deleted", n).Msg("pruned processed messages")
+		}
+	}
+}
+
 func cleanup() {
 	log.Info().Msg("shutting down services...")
 
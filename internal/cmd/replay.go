@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayStatus string
+	replayLimit  int32
+	replayAll    bool
+)
+
+func init() {
+	replayCmd.PersistentFlags().StringVarP(&dbURL, "db-url", "d", "", "database URL (required unless DATABASE_URL env var is set)")
+	replayListCmd.Flags().StringVar(&replayStatus, "status", entity.FailedPending, "only list entries with this status (pending, replayed, discarded)")
+	replayListCmd.Flags().Int32Var(&replayLimit, "limit", 100, "maximum number of entries to list")
+	replayRunCmd.Flags().BoolVar(&replayAll, "all", false, "replay every pending entry")
+
+	replayCmd.AddCommand(replayListCmd, replayShowCmd, replayRunCmd, replayDiscardCmd)
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Inspect and replay messages that failed to save",
+}
+
+var replayListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List failed messages",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := connectRepo(cmd)
+		if err != nil {
+			return err
+		}
+		msgs, err := repo.ListFailedMessages(cmd.Context(), replayStatus, 0, replayLimit)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCONTROL ID\tCATEGORY\tATTEMPTS\tLAST FAILED\tERROR")
+		for _, m := range msgs {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n", m.ID, m.ControlID, m.ErrorCategory, m.Attempts, m.LastFailedAt.Format("2006-01-02 15:04:05"), m.ErrorMessage)
+		}
+		return tw.Flush()
+	},
+}
+
+var replayShowCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Print a failed message and its last error",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseFailedID(args[0])
+		if err != nil {
+			return err
+		}
+		repo, err := connectRepo(cmd)
+		if err != nil {
+			return err
+		}
+		m, err := repo.GetFailedMessage(cmd.Context(), id)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "id:          %d\n", m.ID)
+		fmt.Fprintf(out, "status:      %s\n", m.Status)
+		fmt.Fprintf(out, "store path:  %s\n", m.StorePath)
+		fmt.Fprintf(out, "control id:  %s\n", m.ControlID)
+		fmt.Fprintf(out, "category:    %s\n", m.ErrorCategory)
+		fmt.Fprintf(out, "error:       %s\n", m.ErrorMessage)
+		fmt.Fprintf(out, "attempts:    %d\n", m.Attempts)
+		fmt.Fprintf(out, "first:       %s\n", m.FirstFailedAt)
+		fmt.Fprintf(out, "last:        %s\n\n", m.LastFailedAt)
+		fmt.Fprintln(out, string(m.Raw))
+		return nil
+	},
+}
+
+var replayRunCmd = &cobra.Command{
+	Use:   "run [ID...]",
+	Short: "Reprocess failed messages",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !replayAll {
+			return fmt.Errorf("provide one or more IDs or --all")
+		}
+		repo, err := connectRepo(cmd)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, 0, len(args))
+		for _, arg := range args {
+			id, err := parseFailedID(arg)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if replayAll {
+			// replaying moves entries out of pending, so keep paging from the
+			// last ID seen rather than from zero
+			var cursor int64
+			for {
+				msgs, err := repo.ListFailedMessages(cmd.Context(), entity.FailedPending, cursor, 100)
+				if err != nil {
+					return err
+				}
+				if len(msgs) == 0 {
+					break
+				}
+				for _, m := range msgs {
+					ids = append(ids, int64(m.ID))
+				}
+				cursor = int64(msgs[len(msgs)-1].ID)
+			}
+		}
+
+		var failed int
+		for _, id := range ids {
+			m, err := api.Replay(cmd.Context(), repo, repo, id)
+			if err != nil {
+				failed++
+				log.Warn().Err(err).Int64("id", id).Str("control_id", m.ControlID).Msg("replay failed")
+				continue
+			}
+			log.Info().Int64("id", id).Str("control_id", m.ControlID).Msg("replayed")
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "replayed %d of %d\n", len(ids)-failed, len(ids))
+		if failed > 0 {
+			return fmt.Errorf("%d message(s) failed to replay", failed)
+		}
+		return nil
+	},
+}
+
+var replayDiscardCmd = &cobra.Command{
+	Use:   "discard ID...",
+	Short: "Mark failed messages as discarded",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := connectRepo(cmd)
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			id, err := parseFailedID(arg)
+			if err != nil {
+				return err
+			}
+			if _, err := repo.GetFailedMessage(cmd.Context(), id); err != nil {
+				return fmt.Errorf("failed message %d: %w", id, err)
+			}
+			discarded, err := repo.DiscardFailedMessage(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			if !discarded {
+				return fmt.Errorf("failed message %d: %w", id, api.ErrNotPending)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "discarded %d\n", id)
+		}
+		return nil
+	},
+}
+
+func parseFailedID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid ID %q", s)
+	}
+	return id, nil
+}
+
+// connectRepo opens the pool shared with cleanup() for one-shot commands.
+func connectRepo(cmd *cobra.Command) (*entity.HL7Repo, error) {
+	if err := resolveDBURL(); err != nil {
+		return nil, err
+	}
+	var err error
+	db, err = pgxpool.New(cmd.Context(), dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(cmd.Context()); err != nil {
+		return nil, fmt.Errorf("couldn't reach database: %w", err)
+	}
+	return entity.NewRepo(db), nil
+}
+
+func resolveDBURL() error {
+	if dbURL == "" {
+		dbURL = os.Getenv("DATABASE_URL")
+	}
+	if dbURL == "" {
+		return fmt.Errorf("database URL is required (--db-url or DATABASE_URL)")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/internal/reprocess"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reprocessDir          string
+	reprocessSince        string
+	reprocessUntil        string
+	reprocessSendingApp   string
+	reprocessType         string
+	reprocessTargetSchema string
+	reprocessArchive      string
+	reprocessDryRun       bool
+)
+
+func init() {
+	f := reprocessCmd.Flags()
+	f.StringVarP(&dbURL, "db-url", "d", "", "database URL (required unless DATABASE_URL env var is set)")
+	f.StringVar(&reprocessDir, "dir", "", "read .hl7 files from this directory instead of the raw_messages archive")
+	f.StringVar(&reprocessSince, "since", "", "only messages received at or after this time (RFC 3339 or YYYY-MM-DD)")
+	f.StringVar(&reprocessUntil, "until", "", "only messages received before this time (RFC 3339 or YYYY-MM-DD)")
+	f.StringVar(&reprocessSendingApp, "sending-app", "", "only messages from this sending application (MSH-3)")
+	f.StringVar(&reprocessType, "type", "", "only messages of this type (MSH-9.1, e.g. ORU)")
+	f.StringVar(&reprocessTargetSchema, "target-schema", "", "write to this schema (e.g. a migrated shadow schema) instead of the default search_path")
+	f.StringVar(&reprocessArchive, "archive-raw", string(entity.ArchiveOff), "archive raw HL7 in the target: off, plain or gzip")
+	f.BoolVar(&reprocessDryRun, "dry-run", false, "list matching messages without saving them")
+}
+
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Re-run archived messages through the current mappings",
+	Long: `Reads raw messages from the raw_messages archive (or a directory of .hl7
+files with --dir) and saves them again, in received_at order. Use
+--target-schema to rebuild into a shadow schema and compare before cutover;
+the schema must already exist with migrations applied.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := reprocessFilter()
+		if err != nil {
+			return err
+		}
+		archive, err := entity.NewArchiveMode(reprocessArchive)
+		if err != nil {
+			return err
+		}
+
+		var src reprocess.Source
+		if reprocessDir != "" {
+			dirSrc, err := reprocess.NewDirSource(reprocessDir, filter)
+			if err != nil {
+				return err
+			}
+			log.Info().Int("messages", dirSrc.Len()).Str("dir", reprocessDir).Msg("found messages to reprocess")
+			src = dirSrc
+		} else {
+			archiveRepo, err := connectRepo(cmd)
+			if err != nil {
+				return err
+			}
+			src = reprocess.NewDBSource(archiveRepo, filter, 0)
+		}
+
+		var target *entity.HL7Repo
+		if !reprocessDryRun {
+			target, err = connectTarget(cmd, reprocessTargetSchema, archive)
+			if err != nil {
+				return err
+			}
+		}
+
+		stats, err := reprocess.Run(cmd.Context(), src, target, reprocessDryRun)
+		fmt.Fprintf(cmd.OutOrStdout(), "processed %d, failed %d\n", stats.Processed, stats.Failed)
+		if err != nil {
+			return err
+		}
+		if stats.Failed > 0 {
+			return fmt.Errorf("%d message(s) failed to reprocess", stats.Failed)
+		}
+		return nil
+	},
+}
+
+func reprocessFilter() (reprocess.Filter, error) {
+	f := reprocess.Filter{SendingApp: reprocessSendingApp, MessageType: reprocessType}
+	var err error
+	if f.Since, err = parseFlagTime(reprocessSince); err != nil {
+		return f, fmt.Errorf("invalid --since: %w", err)
+	}
+	if f.Until, err = parseFlagTime(reprocessUntil); err != nil {
+		return f, fmt.Errorf("invalid --until: %w", err)
+	}
+	return f, nil
+}
+
+func parseFlagTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// connectTarget opens a separate pool for writes so that --target-schema can
+// set search_path without affecting where the archive is read from.
+func connectTarget(cmd *cobra.Command, schema string, archive entity.ArchiveMode) (*entity.HL7Repo, error) {
+	if err := resolveDBURL(); err != nil {
+		return nil, err
+	}
+	cfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if schema != "" {
+		cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	}
+	pool, err := pgxpool.NewWithConfig(cmd.Context(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	var migrated bool
+	if err := pool.QueryRow(cmd.Context(), "SELECT to_regclass('messages') IS NOT NULL").Scan(&migrated); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("couldn't reach target database: %w", err)
+	}
+	if !migrated {
+		pool.Close()
+		return nil, fmt.Errorf("target schema %q has no messages table; run migrations against it first", schema)
+	}
+	return entity.NewRepo(pool, entity.WithRawArchive(archive)), nil
+}
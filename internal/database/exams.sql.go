@@ -27,9 +27,10 @@ WITH upsert as (
         exam_cancelled_dt, -- $11
         message_id, -- $12
 	sending_app, -- $13
-	priority -- $14
+	priority, -- $14
+        last_event_dt -- $15
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
     ON CONFLICT (sending_app, accession) DO UPDATE
     SET
         updated_at = CURRENT_TIMESTAMP,
@@ -43,18 +44,28 @@ WITH upsert as (
         begin_exam_dt = COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt),
         end_exam_dt = COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt),
         exam_cancelled_dt = COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt),
-        message_id = EXCLUDED.message_id
+        message_id = EXCLUDED.message_id,
+        last_event_dt = GREATEST(EXCLUDED.last_event_dt, exams.last_event_dt)
     WHERE
-        exams.visit_id IS DISTINCT FROM EXCLUDED.visit_id
-        OR exams.mrn_id IS DISTINCT FROM EXCLUDED.mrn_id
-        OR exams.site_id IS DISTINCT FROM EXCLUDED.site_id
-        OR exams.procedure_id IS DISTINCT FROM EXCLUDED.procedure_id
-        OR exams.ordering_physician_id IS DISTINCT FROM EXCLUDED.ordering_physician_id
-        OR COALESCE(NULLIF(EXCLUDED.current_status, ''), exams.current_status) IS DISTINCT FROM exams.current_status
-        OR COALESCE(EXCLUDED.schedule_dt, exams.schedule_dt) IS DISTINCT FROM exams.schedule_dt
-        OR COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt) IS DISTINCT FROM exams.begin_exam_dt
-        OR COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt) IS DISTINCT FROM exams.end_exam_dt
-        OR COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt) IS DISTINCT FROM exams.exam_cancelled_dt
+        -- never let an older message overwrite a newer one
+        (
+            exams.last_event_dt IS NULL
+            OR EXCLUDED.last_event_dt IS NULL
+            OR EXCLUDED.last_event_dt >= exams.last_event_dt
+        )
+        AND (
+            exams.visit_id IS DISTINCT FROM EXCLUDED.visit_id
+            OR exams.mrn_id IS DISTINCT FROM EXCLUDED.mrn_id
+            OR exams.site_id IS DISTINCT FROM EXCLUDED.site_id
+            OR exams.procedure_id IS DISTINCT FROM EXCLUDED.procedure_id
+            OR exams.ordering_physician_id IS DISTINCT FROM EXCLUDED.ordering_physician_id
+            OR COALESCE(NULLIF(EXCLUDED.current_status, ''), exams.current_status) IS DISTINCT FROM exams.current_status
+            OR COALESCE(EXCLUDED.schedule_dt, exams.schedule_dt) IS DISTINCT FROM exams.schedule_dt
+            OR COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt) IS DISTINCT FROM exams.begin_exam_dt
+            OR COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt) IS DISTINCT FROM exams.end_exam_dt
+            OR COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt) IS DISTINCT FROM exams.exam_cancelled_dt
+            OR EXCLUDED.last_event_dt IS DISTINCT FROM exams.last_event_dt
+        )
     RETURNING id
 )
 SELECT id FROM upsert
@@ -81,6 +92,7 @@ type CreateExamParams struct {
 	MessageID           pgtype.Int8
 	SendingApp          string
 	Priority            pgtype.Text
+	LastEventDt         pgtype.Timestamp
 }
 
 func (q *Queries) CreateExam(ctx context.Context, arg CreateExamParams) (int64, error) {
@@ -99,6 +111,7 @@ func (q *Queries) CreateExam(ctx context.Context, arg CreateExamParams) (int64,
 		arg.MessageID,
 		arg.SendingApp,
 		arg.Priority,
+		arg.LastEventDt,
 	)
 	var id int64
 	err := row.Scan(&id)
@@ -106,7 +119,7 @@ func (q *Queries) CreateExam(ctx context.Context, arg CreateExamParams) (int64,
 }
 
 const getAllExams = `-- name: GetAllExams :many
-SELECT id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority
+SELECT id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, ordering_physician_id, message_id, sending_app, priority, last_event_dt, replaced_accession, study_instance_uid, result_status, result_event_dt
 FROM exams
 `
 
@@ -127,19 +140,21 @@ func (q *Queries) GetAllExams(ctx context.Context) ([]Exam, error) {
 			&i.MrnID,
 			&i.SiteID,
 			&i.ProcedureID,
-			&i.FinalReportID,
-			&i.AddendumReportID,
 			&i.Accession,
 			&i.CurrentStatus,
 			&i.ScheduleDt,
 			&i.BeginExamDt,
 			&i.EndExamDt,
 			&i.ExamCancelledDt,
-			&i.PrelimReportID,
 			&i.OrderingPhysicianID,
 			&i.MessageID,
 			&i.SendingApp,
 			&i.Priority,
+			&i.LastEventDt,
+			&i.ReplacedAccession,
+			&i.StudyInstanceUid,
+			&i.ResultStatus,
+			&i.ResultEventDt,
 		); err != nil {
 			return nil, err
 		}
@@ -152,7 +167,7 @@ func (q *Queries) GetAllExams(ctx context.Context) ([]Exam, error) {
 }
 
 const getExamById = `-- name: GetExamById :one
-SELECT id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority FROM exams
+SELECT id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, ordering_physician_id, message_id, sending_app, priority, last_event_dt, replaced_accession, study_instance_uid, result_status, result_event_dt FROM exams
 WHERE id = $1
 `
 
@@ -167,26 +182,28 @@ func (q *Queries) GetExamById(ctx context.Context, id int64) (Exam, error) {
 		&i.MrnID,
 		&i.SiteID,
 		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
 		&i.Accession,
 		&i.CurrentStatus,
 		&i.ScheduleDt,
 		&i.BeginExamDt,
 		&i.EndExamDt,
 		&i.ExamCancelledDt,
-		&i.PrelimReportID,
 		&i.OrderingPhysicianID,
 		&i.MessageID,
 		&i.SendingApp,
 		&i.Priority,
+		&i.LastEventDt,
+		&i.ReplacedAccession,
+		&i.StudyInstanceUid,
+		&i.ResultStatus,
+		&i.ResultEventDt,
 	)
 	return i, err
 }
 
 const getExamBySendingAppAccession = `-- name: GetExamBySendingAppAccession :one
 SELECT
-    e.id, e.created_at, e.updated_at, e.visit_id, e.mrn_id, e.site_id, e.procedure_id, e.final_report_id, e.addendum_report_id, e.accession, e.current_status, e.schedule_dt, e.begin_exam_dt, e.end_exam_dt, e.exam_cancelled_dt, e.prelim_report_id, e.ordering_physician_id, e.message_id, e.sending_app, e.priority,
+    e.id, e.created_at, e.updated_at, e.visit_id, e.mrn_id, e.site_id, e.procedure_id, e.accession, e.current_status, e.schedule_dt, e.begin_exam_dt, e.end_exam_dt, e.exam_cancelled_dt, e.ordering_physician_id, e.message_id, e.sending_app, e.priority, e.last_event_dt, e.replaced_accession, e.study_instance_uid, e.result_status, e.result_event_dt,
     m.created_at AS mrn_created_at,
     m.updated_at AS mrn_updated_at,
     m.mrn AS mrn_value,
@@ -235,19 +252,21 @@ type GetExamBySendingAppAccessionRow struct {
 	MrnID                pgtype.Int8
 	SiteID               pgtype.Int4
 	ProcedureID          pgtype.Int4
-	FinalReportID        pgtype.Int8
-	AddendumReportID     pgtype.Int8
 	Accession            string
 	CurrentStatus        string
 	ScheduleDt           pgtype.Timestamp
 	BeginExamDt          pgtype.Timestamp
 	EndExamDt            pgtype.Timestamp
 	ExamCancelledDt      pgtype.Timestamp
-	PrelimReportID       pgtype.Int8
 	OrderingPhysicianID  pgtype.Int8
 	MessageID            pgtype.Int8
 	SendingApp           string
 	Priority             pgtype.Text
+	LastEventDt          pgtype.Timestamp
+	ReplacedAccession    pgtype.Text
+	StudyInstanceUid     pgtype.Text
+	ResultStatus         string
+	ResultEventDt        pgtype.Timestamp
 	MrnCreatedAt         pgtype.Timestamp
 	MrnUpdatedAt         pgtype.Timestamp
 	MrnValue             pgtype.Text
@@ -286,19 +305,21 @@ func (q *Queries) GetExamBySendingAppAccession(ctx context.Context, arg GetExamB
 		&i.MrnID,
 		&i.SiteID,
 		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
 		&i.Accession,
 		&i.CurrentStatus,
 		&i.ScheduleDt,
 		&i.BeginExamDt,
 		&i.EndExamDt,
 		&i.ExamCancelledDt,
-		&i.PrelimReportID,
 		&i.OrderingPhysicianID,
 		&i.MessageID,
 		&i.SendingApp,
 		&i.Priority,
+		&i.LastEventDt,
+		&i.ReplacedAccession,
+		&i.StudyInstanceUid,
+		&i.ResultStatus,
+		&i.ResultEventDt,
 		&i.MrnCreatedAt,
 		&i.MrnUpdatedAt,
 		&i.MrnValue,
@@ -328,6 +349,40 @@ func (q *Queries) GetExamBySendingAppAccession(ctx context.Context, arg GetExamB
 	return i, err
 }
 
+const getExamEventBySendingAppAccession = `-- name: GetExamEventBySendingAppAccession :one
+SELECT id, current_status, last_event_dt, result_status, result_event_dt
+FROM exams
+WHERE
+    sending_app = $1
+    AND accession = $2
+`
+
+type GetExamEventBySendingAppAccessionParams struct {
+	SendingApp string
+	Accession  string
+}
+
+type GetExamEventBySendingAppAccessionRow struct {
+	ID            int64
+	CurrentStatus string
+	LastEventDt   pgtype.Timestamp
+	ResultStatus  string
+	ResultEventDt pgtype.Timestamp
+}
+
+func (q *Queries) GetExamEventBySendingAppAccession(ctx context.Context, arg GetExamEventBySendingAppAccessionParams) (GetExamEventBySendingAppAccessionRow, error) {
+	row := q.db.QueryRow(ctx, getExamEventBySendingAppAccession, arg.SendingApp, arg.Accession)
+	var i GetExamEventBySendingAppAccessionRow
+	err := row.Scan(
+		&i.ID,
+		&i.CurrentStatus,
+		&i.LastEventDt,
+		&i.ResultStatus,
+		&i.ResultEventDt,
+	)
+	return i, err
+}
+
 const getExamIDBySendingAppAccession = `-- name: GetExamIDBySendingAppAccession :one
 SELECT id
 FROM exams
@@ -348,6 +403,150 @@ func (q *Queries) GetExamIDBySendingAppAccession(ctx context.Context, arg GetExa
 	return id, err
 }
 
+const moveExamsToMrn = `-- name: MoveExamsToMrn :many
+UPDATE exams
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1
+WHERE mrn_id = $2
+RETURNING id
+`
+
+type MoveExamsToMrnParams struct {
+	ToMrnID   pgtype.Int8
+	FromMrnID pgtype.Int8
+}
+
+func (q *Queries) MoveExamsToMrn(ctx context.Context, arg MoveExamsToMrnParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, moveExamsToMrn, arg.ToMrnID, arg.FromMrnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveExamsToMrnByIds = `-- name: MoveExamsToMrnByIds :execrows
+UPDATE exams
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1
+WHERE
+    id = ANY($2::bigint[])
+    AND mrn_id = $3
+`
+
+type MoveExamsToMrnByIdsParams struct {
+	ToMrnID   pgtype.Int8
+	Ids       []int64
+	FromMrnID pgtype.Int8
+}
+
+func (q *Queries) MoveExamsToMrnByIds(ctx context.Context, arg MoveExamsToMrnByIdsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveExamsToMrnByIds,
+		arg.ToMrnID,
+		arg.Ids,
+		arg.FromMrnID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveExamsToMrnVisits = `-- name: MoveExamsToMrnVisits :many
+UPDATE exams AS e
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1,
+    visit_id = w.id
+FROM visits AS v, visits AS w
+WHERE
+    e.mrn_id = $2
+    AND e.visit_id = v.id
+    AND v.mrn_id = $2
+    AND w.site_id = v.site_id
+    AND w.mrn_id = $1
+    AND w.number = v.number
+RETURNING e.id, v.id AS prior_visit_id
+`
+
+type MoveExamsToMrnVisitsParams struct {
+	ToMrnID   pgtype.Int8
+	FromMrnID pgtype.Int8
+}
+
+type MoveExamsToMrnVisitsRow struct {
+	ID           int64
+	PriorVisitID int64
+}
+
+func (q *Queries) MoveExamsToMrnVisits(ctx context.Context, arg MoveExamsToMrnVisitsParams) ([]MoveExamsToMrnVisitsRow, error) {
+	rows, err := q.db.Query(ctx, moveExamsToMrnVisits, arg.ToMrnID, arg.FromMrnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MoveExamsToMrnVisitsRow
+	for rows.Next() {
+		var i MoveExamsToMrnVisitsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PriorVisitID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveExamsToVisitsByIds = `-- name: MoveExamsToVisitsByIds :execrows
+UPDATE exams AS e
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1,
+    visit_id = r.visit_id
+FROM unnest($2::bigint[], $3::bigint[]) AS r (id, visit_id)
+WHERE
+    e.id = r.id
+    AND e.mrn_id = $4
+`
+
+type MoveExamsToVisitsByIdsParams struct {
+	ToMrnID   pgtype.Int8
+	Ids       []int64
+	VisitIds  []int64
+	FromMrnID pgtype.Int8
+}
+
+func (q *Queries) MoveExamsToVisitsByIds(ctx context.Context, arg MoveExamsToVisitsByIdsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveExamsToVisitsByIds,
+		arg.ToMrnID,
+		arg.Ids,
+		arg.VisitIds,
+		arg.FromMrnID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateExam = `-- name: UpdateExam :one
 UPDATE exams
 SET
@@ -363,7 +562,7 @@ SET
     begin_exam_dt = $10,
     end_exam_dt = $11
 WHERE id = $1
-RETURNING id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority
+RETURNING id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, ordering_physician_id, message_id, sending_app, priority, last_event_dt, replaced_accession, study_instance_uid, result_status, result_event_dt
 `
 
 type UpdateExamParams struct {
@@ -403,145 +602,85 @@ func (q *Queries) UpdateExam(ctx context.Context, arg UpdateExamParams) (Exam, e
 		&i.MrnID,
 		&i.SiteID,
 		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
 		&i.Accession,
 		&i.CurrentStatus,
 		&i.ScheduleDt,
 		&i.BeginExamDt,
 		&i.EndExamDt,
 		&i.ExamCancelledDt,
-		&i.PrelimReportID,
 		&i.OrderingPhysicianID,
 		&i.MessageID,
 		&i.SendingApp,
 		&i.Priority,
+		&i.LastEventDt,
+		&i.ReplacedAccession,
+		&i.StudyInstanceUid,
+		&i.ResultStatus,
+		&i.ResultEventDt,
 	)
 	return i, err
 }
 
-const updateExamAddendumReport = `-- name: UpdateExamAddendumReport :one
+const updateExamOrderDetails = `-- name: UpdateExamOrderDetails :exec
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    addendum_report_id = $2
-WHERE id = $1
-RETURNING id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority
+    replaced_accession = COALESCE(NULLIF($1::text, ''), replaced_accession),
+    study_instance_uid = COALESCE(NULLIF($2::text, ''), study_instance_uid)
+WHERE id = $3
 `
 
-type UpdateExamAddendumReportParams struct {
-	ID               int64
-	AddendumReportID pgtype.Int8
+type UpdateExamOrderDetailsParams struct {
+	ReplacedAccession string
+	StudyInstanceUid  string
+	ID                int64
 }
 
-func (q *Queries) UpdateExamAddendumReport(ctx context.Context, arg UpdateExamAddendumReportParams) (Exam, error) {
-	row := q.db.QueryRow(ctx, updateExamAddendumReport, arg.ID, arg.AddendumReportID)
-	var i Exam
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.VisitID,
-		&i.MrnID,
-		&i.SiteID,
-		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
-		&i.Accession,
-		&i.CurrentStatus,
-		&i.ScheduleDt,
-		&i.BeginExamDt,
-		&i.EndExamDt,
-		&i.ExamCancelledDt,
-		&i.PrelimReportID,
-		&i.OrderingPhysicianID,
-		&i.MessageID,
-		&i.SendingApp,
-		&i.Priority,
+func (q *Queries) UpdateExamOrderDetails(ctx context.Context, arg UpdateExamOrderDetailsParams) error {
+	_, err := q.db.Exec(ctx, updateExamOrderDetails,
+		arg.ReplacedAccession,
+		arg.StudyInstanceUid,
+		arg.ID,
 	)
-	return i, err
+	return err
 }
 
-const updateExamFinalReport = `-- name: UpdateExamFinalReport :one
+const updateExamResultStatus = `-- name: UpdateExamResultStatus :exec
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    final_report_id = $2
-WHERE id = $1
-RETURNING id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority
+    result_status = $1,
+    result_event_dt = GREATEST($2::timestamp, result_event_dt)
+WHERE id = $3
 `
 
-type UpdateExamFinalReportParams struct {
-	ID            int64
-	FinalReportID pgtype.Int8
+type UpdateExamResultStatusParams struct {
+	ResultStatus string
+	EventDt      pgtype.Timestamp
+	ID           int64
 }
 
-func (q *Queries) UpdateExamFinalReport(ctx context.Context, arg UpdateExamFinalReportParams) (Exam, error) {
-	row := q.db.QueryRow(ctx, updateExamFinalReport, arg.ID, arg.FinalReportID)
-	var i Exam
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.VisitID,
-		&i.MrnID,
-		&i.SiteID,
-		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
-		&i.Accession,
-		&i.CurrentStatus,
-		&i.ScheduleDt,
-		&i.BeginExamDt,
-		&i.EndExamDt,
-		&i.ExamCancelledDt,
-		&i.PrelimReportID,
-		&i.OrderingPhysicianID,
-		&i.MessageID,
-		&i.SendingApp,
-		&i.Priority,
-	)
-	return i, err
+func (q *Queries) UpdateExamResultStatus(ctx context.Context, arg UpdateExamResultStatusParams) error {
+	_, err := q.db.Exec(ctx, updateExamResultStatus, arg.ResultStatus, arg.EventDt, arg.ID)
+	return err
 }
 
-const updateExamPrelimReport = `-- name: UpdateExamPrelimReport :one
+const updateExamStatus = `-- name: UpdateExamStatus :exec
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    prelim_report_id = $2
-WHERE id = $1
-RETURNING id, created_at, updated_at, visit_id, mrn_id, site_id, procedure_id, final_report_id, addendum_report_id, accession, current_status, schedule_dt, begin_exam_dt, end_exam_dt, exam_cancelled_dt, prelim_report_id, ordering_physician_id, message_id, sending_app, priority
+    current_status = $1,
+    last_event_dt = GREATEST($2::timestamp, last_event_dt)
+WHERE id = $3
 `
 
-type UpdateExamPrelimReportParams struct {
-	ID             int64
-	PrelimReportID pgtype.Int8
+type UpdateExamStatusParams struct {
+	CurrentStatus string
+	EventDt       pgtype.Timestamp
+	ID            int64
 }
 
-func (q *Queries) UpdateExamPrelimReport(ctx context.Context, arg UpdateExamPrelimReportParams) (Exam, error) {
-	row := q.db.QueryRow(ctx, updateExamPrelimReport, arg.ID, arg.PrelimReportID)
-	var i Exam
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.VisitID,
-		&i.MrnID,
-		&i.SiteID,
-		&i.ProcedureID,
-		&i.FinalReportID,
-		&i.AddendumReportID,
-		&i.Accession,
-		&i.CurrentStatus,
-		&i.ScheduleDt,
-		&i.BeginExamDt,
-		&i.EndExamDt,
-		&i.ExamCancelledDt,
-		&i.PrelimReportID,
-		&i.OrderingPhysicianID,
-		&i.MessageID,
-		&i.SendingApp,
-		&i.Priority,
-	)
-	return i, err
+func (q *Queries) UpdateExamStatus(ctx context.Context, arg UpdateExamStatusParams) error {
+	_, err := q.db.Exec(ctx, updateExamStatus, arg.CurrentStatus, arg.EventDt, arg.ID)
+	return err
 }
@@ -8,6 +8,44 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Appointment struct {
+	ID              int64
+	CreatedAt       pgtype.Timestamp
+	UpdatedAt       pgtype.Timestamp
+	MessageID       pgtype.Int8
+	SiteID          pgtype.Int4
+	MrnID           pgtype.Int8
+	ExamID          pgtype.Int8
+	SendingApp      string
+	AppointmentID   string
+	Accession       string
+	StartDt         pgtype.Timestamp
+	DurationMinutes pgtype.Int4
+	Resource        pgtype.Text
+	Room            pgtype.Text
+	Status          string
+	NoShow          bool
+	LastEvent       string
+	LastEventDt     pgtype.Timestamp
+}
+
+type CriticalResult struct {
+	ID                 int64
+	CreatedAt          pgtype.Timestamp
+	ReportID           int64
+	ExamID             int64
+	RadiologistID      pgtype.Int8
+	Rule               string
+	Matched            string
+	NotifiedAt         pgtype.Timestamp
+	NotifyError        string
+	AcknowledgedAt     pgtype.Timestamp
+	AcknowledgedBy     string
+	MessageID          pgtype.Int8
+	NotifiedBy         []string
+	NotifyClaimedUntil pgtype.Timestamp
+}
+
 type Exam struct {
 	ID                  int64
 	CreatedAt           pgtype.Timestamp
@@ -16,19 +54,58 @@ type Exam struct {
 	MrnID               pgtype.Int8
 	SiteID              pgtype.Int4
 	ProcedureID         pgtype.Int4
-	FinalReportID       pgtype.Int8
-	AddendumReportID    pgtype.Int8
 	Accession           string
 	CurrentStatus       string
 	ScheduleDt          pgtype.Timestamp
 	BeginExamDt         pgtype.Timestamp
 	EndExamDt           pgtype.Timestamp
 	ExamCancelledDt     pgtype.Timestamp
-	PrelimReportID      pgtype.Int8
 	OrderingPhysicianID pgtype.Int8
 	MessageID           pgtype.Int8
 	SendingApp          string
 	Priority            pgtype.Text
+	LastEventDt         pgtype.Timestamp
+	ReplacedAccession   pgtype.Text
+	StudyInstanceUid    pgtype.Text
+	ResultStatus        string
+	ResultEventDt       pgtype.Timestamp
+}
+
+type ExamReport struct {
+	ID        int64
+	CreatedAt pgtype.Timestamp
+	ExamID    int64
+	ReportID  int64
+	Role      string
+	Sequence  int32
+	MessageID pgtype.Int8
+}
+
+type ExamStatusHistory struct {
+	ID        int64
+	CreatedAt pgtype.Timestamp
+	ExamID    int64
+	OldStatus pgtype.Text
+	NewStatus string
+	EventDt   pgtype.Timestamp
+	MessageID pgtype.Int8
+	SourceApp string
+}
+
+type FailedMessage struct {
+	ID            int64
+	CreatedAt     pgtype.Timestamp
+	UpdatedAt     pgtype.Timestamp
+	StorePath     string
+	Raw           []byte
+	RawHash       string
+	ControlID     string
+	ErrorCategory string
+	ErrorMessage  string
+	Attempts      int32
+	FirstFailedAt pgtype.Timestamp
+	LastFailedAt  pgtype.Timestamp
+	Status        string
 }
 
 type Message struct {
@@ -86,6 +163,26 @@ type Patient struct {
 	MessageID  pgtype.Int8
 }
 
+type PatientMerge struct {
+	ID                int64
+	CreatedAt         pgtype.Timestamp
+	MessageID         pgtype.Int8
+	Event             string
+	SurvivorMrn       string
+	PriorMrn          string
+	SurvivorPatientID pgtype.Int8
+	SurvivorMrnID     pgtype.Int8
+	PriorPatientID    pgtype.Int8
+	PriorMrnID        pgtype.Int8
+	Renamed           bool
+	ExamIds           []int64
+	VisitIds          []int64
+	MrnIds            []int64
+	UnmergedAt        pgtype.Timestamp
+	RepointedExamIds  []int64
+	RepointedVisitIds []int64
+}
+
 type Physician struct {
 	ID         int64
 	CreatedAt  pgtype.Timestamp
@@ -102,6 +199,20 @@ type Physician struct {
 	MessageID  pgtype.Int8
 }
 
+type ProcessedMessage struct {
+	ID              int64
+	CreatedAt       pgtype.Timestamp
+	UpdatedAt       pgtype.Timestamp
+	DedupeKey       string
+	PubsubMessageID string
+	StorePath       string
+	ControlID       string
+	StatusCode      int32
+	ErrorCategory   string
+	Response        []byte
+	ProcessedAt     pgtype.Timestamp
+}
+
 type Procedure struct {
 	ID          int32
 	CreatedAt   pgtype.Timestamp
@@ -116,18 +227,54 @@ type Procedure struct {
 	Reportable  pgtype.Bool
 }
 
+type RawMessage struct {
+	ID          int64
+	CreatedAt   pgtype.Timestamp
+	MessageID   int64
+	ContentHash string
+	Encoding    string
+	Size        int32
+	Data        []byte
+}
+
 type Report struct {
-	ID             int64
-	CreatedAt      pgtype.Timestamp
-	UpdatedAt      pgtype.Timestamp
-	RadiologistID  pgtype.Int8
-	Body           string
-	Impression     string
-	ReportStatus   string
-	SubmittedDt    pgtype.Timestamp
-	MessageID      pgtype.Int8
-	DictationStart pgtype.Timestamp
-	DictationEnd   pgtype.Timestamp
+	ID               int64
+	CreatedAt        pgtype.Timestamp
+	UpdatedAt        pgtype.Timestamp
+	RadiologistID    pgtype.Int8
+	Body             string
+	Impression       string
+	ReportStatus     string
+	SubmittedDt      pgtype.Timestamp
+	MessageID        pgtype.Int8
+	DictationStart   pgtype.Timestamp
+	DictationEnd     pgtype.Timestamp
+	PreviousReportID pgtype.Int8
+	SendingApp       string
+	Accessions       []string
+	BodyHash         string
+	Sequence         int32
+	Findings         string
+	Technique        string
+	Comparison       string
+	History          string
+	DocumentID       string
+	Obsolete         bool
+}
+
+type ReportAttachment struct {
+	ID          int64
+	CreatedAt   pgtype.Timestamp
+	ReportID    int64
+	Sequence    int32
+	ValueType   string
+	MimeType    string
+	Size        int64
+	ContentHash string
+	BlobKey     string
+	Data        []byte
+	Reference   string
+	MessageID   pgtype.Int8
 }
 
 type Site struct {
@@ -142,13 +289,33 @@ type Site struct {
 	MetasiteID pgtype.Int4
 }
 
+type StaleExamUpdate struct {
+	ID              int64
+	CreatedAt       pgtype.Timestamp
+	ExamID          int64
+	MessageID       pgtype.Int8
+	IncomingStatus  string
+	IncomingEventDt pgtype.Timestamp
+	CurrentStatus   string
+	LastEventDt     pgtype.Timestamp
+	Reason          string
+}
+
 type Visit struct {
-	ID          int64
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
-	SiteID      pgtype.Int4
-	MrnID       pgtype.Int8
-	Number      string
-	PatientType int16
-	MessageID   pgtype.Int8
+	ID                   int64
+	CreatedAt            pgtype.Timestamp
+	UpdatedAt            pgtype.Timestamp
+	SiteID               pgtype.Int4
+	MrnID                pgtype.Int8
+	Number               string
+	PatientType          int16
+	MessageID            pgtype.Int8
+	PatientClass         pgtype.Text
+	PointOfCare          pgtype.Text
+	Room                 pgtype.Text
+	Bed                  pgtype.Text
+	AdmitDt              pgtype.Timestamp
+	DischargeDt          pgtype.Timestamp
+	AttendingPhysicianID pgtype.Int8
+	ReferringPhysicianID pgtype.Int8
 }
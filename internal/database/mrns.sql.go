@@ -98,3 +98,136 @@ func (q *Queries) GetMrnBySitePatient(ctx context.Context, arg GetMrnBySitePatie
 	)
 	return i, err
 }
+
+const getMrnBySiteValue = `-- name: GetMrnBySiteValue :one
+SELECT id, created_at, updated_at, patient_id, mrn, site_id, message_id
+FROM mrns
+WHERE
+    site_id = $1
+    AND mrn = $2
+ORDER BY id DESC
+LIMIT 1
+`
+
+type GetMrnBySiteValueParams struct {
+	SiteID int32
+	Mrn    string
+}
+
+func (q *Queries) GetMrnBySiteValue(ctx context.Context, arg GetMrnBySiteValueParams) (Mrn, error) {
+	row := q.db.QueryRow(ctx, getMrnBySiteValue, arg.SiteID, arg.Mrn)
+	var i Mrn
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.PatientID,
+		&i.Mrn,
+		&i.SiteID,
+		&i.MessageID,
+	)
+	return i, err
+}
+
+const moveMrnsToPatient = `-- name: MoveMrnsToPatient :many
+UPDATE mrns AS m
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_id = $1
+WHERE
+    m.patient_id = $2
+    AND m.id <> $3
+    AND NOT EXISTS (
+        SELECT 1 FROM mrns AS x
+        WHERE
+            x.site_id = m.site_id
+            AND x.patient_id = $1
+    )
+RETURNING m.id
+`
+
+type MoveMrnsToPatientParams struct {
+	ToPatientID   pgtype.Int8
+	FromPatientID pgtype.Int8
+	KeepID        int64
+}
+
+func (q *Queries) MoveMrnsToPatient(ctx context.Context, arg MoveMrnsToPatientParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, moveMrnsToPatient,
+		arg.ToPatientID,
+		arg.FromPatientID,
+		arg.KeepID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveMrnsToPatientByIds = `-- name: MoveMrnsToPatientByIds :execrows
+UPDATE mrns
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_id = $1
+WHERE
+    id = ANY($2::bigint[])
+    AND patient_id = $3
+`
+
+type MoveMrnsToPatientByIdsParams struct {
+	ToPatientID   pgtype.Int8
+	Ids           []int64
+	FromPatientID pgtype.Int8
+}
+
+func (q *Queries) MoveMrnsToPatientByIds(ctx context.Context, arg MoveMrnsToPatientByIdsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveMrnsToPatientByIds,
+		arg.ToPatientID,
+		arg.Ids,
+		arg.FromPatientID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const renameMrn = `-- name: RenameMrn :execrows
+UPDATE mrns
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn = $1
+WHERE
+    id = $2
+    AND mrn = $3
+`
+
+type RenameMrnParams struct {
+	NewMrn string
+	ID     int64
+	OldMrn string
+}
+
+func (q *Queries) RenameMrn(ctx context.Context, arg RenameMrnParams) (int64, error) {
+	result, err := q.db.Exec(ctx, renameMrn,
+		arg.NewMrn,
+		arg.ID,
+		arg.OldMrn,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
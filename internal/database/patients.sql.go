@@ -266,3 +266,54 @@ func (q *Queries) UpdatePatient(ctx context.Context, arg UpdatePatientParams) (P
 	)
 	return i, err
 }
+
+const updatePatientDemographics = `-- name: UpdatePatientDemographics :exec
+UPDATE patients
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    first_name = COALESCE(NULLIF($1::text, ''), first_name),
+    last_name = COALESCE(NULLIF($2::text, ''), last_name),
+    middle_name = COALESCE(NULLIF($3::text, ''), middle_name),
+    suffix = COALESCE(NULLIF($4::text, ''), suffix),
+    prefix = COALESCE(NULLIF($5::text, ''), prefix),
+    degree = COALESCE(NULLIF($6::text, ''), degree),
+    dob = COALESCE($7::date, dob),
+    sex = COALESCE(NULLIF($8::text, ''), sex),
+    home_phone = COALESCE(NULLIF($9::text, ''), home_phone),
+    work_phone = COALESCE(NULLIF($10::text, ''), work_phone),
+    message_id = $11
+WHERE id = $12
+`
+
+type UpdatePatientDemographicsParams struct {
+	FirstName  string
+	LastName   string
+	MiddleName string
+	Suffix     string
+	Prefix     string
+	Degree     string
+	Dob        pgtype.Date
+	Sex        string
+	HomePhone  string
+	WorkPhone  string
+	MessageID  pgtype.Int8
+	ID         int64
+}
+
+func (q *Queries) UpdatePatientDemographics(ctx context.Context, arg UpdatePatientDemographicsParams) error {
+	_, err := q.db.Exec(ctx, updatePatientDemographics,
+		arg.FirstName,
+		arg.LastName,
+		arg.MiddleName,
+		arg.Suffix,
+		arg.Prefix,
+		arg.Degree,
+		arg.Dob,
+		arg.Sex,
+		arg.HomePhone,
+		arg.WorkPhone,
+		arg.MessageID,
+		arg.ID,
+	)
+	return err
+}
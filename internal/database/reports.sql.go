@@ -19,17 +19,31 @@ WITH upsert as (
         impression,
         report_status,
         submitted_dt,
-	dictation_start,
-	dictation_end,
-        message_id
+        dictation_start,
+        dictation_end,
+        message_id,
+        sending_app,
+        accessions,
+        body_hash,
+        findings,
+        technique,
+        comparison,
+        history,
+        document_id,
+        sequence
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    ON CONFLICT (radiologist_id, impression, report_status, submitted_dt) DO UPDATE
-    SET
-        updated_at = CURRENT_TIMESTAMP,
-        body = COALESCE(EXCLUDED.body, reports.body)
-    WHERE
-        COALESCE(EXCLUDED.body, reports.body) IS DISTINCT FROM EXCLUDED.body
+    VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+        (
+            SELECT COALESCE(MAX(sequence), 0) + 1
+            FROM reports
+            WHERE
+                sending_app = $9
+                AND accessions = $10
+                AND report_status = $4
+        )
+    )
+    ON CONFLICT (sending_app, accessions, report_status, body_hash) DO NOTHING
     RETURNING id
 )
 
@@ -37,10 +51,10 @@ SELECT id FROM upsert
 UNION ALL
 SELECT id FROM reports
 WHERE
-    radiologist_id = $1
-    AND impression = $3
+    sending_app = $9
+    AND accessions = $10
     AND report_status = $4
-    AND submitted_dt = $5
+    AND body_hash = $11
 `
 
 type CreateReportParams struct {
@@ -52,6 +66,14 @@ type CreateReportParams struct {
 	DictationStart pgtype.Timestamp
 	DictationEnd   pgtype.Timestamp
 	MessageID      pgtype.Int8
+	SendingApp     string
+	Accessions     []string
+	BodyHash       string
+	Findings       string
+	Technique      string
+	Comparison     string
+	History        string
+	DocumentID     string
 }
 
 func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (int64, error) {
@@ -64,6 +86,14 @@ func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (int
 		arg.DictationStart,
 		arg.DictationEnd,
 		arg.MessageID,
+		arg.SendingApp,
+		arg.Accessions,
+		arg.BodyHash,
+		arg.Findings,
+		arg.Technique,
+		arg.Comparison,
+		arg.History,
+		arg.DocumentID,
 	)
 	var id int64
 	err := row.Scan(&id)
@@ -71,7 +101,7 @@ func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (int
 }
 
 const getAllReports = `-- name: GetAllReports :many
-SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end
+SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end, previous_report_id, sending_app, accessions, body_hash, sequence, findings, technique, comparison, history, document_id, obsolete
 FROM reports
 `
 
@@ -96,6 +126,17 @@ func (q *Queries) GetAllReports(ctx context.Context) ([]Report, error) {
 			&i.MessageID,
 			&i.DictationStart,
 			&i.DictationEnd,
+			&i.PreviousReportID,
+			&i.SendingApp,
+			&i.Accessions,
+			&i.BodyHash,
+			&i.Sequence,
+			&i.Findings,
+			&i.Technique,
+			&i.Comparison,
+			&i.History,
+			&i.DocumentID,
+			&i.Obsolete,
 		); err != nil {
 			return nil, err
 		}
@@ -107,8 +148,36 @@ func (q *Queries) GetAllReports(ctx context.Context) ([]Report, error) {
 	return items, nil
 }
 
+const getPreviousExamReportID = `-- name: GetPreviousExamReportID :one
+SELECT r.id
+FROM exam_reports er
+JOIN reports r ON r.id = er.report_id
+WHERE
+    er.exam_id = $1
+    AND (r.submitted_dt, r.id) < ($2::timestamp, $3::bigint)
+ORDER BY r.submitted_dt DESC, r.id DESC
+LIMIT 1
+`
+
+type GetPreviousExamReportIDParams struct {
+	ExamID      int64
+	SubmittedDt pgtype.Timestamp
+	ReportID    int64
+}
+
+func (q *Queries) GetPreviousExamReportID(ctx context.Context, arg GetPreviousExamReportIDParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getPreviousExamReportID,
+		arg.ExamID,
+		arg.SubmittedDt,
+		arg.ReportID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getReportById = `-- name: GetReportById :one
-SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end FROM reports
+SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end, previous_report_id, sending_app, accessions, body_hash, sequence, findings, technique, comparison, history, document_id, obsolete FROM reports
 WHERE id = $1
 `
 
@@ -127,12 +196,23 @@ func (q *Queries) GetReportById(ctx context.Context, id int64) (Report, error) {
 		&i.MessageID,
 		&i.DictationStart,
 		&i.DictationEnd,
+		&i.PreviousReportID,
+		&i.SendingApp,
+		&i.Accessions,
+		&i.BodyHash,
+		&i.Sequence,
+		&i.Findings,
+		&i.Technique,
+		&i.Comparison,
+		&i.History,
+		&i.DocumentID,
+		&i.Obsolete,
 	)
 	return i, err
 }
 
 const getReportByRadID = `-- name: GetReportByRadID :one
-SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end FROM reports
+SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end, previous_report_id, sending_app, accessions, body_hash, sequence, findings, technique, comparison, history, document_id, obsolete FROM reports
 where radiologist_id = $1
 `
 
@@ -151,33 +231,44 @@ func (q *Queries) GetReportByRadID(ctx context.Context, radiologistID pgtype.Int
 		&i.MessageID,
 		&i.DictationStart,
 		&i.DictationEnd,
+		&i.PreviousReportID,
+		&i.SendingApp,
+		&i.Accessions,
+		&i.BodyHash,
+		&i.Sequence,
+		&i.Findings,
+		&i.Technique,
+		&i.Comparison,
+		&i.History,
+		&i.DocumentID,
+		&i.Obsolete,
 	)
 	return i, err
 }
 
 const getReportByUniqueFields = `-- name: GetReportByUniqueFields :one
-SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end
+SELECT id, created_at, updated_at, radiologist_id, body, impression, report_status, submitted_dt, message_id, dictation_start, dictation_end, previous_report_id, sending_app, accessions, body_hash, sequence, findings, technique, comparison, history, document_id, obsolete
 FROM reports
 WHERE
-    radiologist_id = $1
-    AND impression = $2
+    sending_app = $1
+    AND accessions = $2
     AND report_status = $3
-    AND submitted_dt = $4
+    AND body_hash = $4
 `
 
 type GetReportByUniqueFieldsParams struct {
-	RadiologistID pgtype.Int8
-	Impression    string
-	ReportStatus  string
-	SubmittedDt   pgtype.Timestamp
+	SendingApp   string
+	Accessions   []string
+	ReportStatus string
+	BodyHash     string
 }
 
 func (q *Queries) GetReportByUniqueFields(ctx context.Context, arg GetReportByUniqueFieldsParams) (Report, error) {
 	row := q.db.QueryRow(ctx, getReportByUniqueFields,
-		arg.RadiologistID,
-		arg.Impression,
+		arg.SendingApp,
+		arg.Accessions,
 		arg.ReportStatus,
-		arg.SubmittedDt,
+		arg.BodyHash,
 	)
 	var i Report
 	err := row.Scan(
@@ -192,6 +283,165 @@ func (q *Queries) GetReportByUniqueFields(ctx context.Context, arg GetReportByUn
 		&i.MessageID,
 		&i.DictationStart,
 		&i.DictationEnd,
+		&i.PreviousReportID,
+		&i.SendingApp,
+		&i.Accessions,
+		&i.BodyHash,
+		&i.Sequence,
+		&i.Findings,
+		&i.Technique,
+		&i.Comparison,
+		&i.History,
+		&i.DocumentID,
+		&i.Obsolete,
 	)
 	return i, err
 }
+
+const linkPreviousReport = `-- name: LinkPreviousReport :exec
+UPDATE reports
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    previous_report_id = $1
+WHERE
+    id = $2
+    AND previous_report_id IS NULL
+`
+
+type LinkPreviousReportParams struct {
+	PreviousReportID pgtype.Int8
+	ID               int64
+}
+
+func (q *Queries) LinkPreviousReport(ctx context.Context, arg LinkPreviousReportParams) error {
+	_, err := q.db.Exec(ctx, linkPreviousReport, arg.PreviousReportID, arg.ID)
+	return err
+}
+
+const listExamReportHistory = `-- name: ListExamReportHistory :many
+SELECT
+    r.id,
+    r.previous_report_id,
+    r.report_status,
+    r.submitted_dt,
+    r.dictation_start,
+    r.dictation_end,
+    r.impression,
+    r.findings,
+    r.technique,
+    r.comparison,
+    r.history,
+    r.body,
+    r.obsolete,
+    COALESCE(p.first_name, '')::text AS radiologist_first_name,
+    COALESCE(p.last_name, '')::text AS radiologist_last_name,
+    er.role,
+    er.sequence,
+    (
+        NOT r.obsolete
+        AND (
+            er.role = 'addendum'
+            OR NOT EXISTS (
+                SELECT 1
+                FROM exam_reports later
+                JOIN reports lr ON lr.id = later.report_id
+                WHERE
+                    later.exam_id = er.exam_id
+                    AND (later.role = 'prelim') = (er.role = 'prelim')
+                    AND later.role <> 'addendum'
+                    AND (lr.submitted_dt, lr.id) > (r.submitted_dt, r.id)
+            )
+        )
+    )::boolean AS current
+FROM exam_reports er
+JOIN reports r ON r.id = er.report_id
+LEFT JOIN physicians p ON p.id = r.radiologist_id
+WHERE er.exam_id = $1
+ORDER BY r.submitted_dt, r.id
+`
+
+type ListExamReportHistoryRow struct {
+	ID                   int64
+	PreviousReportID     pgtype.Int8
+	ReportStatus         string
+	SubmittedDt          pgtype.Timestamp
+	DictationStart       pgtype.Timestamp
+	DictationEnd         pgtype.Timestamp
+	Impression           string
+	Findings             string
+	Technique            string
+	Comparison           string
+	History              string
+	Body                 string
+	Obsolete             bool
+	RadiologistFirstName string
+	RadiologistLastName  string
+	Role                 string
+	Sequence             int32
+	Current              bool
+}
+
+func (q *Queries) ListExamReportHistory(ctx context.Context, examID int64) ([]ListExamReportHistoryRow, error) {
+	rows, err := q.db.Query(ctx, listExamReportHistory, examID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListExamReportHistoryRow
+	for rows.Next() {
+		var i ListExamReportHistoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PreviousReportID,
+			&i.ReportStatus,
+			&i.SubmittedDt,
+			&i.DictationStart,
+			&i.DictationEnd,
+			&i.Impression,
+			&i.Findings,
+			&i.Technique,
+			&i.Comparison,
+			&i.History,
+			&i.Body,
+			&i.Obsolete,
+			&i.RadiologistFirstName,
+			&i.RadiologistLastName,
+			&i.Role,
+			&i.Sequence,
+			&i.Current,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markDocumentObsolete = `-- name: MarkDocumentObsolete :exec
+UPDATE reports
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    obsolete = true
+WHERE
+    sending_app = $1
+    AND document_id = $2
+    AND id <> $3
+`
+
+type MarkDocumentObsoleteParams struct {
+	SendingApp string
+	DocumentID string
+	ExceptID   int64
+}
+
+func (q *Queries) MarkDocumentObsolete(ctx context.Context, arg MarkDocumentObsoleteParams) error {
+	_, err := q.db.Exec(ctx, markDocumentObsolete,
+		arg.SendingApp,
+		arg.DocumentID,
+		arg.ExceptID,
+	)
+	return err
+}
@@ -11,6 +11,92 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createAdmission = `-- name: CreateAdmission :one
+INSERT INTO visits (
+    site_id, -- $1
+    mrn_id, -- $2
+    number, -- $3
+    patient_type, -- $4
+    patient_class, -- $5
+    point_of_care, -- $6
+    room, -- $7
+    bed, -- $8
+    admit_dt, -- $9
+    discharge_dt, -- $10
+    attending_physician_id, -- $11
+    referring_physician_id, -- $12
+    message_id -- $13
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
+)
+ON CONFLICT (site_id, mrn_id, number) DO UPDATE
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_type = CASE
+        WHEN COALESCE(EXCLUDED.patient_class, '') = '' THEN visits.patient_type
+        ELSE EXCLUDED.patient_type
+    END,
+    patient_class = COALESCE(NULLIF(EXCLUDED.patient_class, ''), visits.patient_class),
+    point_of_care = COALESCE(NULLIF(EXCLUDED.point_of_care, ''), visits.point_of_care),
+    room = COALESCE(NULLIF(EXCLUDED.room, ''), visits.room),
+    bed = COALESCE(NULLIF(EXCLUDED.bed, ''), visits.bed),
+    admit_dt = COALESCE(EXCLUDED.admit_dt, visits.admit_dt),
+    discharge_dt = COALESCE(EXCLUDED.discharge_dt, visits.discharge_dt),
+    attending_physician_id = COALESCE(EXCLUDED.attending_physician_id, visits.attending_physician_id),
+    referring_physician_id = COALESCE(EXCLUDED.referring_physician_id, visits.referring_physician_id)
+RETURNING id
+`
+
+type CreateAdmissionParams struct {
+	SiteID               pgtype.Int4
+	MrnID                pgtype.Int8
+	Number               string
+	PatientType          int16
+	PatientClass         pgtype.Text
+	PointOfCare          pgtype.Text
+	Room                 pgtype.Text
+	Bed                  pgtype.Text
+	AdmitDt              pgtype.Timestamp
+	DischargeDt          pgtype.Timestamp
+	AttendingPhysicianID pgtype.Int8
+	ReferringPhysicianID pgtype.Int8
+	MessageID            pgtype.Int8
+}
+
+func (q *Queries) CreateAdmission(ctx context.Context, arg CreateAdmissionParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createAdmission,
+		arg.SiteID,
+		arg.MrnID,
+		arg.Number,
+		arg.PatientType,
+		arg.PatientClass,
+		arg.PointOfCare,
+		arg.Room,
+		arg.Bed,
+		arg.AdmitDt,
+		arg.DischargeDt,
+		arg.AttendingPhysicianID,
+		arg.ReferringPhysicianID,
+		arg.MessageID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createVisit = `-- name: CreateVisit :one
 WITH upsert AS (
     INSERT INTO visits (
@@ -68,7 +154,7 @@ func (q *Queries) CreateVisit(ctx context.Context, arg CreateVisitParams) (int64
 
 const getVisitById = `-- name: GetVisitById :one
 SELECT
-    v.id, v.created_at, v.updated_at, v.site_id, v.mrn_id, v.number, v.patient_type, v.message_id,
+    v.id, v.created_at, v.updated_at, v.site_id, v.mrn_id, v.number, v.patient_type, v.message_id, v.patient_class, v.point_of_care, v.room, v.bed, v.admit_dt, v.discharge_dt, v.attending_physician_id, v.referring_physician_id,
     s.created_at as site_created_at,
     s.updated_at as site_updated_at,
     s.code as site_code,
@@ -85,23 +171,31 @@ WHERE v.id = $1
 `
 
 type GetVisitByIdRow struct {
-	ID            int64
-	CreatedAt     pgtype.Timestamp
-	UpdatedAt     pgtype.Timestamp
-	SiteID        pgtype.Int4
-	MrnID         pgtype.Int8
-	Number        string
-	PatientType   int16
-	MessageID     pgtype.Int8
-	SiteCreatedAt pgtype.Timestamp
-	SiteUpdatedAt pgtype.Timestamp
-	SiteCode      pgtype.Text
-	SiteName      pgtype.Text
-	SiteAddress   pgtype.Text
-	SiteIsCms     pgtype.Bool
-	MrnCreatedAt  pgtype.Timestamp
-	MrnUpdatedAt  pgtype.Timestamp
-	MrnValue      pgtype.Text
+	ID                   int64
+	CreatedAt            pgtype.Timestamp
+	UpdatedAt            pgtype.Timestamp
+	SiteID               pgtype.Int4
+	MrnID                pgtype.Int8
+	Number               string
+	PatientType          int16
+	MessageID            pgtype.Int8
+	PatientClass         pgtype.Text
+	PointOfCare          pgtype.Text
+	Room                 pgtype.Text
+	Bed                  pgtype.Text
+	AdmitDt              pgtype.Timestamp
+	DischargeDt          pgtype.Timestamp
+	AttendingPhysicianID pgtype.Int8
+	ReferringPhysicianID pgtype.Int8
+	SiteCreatedAt        pgtype.Timestamp
+	SiteUpdatedAt        pgtype.Timestamp
+	SiteCode             pgtype.Text
+	SiteName             pgtype.Text
+	SiteAddress          pgtype.Text
+	SiteIsCms            pgtype.Bool
+	MrnCreatedAt         pgtype.Timestamp
+	MrnUpdatedAt         pgtype.Timestamp
+	MrnValue             pgtype.Text
 }
 
 func (q *Queries) GetVisitById(ctx context.Context, id int64) (GetVisitByIdRow, error) {
@@ -116,6 +210,14 @@ func (q *Queries) GetVisitById(ctx context.Context, id int64) (GetVisitByIdRow,
 		&i.Number,
 		&i.PatientType,
 		&i.MessageID,
+		&i.PatientClass,
+		&i.PointOfCare,
+		&i.Room,
+		&i.Bed,
+		&i.AdmitDt,
+		&i.DischargeDt,
+		&i.AttendingPhysicianID,
+		&i.ReferringPhysicianID,
 		&i.SiteCreatedAt,
 		&i.SiteUpdatedAt,
 		&i.SiteCode,
@@ -131,7 +233,7 @@ func (q *Queries) GetVisitById(ctx context.Context, id int64) (GetVisitByIdRow,
 
 const getVisitBySiteIdNumber = `-- name: GetVisitBySiteIdNumber :one
 SELECT
-    v.id, v.created_at, v.updated_at, v.site_id, v.mrn_id, v.number, v.patient_type, v.message_id,
+    v.id, v.created_at, v.updated_at, v.site_id, v.mrn_id, v.number, v.patient_type, v.message_id, v.patient_class, v.point_of_care, v.room, v.bed, v.admit_dt, v.discharge_dt, v.attending_physician_id, v.referring_physician_id,
     s.created_at as site_created_at,
     s.updated_at as site_updated_at,
     s.code as site_code,
@@ -155,23 +257,31 @@ type GetVisitBySiteIdNumberParams struct {
 }
 
 type GetVisitBySiteIdNumberRow struct {
-	ID            int64
-	CreatedAt     pgtype.Timestamp
-	UpdatedAt     pgtype.Timestamp
-	SiteID        pgtype.Int4
-	MrnID         pgtype.Int8
-	Number        string
-	PatientType   int16
-	MessageID     pgtype.Int8
-	SiteCreatedAt pgtype.Timestamp
-	SiteUpdatedAt pgtype.Timestamp
-	SiteCode      pgtype.Text
-	SiteName      pgtype.Text
-	SiteAddress   pgtype.Text
-	SiteIsCms     pgtype.Bool
-	MrnCreatedAt  pgtype.Timestamp
-	MrnUpdatedAt  pgtype.Timestamp
-	MrnValue      pgtype.Text
+	ID                   int64
+	CreatedAt            pgtype.Timestamp
+	UpdatedAt            pgtype.Timestamp
+	SiteID               pgtype.Int4
+	MrnID                pgtype.Int8
+	Number               string
+	PatientType          int16
+	MessageID            pgtype.Int8
+	PatientClass         pgtype.Text
+	PointOfCare          pgtype.Text
+	Room                 pgtype.Text
+	Bed                  pgtype.Text
+	AdmitDt              pgtype.Timestamp
+	DischargeDt          pgtype.Timestamp
+	AttendingPhysicianID pgtype.Int8
+	ReferringPhysicianID pgtype.Int8
+	SiteCreatedAt        pgtype.Timestamp
+	SiteUpdatedAt        pgtype.Timestamp
+	SiteCode             pgtype.Text
+	SiteName             pgtype.Text
+	SiteAddress          pgtype.Text
+	SiteIsCms            pgtype.Bool
+	MrnCreatedAt         pgtype.Timestamp
+	MrnUpdatedAt         pgtype.Timestamp
+	MrnValue             pgtype.Text
 }
 
 func (q *Queries) GetVisitBySiteIdNumber(ctx context.Context, arg GetVisitBySiteIdNumberParams) (GetVisitBySiteIdNumberRow, error) {
@@ -186,6 +296,14 @@ func (q *Queries) GetVisitBySiteIdNumber(ctx context.Context, arg GetVisitBySite
 		&i.Number,
 		&i.PatientType,
 		&i.MessageID,
+		&i.PatientClass,
+		&i.PointOfCare,
+		&i.Room,
+		&i.Bed,
+		&i.AdmitDt,
+		&i.DischargeDt,
+		&i.AttendingPhysicianID,
+		&i.ReferringPhysicianID,
 		&i.SiteCreatedAt,
 		&i.SiteUpdatedAt,
 		&i.SiteCode,
@@ -199,6 +317,76 @@ func (q *Queries) GetVisitBySiteIdNumber(ctx context.Context, arg GetVisitBySite
 	return i, err
 }
 
+const moveVisitsToMrn = `-- name: MoveVisitsToMrn :many
+UPDATE visits AS v
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1
+WHERE
+    v.mrn_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM visits AS w
+        WHERE
+            w.site_id = v.site_id
+            AND w.mrn_id = $1
+            AND w.number = v.number
+    )
+RETURNING v.id
+`
+
+type MoveVisitsToMrnParams struct {
+	ToMrnID   pgtype.Int8
+	FromMrnID pgtype.Int8
+}
+
+func (q *Queries) MoveVisitsToMrn(ctx context.Context, arg MoveVisitsToMrnParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, moveVisitsToMrn, arg.ToMrnID, arg.FromMrnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveVisitsToMrnByIds = `-- name: MoveVisitsToMrnByIds :execrows
+UPDATE visits
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = $1
+WHERE
+    id = ANY($2::bigint[])
+    AND mrn_id = $3
+`
+
+type MoveVisitsToMrnByIdsParams struct {
+	ToMrnID   pgtype.Int8
+	Ids       []int64
+	FromMrnID pgtype.Int8
+}
+
+func (q *Queries) MoveVisitsToMrnByIds(ctx context.Context, arg MoveVisitsToMrnByIdsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveVisitsToMrnByIds,
+		arg.ToMrnID,
+		arg.Ids,
+		arg.FromMrnID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateVisit = `-- name: UpdateVisit :one
 UPDATE visits
 SET
@@ -208,7 +396,7 @@ SET
     number = $4,
     patient_type = $5
 WHERE id = $1
-RETURNING id, created_at, updated_at, site_id, mrn_id, number, patient_type, message_id
+RETURNING id, created_at, updated_at, site_id, mrn_id, number, patient_type, message_id, patient_class, point_of_care, room, bed, admit_dt, discharge_dt, attending_physician_id, referring_physician_id
 `
 
 type UpdateVisitParams struct {
@@ -237,6 +425,14 @@ func (q *Queries) UpdateVisit(ctx context.Context, arg UpdateVisitParams) (Visit
 		&i.Number,
 		&i.PatientType,
 		&i.MessageID,
+		&i.PatientClass,
+		&i.PointOfCare,
+		&i.Room,
+		&i.Bed,
+		&i.AdmitDt,
+		&i.DischargeDt,
+		&i.AttendingPhysicianID,
+		&i.ReferringPhysicianID,
 	)
 	return i, err
 }
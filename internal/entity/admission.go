@@ -0,0 +1,174 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// Admission is an ADT event (A01, A03, A04, A08): the patient's demographics
+// and the visit as the ADT system currently has them.
+type Admission struct {
+	Message   Message
+	Patient   Patient
+	Visit     Visit
+	Attending Physician
+	Referring Physician
+}
+
+func (a *Admission) validate() error {
+	if a.Visit.MRN.Value == "" {
+		return ValidationError{"mrn", "PID-3 is empty"}
+	}
+	return nil
+}
+
+// ADTs don't carry an accession, so they're serialized per patient unless
+// locking is off.
+func (a *Admission) lockKeys(scope LockScope) []string {
+	if scope == LockOff {
+		return nil
+	}
+	return []string{patientLockKey(a.Visit)}
+}
+
+// SaveADT updates the patient found by site + MRN (creating one if there
+// isn't a match) and upserts the visit, if PV1-19 is set. Blank fields leave
+// what's stored alone.
+func (h *HL7Repo) SaveADT(ctx context.Context, adt *Admission) error {
+	if err := adt.validate(); err != nil {
+		return err
+	}
+	keys := adt.lockKeys(h.lockScope)
+	release, err := h.acquire(ctx, keys)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Printf("error with rollback: %v\n", err)
+		}
+	}()
+
+	qtx := h.Queries.WithTx(tx)
+	if err := lockKeys(ctx, qtx, keys); err != nil {
+		return err
+	}
+	msgID, err := h.saveMessage(ctx, qtx, adt.Message)
+	if err != nil {
+		return err
+	}
+	sID, err := qtx.CreateSite(ctx, createSiteParam(adt.Visit.Site, msgID))
+	if err != nil {
+		return dbErr{"site", err}
+	}
+	pID, err := savePatientByMRN(ctx, qtx, adt.Patient, adt.Visit.MRN, sID, msgID)
+	if err != nil {
+		return err
+	}
+	mID, err := qtx.CreateMrn(ctx, createMrnParam(adt.Visit.MRN, sID, pID, msgID))
+	if err != nil {
+		return dbErr{"MRN", err}
+	}
+	if adt.Visit.VisitNo == "" {
+		return tx.Commit(ctx)
+	}
+	attID, err := saveOptionalPhysician(ctx, qtx, adt.Attending, msgID)
+	if err != nil {
+		return dbErr{"attending physician", err}
+	}
+	refID, err := saveOptionalPhysician(ctx, qtx, adt.Referring, msgID)
+	if err != nil {
+		return dbErr{"referring physician", err}
+	}
+	if _, err := qtx.CreateAdmission(ctx, createAdmissionParam(adt.Visit, sID, mID, attID, refID, msgID)); err != nil {
+		return dbErr{"visit", err}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// savePatientByMRN updates the patient the site's MRN already belongs to, or
+// creates one. The SSN of a known patient isn't changed, since it's unique
+// and may already be on another patient row.
+func savePatientByMRN(ctx context.Context, qtx *database.Queries, patient Patient, mrn MRN, siteID int32, msgID int64) (int64, error) {
+	existing, err := qtx.GetMrnBySiteValue(ctx, database.GetMrnBySiteValueParams{
+		SiteID: siteID,
+		Mrn:    mrn.Value,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, dbErr{"MRN", err}
+	}
+	if err == nil && existing.PatientID.Valid {
+		if err := qtx.UpdatePatientDemographics(ctx, updatePatientDemographicsParam(patient, existing.PatientID.Int64, msgID)); err != nil {
+			return 0, dbErr{"patient", err}
+		}
+		return existing.PatientID.Int64, nil
+	}
+	pID, err := qtx.CreatePatient(ctx, createPatientParam(patient, msgID))
+	if err != nil {
+		return 0, dbErr{"patient", err}
+	}
+	return pID, nil
+}
+
+func saveOptionalPhysician(ctx context.Context, qtx *database.Queries, phys Physician, msgID int64) (pgtype.Int8, error) {
+	if phys.Name.Last == "" && phys.AppCode == "" {
+		return pgtype.Int8{}, nil
+	}
+	id, err := qtx.CreatePhysician(ctx, createPhysicianParam(phys, msgID))
+	if err != nil {
+		return pgtype.Int8{}, err
+	}
+	return pgtype.Int8{Int64: id, Valid: true}, nil
+}
+
+func updatePatientDemographicsParam(obj Patient, patientID, msgID int64) database.UpdatePatientDemographicsParams {
+	params := database.UpdatePatientDemographicsParams{}
+	params.ID = patientID
+	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
+	params.FirstName = obj.Name.First
+	params.LastName = obj.Name.Last
+	params.MiddleName = obj.Name.Middle
+	params.Suffix = obj.Name.Suffix
+	params.Prefix = obj.Name.Prefix
+	params.Degree = obj.Name.Degree
+	params.Dob = pgtype.Date{Time: obj.DOB, Valid: !obj.DOB.IsZero()}
+	params.Sex = obj.Sex
+	if obj.HomePhone != 0 {
+		params.HomePhone = obj.HomePhone.String()
+	}
+	if obj.WorkPhone != 0 {
+		params.WorkPhone = obj.WorkPhone.String()
+	}
+	return params
+}
+
+func createAdmissionParam(obj Visit, siteID int32, mrnID int64, attendingID, referringID pgtype.Int8, msgID int64) database.CreateAdmissionParams {
+	params := database.CreateAdmissionParams{}
+	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
+	params.SiteID = pgtype.Int4{Int32: siteID, Valid: true}
+	params.MrnID = pgtype.Int8{Int64: mrnID, Valid: true}
+	params.Number = obj.VisitNo
+	params.PatientType = obj.Type.Int16()
+	params.PatientClass = pgtype.Text{String: obj.Class, Valid: true}
+	params.PointOfCare = pgtype.Text{String: obj.Location.PointOfCare, Valid: true}
+	params.Room = pgtype.Text{String: obj.Location.Room, Valid: true}
+	params.Bed = pgtype.Text{String: obj.Location.Bed, Valid: true}
+	params.AdmitDt = pgtype.Timestamp{Time: obj.Admitted, Valid: !obj.Admitted.IsZero()}
+	params.DischargeDt = pgtype.Timestamp{Time: obj.Discharged, Valid: !obj.Discharged.IsZero()}
+	params.AttendingPhysicianID = attendingID
+	params.ReferringPhysicianID = referringID
+	return params
+}
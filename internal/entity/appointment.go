@@ -0,0 +1,235 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// AppointmentStatus is the filler status of an appointment (SCH-25, HL7
+// table 0278).
+type AppointmentStatus string
+
+const (
+	AppointmentPending   AppointmentStatus = "Pending"
+	AppointmentBooked    AppointmentStatus = "Booked"
+	AppointmentStarted   AppointmentStatus = "Started"
+	AppointmentComplete  AppointmentStatus = "Complete"
+	AppointmentCancelled AppointmentStatus = "Cancelled"
+	AppointmentDeleted   AppointmentStatus = "Deleted"
+	AppointmentNoShow    AppointmentStatus = "Noshow"
+)
+
+// AppointmentStatusFrom picks an appointment's status from a SIU event: S15
+// and S26 are a cancellation and a no-show whatever SCH-25 says; the others
+// take SCH-25, or Booked without one.
+func AppointmentStatusFrom(trigger, fillerStatus string) AppointmentStatus {
+	switch trigger {
+	case "S15":
+		return AppointmentCancelled
+	case "S26":
+		return AppointmentNoShow
+	}
+	if fillerStatus != "" {
+		return AppointmentStatus(fillerStatus)
+	}
+	return AppointmentBooked
+}
+
+// Appointment is a scheduled slot from a SIU event (S12-S15, S26). It's
+// linked to the exam with the same accession once both exist.
+type Appointment struct {
+	Message Message
+	Patient Patient
+	Visit   Visit
+	// SCH-2, else SCH-1
+	ID string
+	// SCH-26 (placer order number), else SCH-27
+	Accession string
+	Start     time.Time
+	Duration  time.Duration
+	// AIG-3, else AIS-3: the scanner or modality booked
+	Resource string
+	// AIL-3
+	Room      string
+	Status    AppointmentStatus
+	EventTime time.Time
+}
+
+func (a *Appointment) NoShow() bool {
+	return a.Status == AppointmentNoShow
+}
+
+func (a *Appointment) validate() error {
+	if a.ID == "" {
+		return ValidationError{"appointment_id", "SCH-1 and SCH-2 are empty"}
+	}
+	return nil
+}
+
+// Appointments with an accession share the exam's lock so that a SIU and an
+// ORM for the same order don't race to link.
+func (a *Appointment) lockKeys(scope LockScope) []string {
+	switch scope {
+	case LockAccession:
+		if a.Accession != "" {
+			return []string{examLockKey(a.Message.ReceivingApp, a.Accession)}
+		}
+		return []string{"appointment:" + a.Message.ReceivingApp + "|" + a.ID}
+	case LockPatient:
+		return []string{patientLockKey(a.Visit)}
+	default:
+		return nil
+	}
+}
+
+// SaveSIU upserts the appointment, keyed by receiving app and appointment
+// ID. Updates older than the last event applied are ignored, and blank
+// fields leave what's stored alone.
+func (h *HL7Repo) SaveSIU(ctx context.Context, siu *Appointment) error {
+	if err := siu.validate(); err != nil {
+		return err
+	}
+	keys := siu.lockKeys(h.lockScope)
+	release, err := h.acquire(ctx, keys)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Printf("error with rollback: %v\n", err)
+		}
+	}()
+
+	qtx := h.Queries.WithTx(tx)
+	if err := lockKeys(ctx, qtx, keys); err != nil {
+		return err
+	}
+	msgID, err := h.saveMessage(ctx, qtx, siu.Message)
+	if err != nil {
+		return err
+	}
+	sID, err := qtx.CreateSite(ctx, createSiteParam(siu.Visit.Site, msgID))
+	if err != nil {
+		return dbErr{"site", err}
+	}
+	var mID pgtype.Int8
+	if siu.Visit.MRN.Value != "" {
+		pID, err := savePatientByMRN(ctx, qtx, siu.Patient, siu.Visit.MRN, sID, msgID)
+		if err != nil {
+			return err
+		}
+		id, err := qtx.CreateMrn(ctx, createMrnParam(siu.Visit.MRN, sID, pID, msgID))
+		if err != nil {
+			return dbErr{"MRN", err}
+		}
+		mID = pgtype.Int8{Int64: id, Valid: true}
+	}
+	var eID pgtype.Int8
+	if siu.Accession != "" {
+		current, err := currentExamEvent(ctx, qtx, siu.Message.ReceivingApp, siu.Accession)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			eID = pgtype.Int8{Int64: current.ID, Valid: true}
+		}
+	}
+	if _, err := qtx.CreateAppointment(ctx, createAppointmentParam(*siu, sID, mID, eID, msgID)); err != nil {
+		return dbErr{"appointment", err}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// linkAppointments points appointments booked for an accession before its
+// exam existed at the exam.
+func linkAppointments(ctx context.Context, qtx *database.Queries, sendingApp, accession string, examID int64) error {
+	err := qtx.LinkAppointmentsToExam(ctx, database.LinkAppointmentsToExamParams{
+		ExamID:     pgtype.Int8{Int64: examID, Valid: true},
+		SendingApp: sendingApp,
+		Accession:  accession,
+	})
+	if err != nil {
+		return dbErr{"appointment", err}
+	}
+	return nil
+}
+
+func createAppointmentParam(obj Appointment, siteID int32, mrnID, examID pgtype.Int8, msgID int64) database.CreateAppointmentParams {
+	params := database.CreateAppointmentParams{}
+	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
+	params.SiteID = pgtype.Int4{Int32: siteID, Valid: true}
+	params.MrnID = mrnID
+	params.ExamID = examID
+	params.SendingApp = obj.Message.ReceivingApp
+	params.AppointmentID = obj.ID
+	params.Accession = obj.Accession
+	params.StartDt = pgtype.Timestamp{Time: obj.Start, Valid: !obj.Start.IsZero()}
+	params.DurationMinutes = pgtype.Int4{Int32: int32(obj.Duration / time.Minute), Valid: obj.Duration > 0} // #nosec G115 -- appointments are minutes to hours long
+	params.Resource = pgtype.Text{String: obj.Resource, Valid: true}
+	params.Room = pgtype.Text{String: obj.Room, Valid: true}
+	params.Status = string(obj.Status)
+	params.NoShow = obj.NoShow()
+	params.LastEvent = obj.Message.TriggerEvent
+	params.LastEventDt = pgtype.Timestamp{Time: obj.EventTime, Valid: !obj.EventTime.IsZero()}
+	return params
+}
+
+// SiteAppointmentMetrics compares what a site scheduled with what it
+// performed over a window of appointment start times.
+type SiteAppointmentMetrics struct {
+	Site      string `json:"site"`
+	Scheduled int64  `json:"scheduled"`
+	Performed int64  `json:"performed"`
+	NoShows   int64  `json:"no_shows"`
+	Cancelled int64  `json:"cancelled"`
+	// Performed and NoShows over Scheduled; 0 when nothing was scheduled
+	PerformedRate float64 `json:"performed_rate"`
+	NoShowRate    float64 `json:"no_show_rate"`
+}
+
+// AppointmentMetrics returns per-site counts for appointments starting in
+// [from, to). Cancelled appointments don't count as scheduled; an
+// appointment counts as performed once its exam has ended or been reported.
+func (h *HL7Repo) AppointmentMetrics(ctx context.Context, from, to time.Time) ([]SiteAppointmentMetrics, error) {
+	rows, err := h.Queries.GetAppointmentMetricsBySite(ctx, database.GetAppointmentMetricsBySiteParams{
+		StartFrom: pgtype.Timestamp{Time: from, Valid: true},
+		StartTo:   pgtype.Timestamp{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics := make([]SiteAppointmentMetrics, len(rows))
+	for i, r := range rows {
+		metrics[i] = DBtoSiteAppointmentMetrics(r)
+	}
+	return metrics, nil
+}
+
+func DBtoSiteAppointmentMetrics(r database.GetAppointmentMetricsBySiteRow) SiteAppointmentMetrics {
+	m := SiteAppointmentMetrics{
+		Site:      r.SiteCode,
+		Scheduled: r.Scheduled,
+		Performed: r.Performed,
+		NoShows:   r.NoShows,
+		Cancelled: r.Cancelled,
+	}
+	if m.Scheduled > 0 {
+		m.PerformedRate = float64(m.Performed) / float64(m.Scheduled)
+		m.NoShowRate = float64(m.NoShows) / float64(m.Scheduled)
+	}
+	return m
+}
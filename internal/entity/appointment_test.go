@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/s-hammon/volta/internal/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppointmentStatusFrom(t *testing.T) {
+	require.Equal(t, AppointmentBooked, AppointmentStatusFrom("S12", ""))
+	require.Equal(t, AppointmentPending, AppointmentStatusFrom("S12", "Pending"))
+	require.Equal(t, AppointmentCancelled, AppointmentStatusFrom("S15", "Booked"))
+	require.Equal(t, AppointmentNoShow, AppointmentStatusFrom("S26", "Booked"))
+}
+
+func TestAppointment_LockKeys(t *testing.T) {
+	a := &Appointment{
+		Message:   Message{ReceivingApp: "STRIC"},
+		Visit:     Visit{Site: Site{Code: "MHS"}, MRN: MRN{Value: "123456"}},
+		ID:        "S1",
+		Accession: "A1",
+	}
+	require.NoError(t, a.validate())
+	require.Equal(t, []string{"exam:STRIC|A1"}, a.lockKeys(LockAccession))
+	require.Equal(t, []string{"patient:MHS|123456"}, a.lockKeys(LockPatient))
+	require.Empty(t, a.lockKeys(LockOff))
+
+	a.Accession = ""
+	require.Equal(t, []string{"appointment:STRIC|S1"}, a.lockKeys(LockAccession))
+
+	a.ID = ""
+	var ve ValidationError
+	require.ErrorAs(t, a.validate(), &ve)
+	require.Equal(t, "appointment_id", ve.Field)
+}
+
+func TestDBtoSiteAppointmentMetrics(t *testing.T) {
+	m := DBtoSiteAppointmentMetrics(database.GetAppointmentMetricsBySiteRow{
+		SiteCode:  "MHS",
+		Scheduled: 8,
+		Performed: 6,
+		NoShows:   2,
+		Cancelled: 1,
+	})
+	require.Equal(t, 0.75, m.PerformedRate)
+	require.Equal(t, 0.25, m.NoShowRate)
+
+	m = DBtoSiteAppointmentMetrics(database.GetAppointmentMetricsBySiteRow{SiteCode: "MHS", Cancelled: 3})
+	require.Zero(t, m.NoShowRate)
+}
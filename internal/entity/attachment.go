@@ -0,0 +1,138 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/blob"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// Attachment value types (OBX-2).
+const (
+	EncapsulatedData = "ED"
+	ReferencePointer = "RP"
+)
+
+// Attachment is a document sent with a report: an encapsulated document
+// (OBX ED, e.g. a PDF) with its contents, or a reference pointer (OBX RP)
+// to one kept elsewhere.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	ReportID    int64     `json:"report_id"`
+	Sequence    int       `json:"sequence"`
+	ValueType   string    `json:"value_type"`
+	MIMEType    string    `json:"mime_type"`
+	Size        int64     `json:"size"`
+	ContentHash string    `json:"content_hash,omitempty"`
+	Reference   string    `json:"reference,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Data        []byte    `json:"-"`
+}
+
+// WithBlobStore keeps attachment contents in s, keyed by content hash,
+// instead of in report_attachments.
+func WithBlobStore(s blob.Store) RepoOption {
+	return func(h *HL7Repo) { h.blobs = s }
+}
+
+// saveAttachments adds the report's attachments, in OBX order, to the
+// report saved as reportID. A resent report has the same attachments, so
+// they're only added once. Blobs are put before the transaction commits; a
+// rollback leaves them behind, but they're found by hash if it's retried.
+func (h *HL7Repo) saveAttachments(ctx context.Context, qtx *database.Queries, reportID int64, report Report, msgID int64) error {
+	for i, a := range report.Attachments {
+		params := createReportAttachmentParam(a, reportID, i+1, msgID)
+		if h.blobs != nil && len(a.Data) > 0 {
+			if err := h.blobs.Put(ctx, params.ContentHash, a.Data); err != nil {
+				return fmt.Errorf("error storing attachment: %w", err)
+			}
+			params.BlobKey, params.Data = params.ContentHash, nil
+		}
+		if err := qtx.CreateReportAttachment(ctx, params); err != nil {
+			return dbErr{"report attachment", err}
+		}
+	}
+	return nil
+}
+
+func createReportAttachmentParam(obj Attachment, reportID int64, sequence int, msgID int64) database.CreateReportAttachmentParams {
+	params := database.CreateReportAttachmentParams{}
+	params.ReportID = reportID
+	params.Sequence = int32(sequence) // #nosec G115 -- one per OBX
+	params.ValueType = obj.ValueType
+	params.MimeType = obj.MIMEType
+	params.Reference = obj.Reference
+	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
+	if obj.ValueType == EncapsulatedData {
+		params.Size = int64(len(obj.Data))
+		params.ContentHash = ContentHash(obj.Data)
+		params.Data = obj.Data
+	}
+	return params
+}
+
+// ReportAttachments lists a report's attachments, without their contents.
+func (h *HL7Repo) ReportAttachments(ctx context.Context, reportID int64) ([]Attachment, error) {
+	rows, err := h.Queries.ListReportAttachments(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	attachments := make([]Attachment, len(rows))
+	for i, r := range rows {
+		attachments[i] = Attachment{
+			ID:          r.ID,
+			ReportID:    r.ReportID,
+			Sequence:    int(r.Sequence),
+			ValueType:   r.ValueType,
+			MIMEType:    r.MimeType,
+			Size:        r.Size,
+			ContentHash: r.ContentHash,
+			Reference:   r.Reference,
+			CreatedAt:   r.CreatedAt.Time,
+		}
+	}
+	return attachments, nil
+}
+
+// ReportAttachment returns an attachment with its contents, from the blob
+// store if it was saved there, checked against the stored hash. A reference
+// pointer has no contents.
+func (h *HL7Repo) ReportAttachment(ctx context.Context, id int64) (Attachment, error) {
+	row, err := h.Queries.GetReportAttachment(ctx, id)
+	if err != nil {
+		return Attachment{}, err
+	}
+	a := DBtoAttachment(row)
+	if row.BlobKey != "" {
+		if h.blobs == nil {
+			return Attachment{}, fmt.Errorf("attachment %d is in a blob store, but none is configured", id)
+		}
+		if a.Data, err = h.blobs.Get(ctx, row.BlobKey); err != nil {
+			return Attachment{}, fmt.Errorf("error getting attachment %d: %w", id, err)
+		}
+	}
+	if a.ValueType == EncapsulatedData {
+		if hash := ContentHash(a.Data); hash != a.ContentHash {
+			return Attachment{}, fmt.Errorf("attachment %d is corrupt: hash %s, want %s", id, hash, a.ContentHash)
+		}
+	}
+	return a, nil
+}
+
+func DBtoAttachment(r database.ReportAttachment) Attachment {
+	return Attachment{
+		ID:          r.ID,
+		ReportID:    r.ReportID,
+		Sequence:    int(r.Sequence),
+		ValueType:   r.ValueType,
+		MIMEType:    r.MimeType,
+		Size:        r.Size,
+		ContentHash: r.ContentHash,
+		Reference:   r.Reference,
+		CreatedAt:   r.CreatedAt.Time,
+		Data:        r.Data,
+	}
+}
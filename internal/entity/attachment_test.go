@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateReportAttachmentParam(t *testing.T) {
+	pdf := Attachment{ValueType: EncapsulatedData, MIMEType: "application/pdf", Data: []byte("Normal.\n")}
+	params := createReportAttachmentParam(pdf, 7, 2, 9)
+	require.Equal(t, int64(7), params.ReportID)
+	require.Equal(t, int32(2), params.Sequence)
+	require.Equal(t, "application/pdf", params.MimeType)
+	require.Equal(t, int64(8), params.Size)
+	require.Equal(t, "d4b23841275cee2e2db729fab75031305e57691ebadde93f085e2fcea1bf307f", params.ContentHash)
+	require.Equal(t, []byte("Normal.\n"), params.Data)
+	require.Equal(t, int64(9), params.MessageID.Int64)
+
+	rp := Attachment{ValueType: ReferencePointer, MIMEType: "image/jpeg", Reference: "https://pacs/wado?uid=1.2.3"}
+	params = createReportAttachmentParam(rp, 7, 3, 9)
+	require.Equal(t, "https://pacs/wado?uid=1.2.3", params.Reference)
+	require.Empty(t, params.ContentHash)
+	require.Nil(t, params.Data)
+}
@@ -0,0 +1,431 @@
+package entity
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+var ErrAlreadyAcknowledged = errors.New("critical result already acknowledged")
+
+// CriticalRule finds a critical finding in a report, either by patterns in
+// its text or by the abnormal flags (OBX-8, HL7 table 0078) of its OBX
+// segments.
+type CriticalRule struct {
+	Name          string   `json:"name"`
+	Patterns      []string `json:"patterns"`
+	AbnormalFlags []string `json:"abnormal_flags"`
+
+	patterns []*regexp.Regexp
+}
+
+// CriticalRules are tried in order, so a report's findings come out the
+// same way every time.
+type CriticalRules []CriticalRule
+
+// DefaultCriticalRules finds the usual ACR "notify now" findings, and any
+// OBX flagged critical by the sender.
+var DefaultCriticalRules = mustCriticalRules(CriticalRules{
+	{Name: "pneumothorax", Patterns: []string{`(?i)\bpneumothora(x|ces)\b`}},
+	{Name: "pulmonary_embolism", Patterns: []string{`(?i)\bpulmonary\s+(embolus|emboli|embolism)\b`, `(?i)\bsaddle\s+(embolus|embolism)\b`}},
+	{Name: "aortic_dissection", Patterns: []string{`(?i)\baortic\s+dissection\b`, `(?i)\bdissection\s+of\s+the\s+(thoracic\s+|abdominal\s+)?aorta\b`}},
+	{Name: "intracranial_hemorrhage", Patterns: []string{`(?i)\b(intracranial|intraparenchymal|subarachnoid|subdural|epidural)\s+(hemorrhage|haemorrhage|hematoma)\b`}},
+	{Name: "free_air", Patterns: []string{`(?i)\bfree\s+(intraperitoneal\s+)?air\b`, `(?i)\bpneumoperitoneum\b`}},
+	{Name: "torsion", Patterns: []string{`(?i)\b(testicular|ovarian|adnexal)\s+torsion\b`}},
+	{Name: "cord_compression", Patterns: []string{`(?i)\bcord\s+compression\b`}},
+	{Name: "critical_flag", AbnormalFlags: []string{"AA", "HH", "LL"}},
+})
+
+// ParseCriticalRules reads critical rules as JSON, e.g.
+// [{"name": "pneumothorax", "patterns": ["(?i)pneumothorax"]},
+// {"name": "critical_flag", "abnormal_flags": ["AA", "HH", "LL"]}].
+// Patterns are regular expressions matched against the report text.
+func ParseCriticalRules(data []byte) (CriticalRules, error) {
+	var r CriticalRules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid critical rules: %w", err)
+	}
+	if err := r.compile(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func mustCriticalRules(r CriticalRules) CriticalRules {
+	if err := r.compile(); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r CriticalRules) compile() error {
+	names := map[string]bool{}
+	for i, rule := range r {
+		switch {
+		case rule.Name == "":
+			return fmt.Errorf("invalid critical rules: rule %d has no name", i+1)
+		case names[rule.Name]:
+			return fmt.Errorf("invalid critical rules: %s is defined twice", rule.Name)
+		case len(rule.Patterns) == 0 && len(rule.AbnormalFlags) == 0:
+			return fmt.Errorf("invalid critical rules: %s has no patterns or abnormal flags", rule.Name)
+		}
+		names[rule.Name] = true
+		rule.patterns = make([]*regexp.Regexp, len(rule.Patterns))
+		for j, pattern := range rule.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid critical rules: %s pattern %q: %w", rule.Name, pattern, err)
+			}
+			rule.patterns[j] = re
+		}
+		r[i] = rule
+	}
+	return nil
+}
+
+// WithCriticalRules replaces DefaultCriticalRules. No rules turns detection
+// off.
+func WithCriticalRules(r CriticalRules) RepoOption {
+	return func(h *HL7Repo) { h.critical = r }
+}
+
+// CriticalFinding is what a rule matched in a report: the sentence, or the
+// flagged OBX's text.
+type CriticalFinding struct {
+	Rule    string
+	Matched string
+}
+
+var (
+	// sentenceBreak ends the text shown for a match. A period has to be
+	// followed by a space, so "2.5 cm" stays whole.
+	sentenceBreak = regexp.MustCompile(`[.;](\s|$)|\n`)
+	// clauseBreak ends the reach of a negation, so "No fracture, but there
+	// is a pneumothorax" is still a finding.
+	clauseBreak = regexp.MustCompile(`(?i)[.;,](\s|$)|\n|\b(but|however|although)\b`)
+	negation    = regexp.MustCompile(`(?i)\b(no|not|without|negative\s+for|free\s+of|rules?\s+out|ruled\s+out|resolved)\b`)
+	// "No change in the large pneumothorax" isn't negated.
+	noChange = regexp.MustCompile(`(?i)\bno\s+(significant\s+|interval\s+)?change\b`)
+)
+
+// Detect returns a finding for each rule that matches the report, in rule
+// order. A pattern preceded in its clause by a negation ("no", "without",
+// "negative for", ...) doesn't count.
+func (r CriticalRules) Detect(report Report) []CriticalFinding {
+	lines := report.Lines
+	if len(lines) == 0 && report.Body != "" {
+		lines = []ReportLine{{Text: report.Body}}
+	}
+	var findings []CriticalFinding
+	for _, rule := range r {
+		if matched, ok := rule.match(lines); ok {
+			findings = append(findings, CriticalFinding{Rule: rule.Name, Matched: matched})
+		}
+	}
+	return findings
+}
+
+func (c CriticalRule) match(lines []ReportLine) (string, bool) {
+	for _, line := range lines {
+		for _, flag := range line.AbnormalFlags {
+			if flag != "" && slices.Contains(c.AbnormalFlags, flag) {
+				return strings.TrimSpace(flag + ": " + line.Text), true
+			}
+		}
+		for _, re := range c.patterns {
+			for _, loc := range re.FindAllStringIndex(line.Text, -1) {
+				if negated(line.Text[:loc[0]]) {
+					continue
+				}
+				return sentence(line.Text, loc), true
+			}
+		}
+	}
+	return "", false
+}
+
+// negated reports whether the clause leading up to a match negates it.
+func negated(before string) bool {
+	if breaks := clauseBreak.FindAllStringIndex(before, -1); len(breaks) > 0 {
+		before = before[breaks[len(breaks)-1][1]:]
+	}
+	return negation.MatchString(noChange.ReplaceAllString(before, ""))
+}
+
+// sentence is the sentence of text around the match at loc.
+func sentence(text string, loc []int) string {
+	start, end := 0, len(text)
+	if breaks := sentenceBreak.FindAllStringIndex(text[:loc[0]], -1); len(breaks) > 0 {
+		start = breaks[len(breaks)-1][1]
+	}
+	if next := sentenceBreak.FindStringIndex(text[loc[1]:]); next != nil {
+		end = loc[1] + next[0] + 1
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+// CriticalResult is a critical finding recorded against an exam, and where
+// it is in being passed on and acknowledged.
+type CriticalResult struct {
+	ID             int64      `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReportID       int64      `json:"report_id"`
+	ExamID         int64      `json:"exam_id"`
+	SendingApp     string     `json:"sending_app"`
+	Accession      string     `json:"accession"`
+	Radiologist    string     `json:"radiologist,omitempty"`
+	Rule           string     `json:"rule"`
+	Matched        string     `json:"matched"`
+	NotifiedAt     *time.Time `json:"notified_at,omitempty"`
+	NotifyError    string     `json:"notify_error,omitempty"`
+	NotifiedBy     []string   `json:"notified_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+}
+
+// CriticalNotifier passes a new critical result on to someone who can act
+// on it. Name identifies it in notified_by, so it should stay the same
+// across restarts.
+type CriticalNotifier interface {
+	Name() string
+	NotifyCritical(ctx context.Context, c CriticalResult) error
+}
+
+// WithCriticalNotifiers sends each new critical result to every n, in
+// order, from NotifyCritical rather than while the report is saved.
+func WithCriticalNotifiers(n ...CriticalNotifier) RepoOption {
+	return func(h *HL7Repo) { h.notifiers = append(h.notifiers, n...) }
+}
+
+// saveCriticalResults records the findings against the exam. A finding
+// already recorded for the exam, e.g. from a resent or corrected report,
+// isn't recorded again, so only the IDs of new results are returned.
+func saveCriticalResults(ctx context.Context, qtx *database.Queries, findings []CriticalFinding, examID, reportID, radID, msgID int64) ([]int64, error) {
+	var ids []int64
+	for _, f := range findings {
+		id, err := qtx.CreateCriticalResult(ctx, database.CreateCriticalResultParams{
+			ReportID:      reportID,
+			ExamID:        examID,
+			RadiologistID: pgtype.Int8{Int64: radID, Valid: radID != 0},
+			Rule:          f.Rule,
+			Matched:       f.Matched,
+			MessageID:     pgtype.Int8{Int64: msgID, Valid: true},
+		})
+		switch {
+		case err == nil:
+			ids = append(ids, id)
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, dbErr{"critical result", err}
+		}
+	}
+	return ids, nil
+}
+
+// DefaultCriticalRetry is how often NotifyCritical retries when it isn't
+// given a positive interval.
+const DefaultCriticalRetry = 30 * time.Second
+
+const (
+	// criticalBatch is how many critical results NotifyPendingCritical
+	// claims at a time.
+	criticalBatch = 50
+	// criticalClaim is how long a claim keeps other workers off a result.
+	// It's cleared once the result's been tried, so it only runs out if
+	// the worker stops partway through a batch.
+	criticalClaim = 10 * time.Minute
+)
+
+// NotifyCritical sends critical results in the background until ctx is
+// done: whenever a report records a new one, and every retry, so that a
+// result whose notifiers failed is tried again until it's sent or
+// acknowledged. A retry that isn't positive means DefaultCriticalRetry.
+func (h *HL7Repo) NotifyCritical(ctx context.Context, retry time.Duration) {
+	if retry <= 0 {
+		retry = DefaultCriticalRetry
+	}
+	ticker := time.NewTicker(retry)
+	defer ticker.Stop()
+	for {
+		if _, err := h.NotifyPendingCritical(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("error notifying critical results: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.criticalAdded:
+		case <-ticker.C:
+		}
+	}
+}
+
+// NotifyPendingCritical sends every critical result that's neither been
+// notified nor acknowledged to the notifiers that don't have it yet, and
+// records how it went. It returns how many were sent to all of them. A
+// notifier that fails doesn't stop the rest; its error is kept with the
+// result, which is left for the next run to send to that notifier again.
+// Results are claimed before they're sent, so that other instances skip
+// them, but no transaction is held open while the notifiers are called.
+func (h *HL7Repo) NotifyPendingCritical(ctx context.Context) (int, error) {
+	if len(h.notifiers) == 0 {
+		return 0, nil
+	}
+	var cursor int64
+	sent := 0
+	for {
+		n, last, err := h.notifyCriticalBatch(ctx, cursor)
+		sent += n
+		if err != nil || last == 0 {
+			return sent, err
+		}
+		cursor = last
+	}
+}
+
+// notifyCriticalBatch claims and sends the next batch of unsent critical
+// results after cursor. It returns how many were sent and the last ID it
+// claimed, or 0 if there were none left.
+func (h *HL7Repo) notifyCriticalBatch(ctx context.Context, cursor int64) (int, int64, error) {
+	rows, err := h.Queries.ClaimCriticalResults(ctx, database.ClaimCriticalResultsParams{
+		CursorID:     cursor,
+		PageSize:     criticalBatch,
+		ClaimSeconds: int32(criticalClaim / time.Second),
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, 0, err
+	}
+	slices.SortFunc(rows, func(a, b database.ClaimCriticalResultsRow) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	sent := 0
+	for _, r := range rows {
+		ok, err := h.notifyCriticalResult(ctx, r.ID, r.NotifiedBy)
+		if err != nil {
+			return sent, 0, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, rows[len(rows)-1].ID, nil
+}
+
+// notifyCriticalResult sends a claimed critical result to each notifier not
+// in notifiedBy, records which now have it, and reports whether they all
+// do.
+func (h *HL7Repo) notifyCriticalResult(ctx context.Context, id int64, notifiedBy []string) (bool, error) {
+	c, err := h.CriticalResult(ctx, id)
+	if err != nil {
+		return false, dbErr{"critical result", err}
+	}
+	var errs []error
+	for _, n := range h.notifiers {
+		if slices.Contains(notifiedBy, n.Name()) {
+			continue
+		}
+		if err := n.NotifyCritical(ctx, c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+			continue
+		}
+		notifiedBy = append(notifiedBy, n.Name())
+	}
+	var notifyErr string
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("error notifying critical result %d: %v\n", c.ID, err)
+		notifyErr = err.Error()
+	}
+	if notifiedBy == nil {
+		notifiedBy = []string{}
+	}
+	if err := h.Queries.SetCriticalResultNotified(ctx, database.SetCriticalResultNotifiedParams{
+		NotifiedBy:  notifiedBy,
+		NotifyError: notifyErr,
+		ID:          c.ID,
+	}); err != nil {
+		return false, dbErr{"critical result", err}
+	}
+	return notifyErr == "", nil
+}
+
+// CriticalResult returns a critical result by ID.
+func (h *HL7Repo) CriticalResult(ctx context.Context, id int64) (CriticalResult, error) {
+	row, err := h.Queries.GetCriticalResult(ctx, id)
+	if err != nil {
+		return CriticalResult{}, err
+	}
+	return DBtoCriticalResult(database.ListCriticalResultsRow(row)), nil
+}
+
+// ListCriticalResults pages through critical results after cursorID, oldest
+// first. status is "open" for those not yet acknowledged, "acknowledged", or
+// "" for both.
+func (h *HL7Repo) ListCriticalResults(ctx context.Context, status string, cursorID int64, limit int32) ([]CriticalResult, error) {
+	rows, err := h.Queries.ListCriticalResults(ctx, database.ListCriticalResultsParams{
+		CursorID: cursorID,
+		Status:   status,
+		PageSize: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CriticalResult, len(rows))
+	for i, r := range rows {
+		results[i] = DBtoCriticalResult(r)
+	}
+	return results, nil
+}
+
+// AcknowledgeCriticalResult records who acknowledged a critical result. A
+// result can only be acknowledged once; after that it returns
+// ErrAlreadyAcknowledged.
+func (h *HL7Repo) AcknowledgeCriticalResult(ctx context.Context, id int64, by string) (CriticalResult, error) {
+	n, err := h.Queries.AcknowledgeCriticalResult(ctx, database.AcknowledgeCriticalResultParams{
+		AcknowledgedBy: by,
+		ID:             id,
+	})
+	if err != nil {
+		return CriticalResult{}, err
+	}
+	c, err := h.CriticalResult(ctx, id)
+	if err != nil {
+		return CriticalResult{}, err
+	}
+	if n == 0 {
+		return c, ErrAlreadyAcknowledged
+	}
+	return c, nil
+}
+
+func DBtoCriticalResult(r database.ListCriticalResultsRow) CriticalResult {
+	c := CriticalResult{
+		ID:             r.ID,
+		CreatedAt:      r.CreatedAt.Time,
+		ReportID:       r.ReportID,
+		ExamID:         r.ExamID,
+		SendingApp:     r.SendingApp,
+		Accession:      r.Accession,
+		Radiologist:    radiologistName(r.RadiologistFirstName, r.RadiologistLastName),
+		Rule:           r.Rule,
+		Matched:        r.Matched,
+		NotifyError:    r.NotifyError,
+		NotifiedBy:     r.NotifiedBy,
+		AcknowledgedBy: r.AcknowledgedBy,
+	}
+	if r.NotifiedAt.Valid {
+		c.NotifiedAt = &r.NotifiedAt.Time
+	}
+	if r.AcknowledgedAt.Valid {
+		c.AcknowledgedAt = &r.AcknowledgedAt.Time
+	}
+	return c
+}
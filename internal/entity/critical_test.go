@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCriticalRules_Detect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []CriticalFinding
+	}{
+		{"none", "Lungs are clear. No acute disease.", nil},
+		{"finding", "FINDINGS: Heart is normal. Large right pneumothorax with 2.5 cm apical separation. No effusion.",
+			[]CriticalFinding{{"pneumothorax", "Large right pneumothorax with 2.5 cm apical separation."}}},
+		{"negated", "No pneumothorax or pleural effusion.", nil},
+		{"negated phrase", "Negative for pulmonary embolism; no aortic dissection.", nil},
+		{"other clause", "No fracture, but there is a small subdural hematoma.",
+			[]CriticalFinding{{"intracranial_hemorrhage", "No fracture, but there is a small subdural hematoma."}}},
+		{"no change", "No change in the large left pneumothorax.",
+			[]CriticalFinding{{"pneumothorax", "No change in the large left pneumothorax."}}},
+		{"second mention", "No pneumothorax on the left. Small right pneumothorax.",
+			[]CriticalFinding{{"pneumothorax", "Small right pneumothorax."}}},
+		{"rule order", "Free air under the diaphragm. Right pneumothorax.",
+			[]CriticalFinding{{"pneumothorax", "Right pneumothorax."}, {"free_air", "Free air under the diaphragm."}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultCriticalRules.Detect(Report{Lines: []ReportLine{{Text: tt.text}}})
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCriticalRules_Detect_Body(t *testing.T) {
+	got := DefaultCriticalRules.Detect(Report{Body: "IMPRESSION:\nAcute aortic dissection.\n"})
+	require.Equal(t, []CriticalFinding{{"aortic_dissection", "Acute aortic dissection."}}, got)
+}
+
+func TestCriticalRules_Detect_AbnormalFlags(t *testing.T) {
+	report := Report{Lines: []ReportLine{
+		{Text: "Lungs are clear.", AbnormalFlags: []string{"N"}},
+		{Text: "Potassium 6.8", AbnormalFlags: []string{"H", "HH"}},
+	}}
+	require.Equal(t, []CriticalFinding{{"critical_flag", "HH: Potassium 6.8"}}, DefaultCriticalRules.Detect(report))
+}
+
+func TestParseCriticalRules(t *testing.T) {
+	r, err := ParseCriticalRules([]byte(`[
+		{"name": "stroke", "patterns": ["(?i)\\bacute\\s+infarct"]},
+		{"name": "flagged", "abnormal_flags": ["A"]}
+	]`))
+	require.NoError(t, err)
+	got := r.Detect(Report{Lines: []ReportLine{{Text: "Acute infarct in the left MCA territory.", AbnormalFlags: []string{"A"}}}})
+	require.Equal(t, []CriticalFinding{
+		{"stroke", "Acute infarct in the left MCA territory."},
+		{"flagged", "A: Acute infarct in the left MCA territory."},
+	}, got)
+
+	for _, data := range []string{
+		`{"name": "stroke"}`,
+		`[{"patterns": ["infarct"]}]`,
+		`[{"name": "stroke"}]`,
+		`[{"name": "stroke", "patterns": ["("]}]`,
+		`[{"name": "stroke", "patterns": ["infarct"]}, {"name": "stroke", "patterns": ["stroke"]}]`,
+	} {
+		_, err := ParseCriticalRules([]byte(data))
+		require.Error(t, err, data)
+	}
+}
+
+func TestNotifyCritical_NonPositiveRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NotPanics(t, func() { NewRepo(nil).NotifyCritical(ctx, 0) })
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"context"
+
+	"github.com/s-hammon/volta/internal/database"
+	"github.com/s-hammon/volta/internal/objects"
+)
+
+// Document completion (TXA-17, HL7 table 0271) and availability (TXA-19,
+// HL7 table 0273) statuses that matter to the report version chain.
+const (
+	DocumentAuthenticated        = "AU"
+	DocumentLegallyAuthenticated = "LA"
+	DocumentObsolete             = "OB"
+	DocumentCancelled            = "CA"
+)
+
+// Document is what an MDM's TXA segment says about the report it carries.
+type Document struct {
+	// TXA-12, unique per document at the sender
+	ID string
+	// TXA-13: the document this one edits or replaces (T08, T10)
+	ParentID string
+	// TXA-17
+	CompletionStatus string
+	// TXA-19
+	AvailabilityStatus string
+}
+
+func (d Document) Authenticated() bool {
+	return d.CompletionStatus == DocumentAuthenticated || d.CompletionStatus == DocumentLegallyAuthenticated
+}
+
+// Obsolete reports whether the notification takes the document out of use
+// rather than adding a version of it.
+func (d Document) Obsolete() bool {
+	return d.ID != "" && (d.AvailabilityStatus == DocumentObsolete || d.AvailabilityStatus == DocumentCancelled)
+}
+
+// ReportStatus places the document in the report version chain. Until it's
+// authenticated it's a prelim. A T10 replaces a document that was already
+// made available, so once authenticated it's a correction; otherwise it's
+// the final.
+func (d Document) ReportStatus(event string) objects.ReportStatus {
+	switch {
+	case !d.Authenticated():
+		return objects.Pending
+	case event == "T10":
+		return objects.Corrected
+	default:
+		return objects.Final
+	}
+}
+
+// obsoleteDocument marks the reports saved for a document obsolete, except
+// the one saved as exceptID (0 for none), which may share its number.
+func obsoleteDocument(ctx context.Context, qtx *database.Queries, sendingApp, documentID string, exceptID int64) error {
+	if documentID == "" {
+		return nil
+	}
+	err := qtx.MarkDocumentObsolete(ctx, database.MarkDocumentObsoleteParams{
+		SendingApp: sendingApp,
+		DocumentID: documentID,
+		ExceptID:   exceptID,
+	})
+	if err != nil {
+		return dbErr{"document", err}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/s-hammon/volta/internal/objects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_ReportStatus(t *testing.T) {
+	require.Equal(t, objects.Pending, Document{CompletionStatus: "DI"}.ReportStatus("T02"))
+	require.Equal(t, objects.Pending, Document{CompletionStatus: "PA"}.ReportStatus("T10"))
+	require.Equal(t, objects.Final, Document{CompletionStatus: "AU"}.ReportStatus("T02"))
+	require.Equal(t, objects.Final, Document{CompletionStatus: "LA"}.ReportStatus("T04"))
+	require.Equal(t, objects.Final, Document{CompletionStatus: "AU"}.ReportStatus("T08"))
+	require.Equal(t, objects.Corrected, Document{CompletionStatus: "AU"}.ReportStatus("T10"))
+}
+
+func TestDocument_Obsolete(t *testing.T) {
+	require.True(t, Document{ID: "DOC1", AvailabilityStatus: "OB"}.Obsolete())
+	require.True(t, Document{ID: "DOC1", AvailabilityStatus: "CA"}.Obsolete())
+	require.False(t, Document{ID: "DOC1", AvailabilityStatus: "AV"}.Obsolete())
+	// there's nothing to make obsolete without a document number
+	require.False(t, Document{AvailabilityStatus: "OB"}.Obsolete())
+
+	obs := &Observation{Report: Report{Document: Document{ID: "DOC1", AvailabilityStatus: "OB"}}}
+	require.NoError(t, obs.validate())
+	obs.Report.Document.AvailabilityStatus = "AV"
+	require.Error(t, obs.validate())
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantTransient  bool
+		wantValidation bool
+	}{
+		{"nil", nil, false, false},
+		{"validation", ValidationError{Field: "accession", Reason: "missing"}, false, true},
+		{"wrapped validation", fmt.Errorf("saving: %w", ValidationError{"exam", "none"}), false, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true, false},
+		{"connection lost", dbErr{"exam", &pgconn.PgError{Code: "08006"}}, true, false},
+		{"not null violation", &pgconn.PgError{Code: "23502"}, false, true},
+		{"bad datetime", &pgconn.PgError{Code: "22007"}, false, true},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, false, false},
+		{"deadline", context.DeadlineExceeded, true, false},
+		{"other", errors.New("boom"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantTransient, IsTransient(tt.err))
+			require.Equal(t, tt.wantValidation, IsValidation(tt.err))
+		})
+	}
+}
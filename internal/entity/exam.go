@@ -7,40 +7,37 @@ import (
 	"github.com/s-hammon/volta/internal/database"
 )
 
-type ExamStatus string
-
-const (
-	ExamScheduled  ExamStatus = "SC"
-	ExamInProgress ExamStatus = "IP"
-	ExamComplete   ExamStatus = "CM"
-	ExamCancelled  ExamStatus = "CA"
-)
-
-func NewExamStatus(s string) ExamStatus {
-	switch s {
-	case "SC", "IP", "CM", "CA":
-		return ExamStatus(s)
-	default:
-		return ExamScheduled
-	}
-}
-
-func (o ExamStatus) String() string {
-	return string(o)
-}
-
 type Exam struct {
 	Base
 	Accession     string
 	Priority      string
 	Procedure     Procedure
 	CurrentStatus ExamStatus
-	Provider      Physician
-	Site          Site
-	Scheduled     time.Time
-	Begin         time.Time
-	End           time.Time
-	Cancelled     time.Time
+	// ResultStatus is the status of the exam's results (OBR-25, or the
+	// report's status), kept apart from the order status
+	ResultStatus ExamStatus
+	Provider     Physician
+	Site         Site
+	Scheduled    time.Time
+	Begin        time.Time
+	End          time.Time
+	Cancelled    time.Time
+	// EventTime is when the change this message describes happened (ORC-9,
+	// falling back to MSH-7). Updates older than the exam's last applied
+	// event are skipped.
+	EventTime time.Time
+	// Control is the order control code (ORC-1) from an ORM or OMI
+	Control OrderControl
+	// Replaces is the accession an RP/RU order replaces (ORC-8)
+	Replaces string
+	// StudyUID is the study instance UID from a v2.5 OMI's IPC segment
+	StudyUID string
+}
+
+// IsStale reports whether e describes an event from before last, the event
+// time of the newest update already applied. Unknown times are never stale.
+func (e Exam) IsStale(last time.Time) bool {
+	return !e.EventTime.IsZero() && !last.IsZero() && e.EventTime.Before(last)
 }
 
 func DBtoExam(exam database.GetExamBySendingAppAccessionRow) Exam {
@@ -73,6 +70,7 @@ func DBtoExam(exam database.GetExamBySendingAppAccessionRow) Exam {
 			Description: exam.ProcedureDescription.String,
 		},
 		CurrentStatus: NewExamStatus(exam.CurrentStatus),
+		ResultStatus:  NewExamStatus(exam.ResultStatus),
 		Site:          site,
 		Scheduled:     exam.ScheduleDt.Time,
 		Begin:         exam.BeginExamDt.Time,
@@ -93,4 +91,7 @@ func (e *Exam) timestamp(params *database.CreateExamParams) {
 	if !e.Cancelled.IsZero() {
 		params.ExamCancelledDt = pgtype.Timestamp{Time: e.Cancelled, Valid: true}
 	}
+	if !e.EventTime.IsZero() {
+		params.LastEventDt = pgtype.Timestamp{Time: e.EventTime, Valid: true}
+	}
 }
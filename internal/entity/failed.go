@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/s-hammon/volta/internal/database"
+)
+
+const (
+	FailedPending   = "pending"
+	FailedReplayed  = "replayed"
+	FailedDiscarded = "discarded"
+)
+
+// FailedMessage is a dead-lettered HL7 message along with the last error it
+// hit. Raw is kept so the message can be replayed without asking the sender
+// (or the Healthcare API) for it again.
+type FailedMessage struct {
+	Base
+	StorePath     string    `json:"store_path"`
+	Raw           []byte    `json:"-"`
+	ControlID     string    `json:"control_id"`
+	ErrorCategory string    `json:"error_category"`
+	ErrorMessage  string    `json:"error_message"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	Status        string    `json:"status"`
+}
+
+func DBtoFailedMessage(m database.FailedMessage) FailedMessage {
+	return FailedMessage{
+		Base: Base{
+			ID:        int(m.ID),
+			CreatedAt: m.CreatedAt.Time,
+			UpdatedAt: m.UpdatedAt.Time,
+		},
+		StorePath:     m.StorePath,
+		Raw:           m.Raw,
+		ControlID:     m.ControlID,
+		ErrorCategory: m.ErrorCategory,
+		ErrorMessage:  m.ErrorMessage,
+		Attempts:      int(m.Attempts),
+		FirstFailedAt: m.FirstFailedAt.Time,
+		LastFailedAt:  m.LastFailedAt.Time,
+		Status:        m.Status,
+	}
+}
+
+// RecordFailure dead-letters a message. If the same bytes already have a
+// pending entry, its attempt count and last error are updated instead.
+func (h *HL7Repo) RecordFailure(ctx context.Context, f FailedMessage) (int64, error) {
+	return h.Queries.CreateFailedMessage(ctx, database.CreateFailedMessageParams{
+		StorePath:     f.StorePath,
+		Raw:           f.Raw,
+		RawHash:       rawHash(f.Raw),
+		ControlID:     f.ControlID,
+		ErrorCategory: f.ErrorCategory,
+		ErrorMessage:  f.ErrorMessage,
+	})
+}
+
+// ResolveFailedMessage marks the pending entry for raw, if there is one,
+// replayed. It's for a message that failed and was then saved when it was
+// delivered again, so that it isn't replayed a second time. It reports
+// whether there was an entry.
+func (h *HL7Repo) ResolveFailedMessage(ctx context.Context, raw []byte) (bool, error) {
+	n, err := h.Queries.ResolveFailedMessage(ctx, rawHash(raw))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func rawHash(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *HL7Repo) GetFailedMessage(ctx context.Context, id int64) (FailedMessage, error) {
+	m, err := h.Queries.GetFailedMessageByID(ctx, id)
+	if err != nil {
+		return FailedMessage{}, err
+	}
+	return DBtoFailedMessage(m), nil
+}
+
+// ListFailedMessages pages through entries with the given status, starting
+// after cursorID.
+func (h *HL7Repo) ListFailedMessages(ctx context.Context, status string, cursorID int64, limit int32) ([]FailedMessage, error) {
+	rows, err := h.Queries.ListFailedMessages(ctx, database.ListFailedMessagesParams{
+		Status: status,
+		ID:     cursorID,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]FailedMessage, len(rows))
+	for i, r := range rows {
+		msgs[i] = DBtoFailedMessage(r)
+	}
+	return msgs, nil
+}
+
+func (h *HL7Repo) SetFailedMessageStatus(ctx context.Context, id int64, status string) error {
+	return h.Queries.UpdateFailedMessageStatus(ctx, database.UpdateFailedMessageStatusParams{
+		ID:     id,
+		Status: status,
+	})
+}
+
+// DiscardFailedMessage marks a pending entry discarded. It reports false if
+// the entry isn't pending, e.g. because it was already replayed.
+func (h *HL7Repo) DiscardFailedMessage(ctx context.Context, id int64) (bool, error) {
+	n, err := h.Queries.DiscardFailedMessage(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// StatusChange is one entry in an exam's status timeline. OldStatus is empty
+// for the message that created the exam.
+type StatusChange struct {
+	ID        int64     `json:"id"`
+	ExamID    int64     `json:"exam_id"`
+	OldStatus string    `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status"`
+	EventDT   time.Time `json:"event_dt"`
+	MessageID int64     `json:"message_id,omitempty"`
+	SourceApp string    `json:"source_app"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func DBtoStatusChange(h database.ExamStatusHistory) StatusChange {
+	return StatusChange{
+		ID:        h.ID,
+		ExamID:    h.ExamID,
+		OldStatus: h.OldStatus.String,
+		NewStatus: h.NewStatus,
+		EventDT:   h.EventDt.Time,
+		MessageID: h.MessageID.Int64,
+		SourceApp: h.SourceApp,
+		CreatedAt: h.CreatedAt.Time,
+	}
+}
+
+// recordStatusChange adds a history row if the exam's stored status differs
+// from before (previous is nil for a new exam). The status is read back
+// after the upsert so that what the database actually holds is recorded.
+func recordStatusChange(ctx context.Context, qtx *database.Queries, examID int64, previous *database.GetExamEventBySendingAppAccessionRow, exam Exam, msg Message, msgID int64) error {
+	stored, err := qtx.GetExamById(ctx, examID)
+	if err != nil {
+		return dbErr{"exam status history", err}
+	}
+	params := database.CreateExamStatusHistoryParams{
+		ExamID:    examID,
+		NewStatus: stored.CurrentStatus,
+		MessageID: pgtype.Int8{Int64: msgID, Valid: true},
+		SourceApp: msg.SendingApp,
+	}
+	if previous != nil {
+		if previous.CurrentStatus == stored.CurrentStatus {
+			return nil
+		}
+		params.OldStatus = pgtype.Text{String: previous.CurrentStatus, Valid: true}
+	}
+	if !exam.EventTime.IsZero() {
+		params.EventDt = pgtype.Timestamp{Time: exam.EventTime, Valid: true}
+	}
+	if err := qtx.CreateExamStatusHistory(ctx, params); err != nil {
+		return dbErr{"exam status history", err}
+	}
+	return nil
+}
+
+// ExamStatusHistory returns an exam's status changes in event order.
+func (h *HL7Repo) ExamStatusHistory(ctx context.Context, examID int64) ([]StatusChange, error) {
+	rows, err := h.Queries.ListExamStatusHistory(ctx, examID)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]StatusChange, len(rows))
+	for i, r := range rows {
+		changes[i] = DBtoStatusChange(r)
+	}
+	return changes, nil
+}
+
+// ExamID looks up an exam by the application it belongs to and accession.
+func (h *HL7Repo) ExamID(ctx context.Context, sendingApp, accession string) (int64, error) {
+	return h.Queries.GetExamIDBySendingAppAccession(ctx, database.GetExamIDBySendingAppAccessionParams{
+		SendingApp: sendingApp,
+		Accession:  accession,
+	})
+}
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// LockScope is what saves are serialized on, so that e.g. an ORM and an ORU
+// for the same accession can't interleave.
+type LockScope string
+
+const (
+	LockAccession LockScope = "accession"
+	LockPatient   LockScope = "patient"
+	LockOff       LockScope = "off"
+)
+
+func NewLockScope(s string) (LockScope, error) {
+	switch l := LockScope(s); l {
+	case LockAccession, LockPatient, LockOff:
+		return l, nil
+	case "":
+		return LockAccession, nil
+	default:
+		return "", fmt.Errorf("unknown lock scope %q (want accession, patient or off)", s)
+	}
+}
+
+// WithLockScope changes the lock scope from the default, LockAccession.
+func WithLockScope(scope LockScope) RepoOption {
+	return func(h *HL7Repo) { h.lockScope = scope }
+}
+
+func examLockKey(sendingApp, accession string) string {
+	return "exam:" + sendingApp + "|" + accession
+}
+
+func patientLockKey(visit Visit) string {
+	return "patient:" + visit.Site.Code + "|" + visit.MRN.Value
+}
+
+func (o *Order) lockKeys(scope LockScope) []string {
+	switch scope {
+	case LockAccession:
+		keys := make([]string, 0, len(o.Exams))
+		for _, exam := range o.Exams {
+			keys = append(keys, examLockKey(o.Message.ReceivingApp, exam.Accession))
+			if replaced := exam.replaced(); replaced != "" {
+				keys = append(keys, examLockKey(o.Message.ReceivingApp, replaced))
+			}
+		}
+		return keys
+	case LockPatient:
+		return []string{patientLockKey(o.Visit)}
+	default:
+		return nil
+	}
+}
+
+func (o *Observation) lockKeys(scope LockScope) []string {
+	switch scope {
+	case LockAccession:
+		keys := make([]string, 0, len(o.Exams))
+		for _, exam := range o.Exams {
+			keys = append(keys, examLockKey(o.Message.ReceivingApp, exam.Accession))
+		}
+		return keys
+	case LockPatient:
+		return []string{patientLockKey(o.Visit)}
+	default:
+		return nil
+	}
+}
+
+// acquire queues behind other saves in this process with any of the same
+// keys, then takes a transaction-level advisory lock on each so replicas
+// are serialized too. The returned func releases the in-process turn; the
+// advisory locks go with the transaction.
+func (h *HL7Repo) acquire(ctx context.Context, keys []string) (func(), error) {
+	release, err := h.locks.Acquire(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for lock: %w", err)
+	}
+	return release, nil
+}
+
+func lockKeys(ctx context.Context, qtx *database.Queries, keys []string) error {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	for _, k := range slices.Compact(keys) {
+		if err := qtx.AcquireAdvisoryXactLock(ctx, k); err != nil {
+			return dbErr{"advisory lock", err}
+		}
+	}
+	return nil
+}
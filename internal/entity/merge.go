@@ -0,0 +1,378 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// LinkEvent is the ADT event that links two patients without merging them.
+const LinkEvent = "A24"
+
+// ErrUnmergeUnsafe means a merge can't be undone because something it moved
+// has changed since (or it was already undone).
+var ErrUnmergeUnsafe = errors.New("unmerge isn't safe")
+
+// Merge is an ADT merge or link event (A18, A24, A34, A40). For each pair,
+// the Prior MRN's exams and visits are moved to the Survivor MRN at the
+// message's site, and the prior patient's other MRNs to the surviving
+// patient. A link (A24) only says the two patients are related, so it's
+// recorded but moves nothing.
+type Merge struct {
+	Message Message
+	Site    Site
+	Pairs   []MergePair
+}
+
+type MergePair struct {
+	Survivor MRN
+	Prior    MRN
+}
+
+func (m *Merge) validate() error {
+	if len(m.Pairs) == 0 {
+		return ValidationError{"merge", "no prior patient identifier (MRG-1 or second PID)"}
+	}
+	for i, pair := range m.Pairs {
+		if pair.Survivor.Value == "" || pair.Prior.Value == "" {
+			return ValidationError{"merge", p.Format("pair %d is missing the surviving or prior MRN", i+1)}
+		}
+	}
+	return nil
+}
+
+func (m *Merge) lockKeys(scope LockScope) []string {
+	if scope == LockOff {
+		return nil
+	}
+	keys := make([]string, 0, 2*len(m.Pairs))
+	for _, pair := range m.Pairs {
+		keys = append(keys,
+			patientLockKey(Visit{Site: m.Site, MRN: pair.Survivor}),
+			patientLockKey(Visit{Site: m.Site, MRN: pair.Prior}),
+		)
+	}
+	return keys
+}
+
+// PatientMerge is a merge as applied, with everything it moved.
+type PatientMerge struct {
+	ID                int64      `json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	MessageID         int64      `json:"message_id,omitempty"`
+	Event             string     `json:"event"`
+	SurvivorMRN       string     `json:"survivor_mrn"`
+	PriorMRN          string     `json:"prior_mrn"`
+	SurvivorPatientID int64      `json:"survivor_patient_id,omitempty"`
+	SurvivorMrnID     int64      `json:"survivor_mrn_id,omitempty"`
+	PriorPatientID    int64      `json:"prior_patient_id,omitempty"`
+	PriorMrnID        int64      `json:"prior_mrn_id,omitempty"`
+	Renamed           bool       `json:"renamed"`
+	ExamIDs           []int64    `json:"exam_ids"`
+	VisitIDs          []int64    `json:"visit_ids"`
+	MrnIDs            []int64    `json:"mrn_ids"`
+	RepointedExamIDs  []int64    `json:"repointed_exam_ids"`
+	RepointedVisitIDs []int64    `json:"repointed_visit_ids"`
+	UnmergedAt        *time.Time `json:"unmerged_at,omitempty"`
+}
+
+func DBtoPatientMerge(m database.PatientMerge) PatientMerge {
+	merge := PatientMerge{
+		ID:                m.ID,
+		CreatedAt:         m.CreatedAt.Time,
+		MessageID:         m.MessageID.Int64,
+		Event:             m.Event,
+		SurvivorMRN:       m.SurvivorMrn,
+		PriorMRN:          m.PriorMrn,
+		SurvivorPatientID: m.SurvivorPatientID.Int64,
+		SurvivorMrnID:     m.SurvivorMrnID.Int64,
+		PriorPatientID:    m.PriorPatientID.Int64,
+		PriorMrnID:        m.PriorMrnID.Int64,
+		Renamed:           m.Renamed,
+		ExamIDs:           orEmpty(m.ExamIds),
+		VisitIDs:          orEmpty(m.VisitIds),
+		MrnIDs:            orEmpty(m.MrnIds),
+		RepointedExamIDs:  orEmpty(m.RepointedExamIds),
+		RepointedVisitIDs: orEmpty(m.RepointedVisitIds),
+	}
+	if m.UnmergedAt.Valid {
+		merge.UnmergedAt = &m.UnmergedAt.Time
+	}
+	return merge
+}
+
+// SaveMerge applies every pair in one transaction and records each in
+// patient_merges. A prior MRN that isn't on file is recorded but moves
+// nothing; if only the prior MRN is on file, it's renamed to the survivor.
+func (h *HL7Repo) SaveMerge(ctx context.Context, m *Merge) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	keys := m.lockKeys(h.lockScope)
+	release, err := h.acquire(ctx, keys)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Printf("error with rollback: %v\n", err)
+		}
+	}()
+
+	qtx := h.Queries.WithTx(tx)
+	if err := lockKeys(ctx, qtx, keys); err != nil {
+		return err
+	}
+	msgID, err := h.saveMessage(ctx, qtx, m.Message)
+	if err != nil {
+		return err
+	}
+	sID, err := qtx.CreateSite(ctx, createSiteParam(m.Site, msgID))
+	if err != nil {
+		return dbErr{"site", err}
+	}
+	for _, pair := range m.Pairs {
+		if err := mergeMRN(ctx, qtx, m.Message.TriggerEvent, sID, pair, msgID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func mergeMRN(ctx context.Context, qtx *database.Queries, event string, siteID int32, pair MergePair, msgID int64) error {
+	prior, err := findMRN(ctx, qtx, siteID, pair.Prior.Value)
+	if err != nil {
+		return err
+	}
+	survivor, err := findMRN(ctx, qtx, siteID, pair.Survivor.Value)
+	if err != nil {
+		return err
+	}
+	params := database.CreatePatientMergeParams{
+		MessageID:         pgtype.Int8{Int64: msgID, Valid: true},
+		Event:             event,
+		SurvivorMrn:       pair.Survivor.Value,
+		PriorMrn:          pair.Prior.Value,
+		ExamIds:           []int64{},
+		VisitIds:          []int64{},
+		MrnIds:            []int64{},
+		RepointedExamIds:  []int64{},
+		RepointedVisitIds: []int64{},
+	}
+	if prior != nil {
+		params.PriorMrnID = pgtype.Int8{Int64: prior.ID, Valid: true}
+		params.PriorPatientID = prior.PatientID
+	}
+	if survivor != nil {
+		params.SurvivorMrnID = pgtype.Int8{Int64: survivor.ID, Valid: true}
+		params.SurvivorPatientID = survivor.PatientID
+	}
+
+	switch {
+	case event == LinkEvent:
+		// neither patient survives a link, so there's nothing to move
+	case prior == nil:
+		log.Printf("%s: prior MRN %s isn't on file; nothing to merge into %s\n", event, pair.Prior.Value, pair.Survivor.Value)
+	case survivor == nil:
+		// the patient's MRN was changed rather than merged into another
+		if _, err := qtx.RenameMrn(ctx, database.RenameMrnParams{
+			NewMrn: pair.Survivor.Value,
+			ID:     prior.ID,
+			OldMrn: pair.Prior.Value,
+		}); err != nil {
+			return dbErr{"MRN", err}
+		}
+		params.Renamed = true
+		params.SurvivorMrnID = params.PriorMrnID
+		params.SurvivorPatientID = prior.PatientID
+	case survivor.ID != prior.ID:
+		from := pgtype.Int8{Int64: prior.ID, Valid: true}
+		to := pgtype.Int8{Int64: survivor.ID, Valid: true}
+		params.VisitIds, err = qtx.MoveVisitsToMrn(ctx, database.MoveVisitsToMrnParams{ToMrnID: to, FromMrnID: from})
+		if err != nil {
+			return dbErr{"merged visits", err}
+		}
+		// a visit the survivor already has stays behind, so its exams
+		// move to the survivor's visit with the same number instead
+		repointed, err := qtx.MoveExamsToMrnVisits(ctx, database.MoveExamsToMrnVisitsParams{ToMrnID: to, FromMrnID: from})
+		if err != nil {
+			return dbErr{"merged exams", err}
+		}
+		for _, r := range repointed {
+			params.RepointedExamIds = append(params.RepointedExamIds, r.ID)
+			params.RepointedVisitIds = append(params.RepointedVisitIds, r.PriorVisitID)
+		}
+		params.ExamIds, err = qtx.MoveExamsToMrn(ctx, database.MoveExamsToMrnParams{ToMrnID: to, FromMrnID: from})
+		if err != nil {
+			return dbErr{"merged exams", err}
+		}
+		if prior.PatientID.Valid && survivor.PatientID.Valid && prior.PatientID != survivor.PatientID {
+			params.MrnIds, err = qtx.MoveMrnsToPatient(ctx, database.MoveMrnsToPatientParams{
+				ToPatientID:   survivor.PatientID,
+				FromPatientID: prior.PatientID,
+				KeepID:        prior.ID,
+			})
+			if err != nil {
+				return dbErr{"merged MRNs", err}
+			}
+		}
+		params.ExamIds = orEmpty(params.ExamIds)
+		params.VisitIds = orEmpty(params.VisitIds)
+		params.MrnIds = orEmpty(params.MrnIds)
+	}
+	if _, err := qtx.CreatePatientMerge(ctx, params); err != nil {
+		return dbErr{"patient merge", err}
+	}
+	return nil
+}
+
+// findMRN returns the latest MRN row with value at the site, or nil.
+func findMRN(ctx context.Context, qtx *database.Queries, siteID int32, value string) (*database.Mrn, error) {
+	mrn, err := qtx.GetMrnBySiteValue(ctx, database.GetMrnBySiteValueParams{SiteID: siteID, Mrn: value})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dbErr{"MRN", err}
+	}
+	return &mrn, nil
+}
+
+// UnmergePatients undoes merge id, moving its exams (to the visits they
+// were on), visits and MRNs back (or renaming the MRN back). It fails with ErrUnmergeUnsafe, changing
+// nothing, if any of them no longer points where the merge left it.
+func (h *HL7Repo) UnmergePatients(ctx context.Context, id int64) (PatientMerge, error) {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return PatientMerge{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Printf("error with rollback: %v\n", err)
+		}
+	}()
+
+	qtx := h.Queries.WithTx(tx)
+	m, err := qtx.GetPatientMerge(ctx, id)
+	if err != nil {
+		return PatientMerge{}, err
+	}
+	n, err := qtx.SetPatientMergeUnmerged(ctx, id)
+	if err := checkUnmerge(n, err, 1, "merge was already undone"); err != nil {
+		return PatientMerge{}, err
+	}
+	if m.Renamed {
+		n, err := qtx.RenameMrn(ctx, database.RenameMrnParams{
+			NewMrn: m.PriorMrn,
+			ID:     m.SurvivorMrnID.Int64,
+			OldMrn: m.SurvivorMrn,
+		})
+		if err := checkUnmerge(n, err, 1, "MRN has changed since"); err != nil {
+			return PatientMerge{}, err
+		}
+	}
+	if len(m.ExamIds) > 0 {
+		n, err := qtx.MoveExamsToMrnByIds(ctx, database.MoveExamsToMrnByIdsParams{
+			ToMrnID:   m.PriorMrnID,
+			Ids:       m.ExamIds,
+			FromMrnID: m.SurvivorMrnID,
+		})
+		if err := checkUnmerge(n, err, len(m.ExamIds), "exams have moved since"); err != nil {
+			return PatientMerge{}, err
+		}
+	}
+	if len(m.RepointedExamIds) > 0 {
+		n, err := qtx.MoveExamsToVisitsByIds(ctx, database.MoveExamsToVisitsByIdsParams{
+			ToMrnID:   m.PriorMrnID,
+			Ids:       m.RepointedExamIds,
+			VisitIds:  m.RepointedVisitIds,
+			FromMrnID: m.SurvivorMrnID,
+		})
+		if err := checkUnmerge(n, err, len(m.RepointedExamIds), "exams have moved since"); err != nil {
+			return PatientMerge{}, err
+		}
+	}
+	if len(m.VisitIds) > 0 {
+		n, err := qtx.MoveVisitsToMrnByIds(ctx, database.MoveVisitsToMrnByIdsParams{
+			ToMrnID:   m.PriorMrnID,
+			Ids:       m.VisitIds,
+			FromMrnID: m.SurvivorMrnID,
+		})
+		if err := checkUnmerge(n, err, len(m.VisitIds), "visits have moved since"); err != nil {
+			return PatientMerge{}, err
+		}
+	}
+	if len(m.MrnIds) > 0 {
+		n, err := qtx.MoveMrnsToPatientByIds(ctx, database.MoveMrnsToPatientByIdsParams{
+			ToPatientID:   m.PriorPatientID,
+			Ids:           m.MrnIds,
+			FromPatientID: m.SurvivorPatientID,
+		})
+		if err := checkUnmerge(n, err, len(m.MrnIds), "MRNs have moved since"); err != nil {
+			return PatientMerge{}, err
+		}
+	}
+	m, err = qtx.GetPatientMerge(ctx, id)
+	if err != nil {
+		return PatientMerge{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return PatientMerge{}, err
+	}
+	return DBtoPatientMerge(m), nil
+}
+
+// checkUnmerge turns a step that didn't touch every row it should have, or
+// that would now violate a constraint, into ErrUnmergeUnsafe.
+func checkUnmerge(n int64, err error, want int, reason string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrUnmergeUnsafe, pgErr.Message)
+	}
+	if err != nil {
+		return dbErr{"unmerge", err}
+	}
+	if n != int64(want) {
+		return fmt.Errorf("%w: %s", ErrUnmergeUnsafe, reason)
+	}
+	return nil
+}
+
+// ListPatientMerges pages through merges, starting after cursorID.
+func (h *HL7Repo) ListPatientMerges(ctx context.Context, cursorID int64, limit int32) ([]PatientMerge, error) {
+	rows, err := h.Queries.ListPatientMerges(ctx, database.ListPatientMergesParams{
+		ID:    cursorID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	merges := make([]PatientMerge, len(rows))
+	for i, r := range rows {
+		merges[i] = DBtoPatientMerge(r)
+	}
+	return merges, nil
+}
+
+func orEmpty(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge_Validate(t *testing.T) {
+	site := Site{Code: "MHS"}
+	m := &Merge{Site: site}
+	require.Error(t, m.validate())
+
+	m.Pairs = []MergePair{{Survivor: MRN{Value: "100"}}}
+	require.Error(t, m.validate())
+
+	m.Pairs[0].Prior = MRN{Value: "200"}
+	require.NoError(t, m.validate())
+	require.Equal(t, []string{"patient:MHS|100", "patient:MHS|200"}, m.lockKeys(LockAccession))
+	require.Empty(t, m.lockKeys(LockOff))
+}
+
+func TestCheckUnmerge(t *testing.T) {
+	require.NoError(t, checkUnmerge(2, nil, 2, "exams have moved since"))
+	require.ErrorIs(t, checkUnmerge(1, nil, 2, "exams have moved since"), ErrUnmergeUnsafe)
+	require.ErrorIs(t, checkUnmerge(0, &pgconn.PgError{Code: "23505"}, 1, "visits have moved since"), ErrUnmergeUnsafe)
+
+	err := checkUnmerge(0, errors.New("connection reset"), 1, "")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrUnmergeUnsafe)
+}
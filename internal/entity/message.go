@@ -16,4 +16,6 @@ type Message struct {
 	ControlID      string
 	ProcessingID   string
 	Version        string
+	// Raw is the message as received, archived if the repo is configured to.
+	Raw []byte
 }
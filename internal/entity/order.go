@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"context"
+
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// OrderControl is what an ORM or OMI asks of its order (ORC-1, HL7 table
+// 0119).
+type OrderControl string
+
+const (
+	OrderNew           OrderControl = "NW"
+	OrderCancel        OrderControl = "CA"
+	OrderCancelled     OrderControl = "OC"
+	OrderHold          OrderControl = "HD"
+	OrderReplace       OrderControl = "RP"
+	OrderReplaced      OrderControl = "RU"
+	OrderStatusChanged OrderControl = "SC"
+)
+
+// Status is the exam status the control code implies, if any: CA and OC
+// cancel the exam and HD puts it on hold whatever ORC-5 says.
+func (c OrderControl) Status() ExamStatus {
+	switch c {
+	case OrderCancel, OrderCancelled:
+		return ExamCancelled
+	case OrderHold:
+		return ExamOnHold
+	}
+	return ""
+}
+
+// IsReplacement reports whether the order replaces another (RP or RU).
+func (c OrderControl) IsReplacement() bool {
+	return c == OrderReplace || c == OrderReplaced
+}
+
+// replaced is the accession exam replaces, if it's a replacement order for
+// a different accession.
+func (e Exam) replaced() string {
+	if e.Control.IsReplacement() && e.Replaces != e.Accession {
+		return e.Replaces
+	}
+	return ""
+}
+
+// saveOrderControl records what's particular to the exam's order control
+// once it's saved as examID. A replacement notes the accession it replaced
+// and moves that exam, if there is one, to RP, subject to the same
+// staleness and transition checks as any other status change.
+func (h *HL7Repo) saveOrderControl(ctx context.Context, qtx *database.Queries, exam Exam, examID int64, msg Message, msgID int64) error {
+	replaced := exam.replaced()
+	if replaced == "" && exam.StudyUID == "" {
+		return nil
+	}
+	if err := qtx.UpdateExamOrderDetails(ctx, database.UpdateExamOrderDetailsParams{
+		ReplacedAccession: replaced,
+		StudyInstanceUid:  exam.StudyUID,
+		ID:                examID,
+	}); err != nil {
+		return dbErr{"exam order details", err}
+	}
+	if replaced == "" {
+		return nil
+	}
+	current, err := currentExamEvent(ctx, qtx, msg.ReceivingApp, replaced)
+	if err != nil || current == nil {
+		return err
+	}
+	old := Exam{
+		Accession:     replaced,
+		CurrentStatus: ExamReplaced,
+		EventTime:     exam.EventTime,
+	}
+	return h.updateExamStatus(ctx, qtx, old, current, msg, msgID)
+}
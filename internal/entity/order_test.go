@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderControl_Status(t *testing.T) {
+	require.Equal(t, ExamCancelled, OrderCancel.Status())
+	require.Equal(t, ExamCancelled, OrderCancelled.Status())
+	require.Equal(t, ExamOnHold, OrderHold.Status())
+	require.Empty(t, OrderNew.Status())
+	require.Empty(t, OrderStatusChanged.Status())
+	require.True(t, OrderReplace.IsReplacement())
+	require.True(t, OrderReplaced.IsReplacement())
+	require.False(t, OrderNew.IsReplacement())
+}
+
+func TestOrder_ReplacementLockKeys(t *testing.T) {
+	orm := &Order{
+		Message: Message{ReceivingApp: "STRIC"},
+		Visit:   Visit{Site: Site{Code: "MHS"}, MRN: MRN{Value: "123456"}},
+		Exams:   []Exam{{Accession: "A2", Control: OrderReplace, Replaces: "A1"}},
+	}
+	require.Equal(t, []string{"exam:STRIC|A2", "exam:STRIC|A1"}, orm.lockKeys(LockAccession))
+	require.Equal(t, []string{"patient:MHS|123456"}, orm.lockKeys(LockPatient))
+
+	// an order can't replace itself, and only RP/RU replace anything
+	orm.Exams[0].Replaces = "A2"
+	require.Equal(t, []string{"exam:STRIC|A2"}, orm.lockKeys(LockAccession))
+	orm.Exams[0].Replaces, orm.Exams[0].Control = "A1", OrderNew
+	require.Equal(t, []string{"exam:STRIC|A2"}, orm.lockKeys(LockAccession))
+}
+
+func TestOrder_LockKeysCoverEveryExam(t *testing.T) {
+	orm := &Order{
+		Message: Message{ReceivingApp: "STRIC"},
+		Exams:   []Exam{{Accession: "A1"}, {Accession: "A2"}, {Accession: "A3"}},
+	}
+	require.Equal(t, []string{"exam:STRIC|A1", "exam:STRIC|A2", "exam:STRIC|A3"}, orm.lockKeys(LockAccession))
+	require.NoError(t, orm.validate())
+
+	orm.Exams = nil
+	require.Error(t, orm.validate())
+	orm.Exams = []Exam{{Accession: "A1"}, {}}
+	require.Error(t, orm.validate())
+}
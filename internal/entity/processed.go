@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// ProcessedMessage is the outcome of a delivery that won't change if the same
+// message is delivered again: the status code and response body that were
+// sent back to Pub/Sub.
+type ProcessedMessage struct {
+	PubSubID      string
+	StorePath     string
+	ControlID     string
+	StatusCode    int
+	ErrorCategory string
+	Response      []byte
+	ProcessedAt   time.Time
+}
+
+// LookupProcessed returns the most recent outcome recorded under any of keys
+// within the last window.
+func (h *HL7Repo) LookupProcessed(ctx context.Context, keys []string, window time.Duration) (ProcessedMessage, bool, error) {
+	row, err := h.Queries.GetProcessedMessage(ctx, database.GetProcessedMessageParams{
+		DedupeKeys:    keys,
+		WindowSeconds: int64(window.Seconds()),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ProcessedMessage{}, false, nil
+	}
+	if err != nil {
+		return ProcessedMessage{}, false, err
+	}
+	return ProcessedMessage{
+		PubSubID:      row.PubsubMessageID,
+		StorePath:     row.StorePath,
+		ControlID:     row.ControlID,
+		StatusCode:    int(row.StatusCode),
+		ErrorCategory: row.ErrorCategory,
+		Response:      row.Response,
+		ProcessedAt:   row.ProcessedAt.Time,
+	}, true, nil
+}
+
+// RecordProcessed stores pm under every key so that a redelivery can be
+// matched by whichever one it shares.
+func (h *HL7Repo) RecordProcessed(ctx context.Context, keys []string, pm ProcessedMessage) error {
+	return h.Queries.CreateProcessedMessages(ctx, database.CreateProcessedMessagesParams{
+		DedupeKeys:      keys,
+		PubsubMessageID: pm.PubSubID,
+		StorePath:       pm.StorePath,
+		ControlID:       pm.ControlID,
+		StatusCode:      int32(pm.StatusCode),
+		ErrorCategory:   pm.ErrorCategory,
+		Response:        pm.Response,
+	})
+}
+
+// PruneProcessed deletes outcomes older than window.
+func (h *HL7Repo) PruneProcessed(ctx context.Context, window time.Duration) (int64, error) {
+	return h.Queries.DeleteProcessedMessagesBefore(ctx, int64(window.Seconds()))
+}
@@ -0,0 +1,180 @@
+package entity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// ArchiveMode controls whether (and how) the raw HL7 is stored alongside each
+// messages row.
+type ArchiveMode string
+
+const (
+	ArchiveOff   ArchiveMode = "off"
+	ArchivePlain ArchiveMode = "plain"
+	ArchiveGzip  ArchiveMode = "gzip"
+)
+
+func NewArchiveMode(s string) (ArchiveMode, error) {
+	switch m := ArchiveMode(s); m {
+	case ArchiveOff, ArchivePlain, ArchiveGzip:
+		return m, nil
+	case "":
+		return ArchiveOff, nil
+	default:
+		return "", fmt.Errorf("unknown archive mode %q (want off, plain or gzip)", s)
+	}
+}
+
+type RepoOption func(*HL7Repo)
+
+// WithRawArchive stores the raw bytes of every saved message in raw_messages.
+func WithRawArchive(mode ArchiveMode) RepoOption {
+	return func(h *HL7Repo) { h.archive = mode }
+}
+
+// RawMessage is an archived message as it was received.
+type RawMessage struct {
+	MessageID   int64
+	ReceivedAt  time.Time
+	SendingApp  string
+	MessageType string
+	ControlID   string
+	ContentHash string
+	Data        []byte
+}
+
+// RawMessageFilter narrows ListRawMessages. Zero values match everything.
+type RawMessageFilter struct {
+	Since       time.Time
+	Until       time.Time
+	SendingApp  string
+	MessageType string
+}
+
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func createRawMessageParam(raw []byte, mode ArchiveMode, msgID int64) (database.CreateRawMessageParams, error) {
+	param := database.CreateRawMessageParams{
+		MessageID:   msgID,
+		ContentHash: ContentHash(raw),
+		Encoding:    string(mode),
+		Size:        int32(len(raw)),
+		Data:        raw,
+	}
+	if mode == ArchiveGzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return param, err
+		}
+		if err := zw.Close(); err != nil {
+			return param, err
+		}
+		param.Data = buf.Bytes()
+	}
+	return param, nil
+}
+
+// saveMessage writes the messages row and, if archiving is enabled, the raw
+// bytes it was parsed from.
+func (h *HL7Repo) saveMessage(ctx context.Context, qtx *database.Queries, msg Message) (int64, error) {
+	msgID, err := qtx.CreateMessage(ctx, createMessageParam(msg))
+	if err != nil {
+		return 0, dbErr{"message", err}
+	}
+	if h.archive == "" || h.archive == ArchiveOff || len(msg.Raw) == 0 {
+		return msgID, nil
+	}
+	param, err := createRawMessageParam(msg.Raw, h.archive, msgID)
+	if err != nil {
+		return 0, fmt.Errorf("error compressing raw message: %w", err)
+	}
+	if err := qtx.CreateRawMessage(ctx, param); err != nil {
+		return 0, dbErr{"raw message", err}
+	}
+	return msgID, nil
+}
+
+// GetRawMessage returns the archived bytes for a messages row, checking them
+// against the stored hash.
+func (h *HL7Repo) GetRawMessage(ctx context.Context, messageID int64) (RawMessage, error) {
+	row, err := h.Queries.GetRawMessageByMessageID(ctx, messageID)
+	if err != nil {
+		return RawMessage{}, err
+	}
+	data, err := decodeRaw(row.Encoding, row.Data)
+	if err != nil {
+		return RawMessage{}, fmt.Errorf("error decoding raw message %d: %w", messageID, err)
+	}
+	if hash := ContentHash(data); hash != row.ContentHash {
+		return RawMessage{}, fmt.Errorf("raw message %d is corrupt: hash %s, want %s", messageID, hash, row.ContentHash)
+	}
+	return RawMessage{MessageID: messageID, ContentHash: row.ContentHash, Data: data}, nil
+}
+
+// ListRawMessages pages through archived messages in received_at order,
+// starting after the (afterReceivedAt, afterID) cursor.
+func (h *HL7Repo) ListRawMessages(ctx context.Context, f RawMessageFilter, afterReceivedAt time.Time, afterID int64, limit int32) ([]RawMessage, error) {
+	until := pgtype.Timestamp{InfinityModifier: pgtype.Infinity, Valid: true}
+	if !f.Until.IsZero() {
+		until = pgtype.Timestamp{Time: f.Until.UTC(), Valid: true}
+	}
+	rows, err := h.Queries.ListRawMessagesForReprocess(ctx, database.ListRawMessagesForReprocessParams{
+		AfterReceivedAt: pgtype.Timestamp{Time: afterReceivedAt.UTC(), Valid: true},
+		AfterID:         afterID,
+		Since:           pgtype.Timestamp{Time: f.Since.UTC(), Valid: true},
+		Until:           until,
+		SendingApp:      f.SendingApp,
+		MessageType:     f.MessageType,
+		PageSize:        limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]RawMessage, len(rows))
+	for i, r := range rows {
+		data, err := decodeRaw(r.Encoding, r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding raw message %d: %w", r.MessageID, err)
+		}
+		msgs[i] = RawMessage{
+			MessageID:   r.MessageID,
+			ReceivedAt:  r.ReceivedAt.Time,
+			SendingApp:  r.SendingApplication,
+			MessageType: r.MessageType,
+			ControlID:   r.ControlID,
+			ContentHash: r.ContentHash,
+			Data:        data,
+		}
+	}
+	return msgs, nil
+}
+
+func decodeRaw(encoding string, data []byte) ([]byte, error) {
+	switch ArchiveMode(encoding) {
+	case ArchivePlain:
+		return data, nil
+	case ArchiveGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = zr.Close() }()
+		return io.ReadAll(zr)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	raw := []byte("MSH|^~\\&|SendingApp|SendingFac|ReceivingApp|ReceivingFac|202205271230||ORM^O01|MSGID123|P|2.3\rPID|1||123456")
+	for _, mode := range []ArchiveMode{ArchivePlain, ArchiveGzip} {
+		t.Run(string(mode), func(t *testing.T) {
+			param, err := createRawMessageParam(raw, mode, 7)
+			require.NoError(t, err)
+			require.Equal(t, int64(7), param.MessageID)
+			require.Equal(t, ContentHash(raw), param.ContentHash)
+			require.Equal(t, int32(len(raw)), param.Size)
+
+			got, err := decodeRaw(param.Encoding, param.Data)
+			require.NoError(t, err)
+			require.Equal(t, raw, got)
+		})
+	}
+
+	_, err := decodeRaw("zstd", raw)
+	require.Error(t, err)
+}
+
+func TestNewArchiveMode(t *testing.T) {
+	m, err := NewArchiveMode("")
+	require.NoError(t, err)
+	require.Equal(t, ArchiveOff, m)
+	m, err = NewArchiveMode("gzip")
+	require.NoError(t, err)
+	require.Equal(t, ArchiveGzip, m)
+	_, err = NewArchiveMode("zip")
+	require.Error(t, err)
+}
@@ -5,13 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/s-hammon/p"
+	"github.com/s-hammon/volta/internal/blob"
 	"github.com/s-hammon/volta/internal/database"
-	"github.com/s-hammon/volta/internal/objects"
+	"github.com/s-hammon/volta/internal/keyqueue"
 )
 
 type dbErr struct {
@@ -23,22 +25,52 @@ func (e dbErr) Error() string {
 	return p.Format("error writing %s to database: %v", e.entityName, e.errMsg)
 }
 
+func (e dbErr) Unwrap() error {
+	return e.errMsg
+}
+
 type HL7Repo struct {
 	DB      *pgxpool.Pool
 	Queries *database.Queries
+
+	archive     ArchiveMode
+	lockScope   LockScope
+	locks       *keyqueue.Queue
+	transitions Transitions
+	sections    SectionRules
+	blobs       blob.Store
+	critical    CriticalRules
+	notifiers   []CriticalNotifier
+	// criticalAdded wakes NotifyCritical when a report records a new
+	// critical result
+	criticalAdded chan struct{}
 }
 
-func NewRepo(db *pgxpool.Pool) *HL7Repo {
-	return &HL7Repo{DB: db, Queries: database.New(db)}
+func NewRepo(db *pgxpool.Pool, opts ...RepoOption) *HL7Repo {
+	h := &HL7Repo{
+		DB:          db,
+		Queries:     database.New(db),
+		lockScope:   LockAccession,
+		transitions: DefaultTransitions,
+		sections:    DefaultSectionRules,
+		critical:    DefaultCriticalRules,
+		locks:       keyqueue.New(),
+
+		criticalAdded: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
+// Order is an ORM or OMI. Each ORC/OBR group is an exam, with its own
+// procedure, ordering provider and order control.
 type Order struct {
-	Message   Message
-	Patient   Patient
-	Visit     Visit
-	Provider  Physician
-	Procedure Procedure
-	Exam      Exam
+	Message Message
+	Patient Patient
+	Visit   Visit
+	Exams   []Exam
 }
 
 type Observation struct {
@@ -51,10 +83,49 @@ type Observation struct {
 	Report    Report
 }
 
+func (o *Order) validate() error {
+	if len(o.Exams) == 0 {
+		return ValidationError{"exams", "no ORC/OBR groups in message"}
+	}
+	for i, exam := range o.Exams {
+		if exam.Accession == "" {
+			return ValidationError{"accession", p.Format("order group %d has empty ORC-2 and ORC-3", i+1)}
+		}
+	}
+	return nil
+}
+
+func (o *Observation) validate() error {
+	if len(o.Exams) == 0 && o.Report.Document.Obsolete() {
+		return nil
+	}
+	if len(o.Exams) == 0 {
+		return ValidationError{"exams", "no ORC/OBR groups in message"}
+	}
+	for i, exam := range o.Exams {
+		if exam.Accession == "" {
+			return ValidationError{"accession", p.Format("order group %d has empty ORC-2 and ORC-3", i+1)}
+		}
+	}
+	return nil
+}
+
+// SaveORM upserts every exam in the order in one transaction. An exam whose
+// update is stale or an illegal status change is skipped (and recorded)
+// without holding up the others.
 func (h *HL7Repo) SaveORM(ctx context.Context, orm *Order) error {
+	if err := orm.validate(); err != nil {
+		return err
+	}
+	keys := orm.lockKeys(h.lockScope)
+	release, err := h.acquire(ctx, keys)
+	if err != nil {
+		return err
+	}
+	defer release()
 	tx, err := h.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer func() {
 		err := tx.Rollback(ctx)
@@ -64,12 +135,15 @@ func (h *HL7Repo) SaveORM(ctx context.Context, orm *Order) error {
 	}()
 
 	qtx := h.Queries.WithTx(tx)
-	var sID, prID int32
-	var msgID, pID, vID, mID, phID int64
+	if err := lockKeys(ctx, qtx, keys); err != nil {
+		return err
+	}
+	var sID int32
+	var msgID, pID, vID, mID int64
 	// TODO: bundle below 4 into goroutines
-	msgID, err = qtx.CreateMessage(ctx, createMessageParam(orm.Message))
+	msgID, err = h.saveMessage(ctx, qtx, orm.Message)
 	if err != nil {
-		return dbErr{"message", err}
+		return err
 	}
 	pID, err = qtx.CreatePatient(ctx, createPatientParam(orm.Patient, msgID))
 	if err != nil {
@@ -79,43 +153,78 @@ func (h *HL7Repo) SaveORM(ctx context.Context, orm *Order) error {
 	if err != nil {
 		return dbErr{"site", err}
 	}
-	phID, err = qtx.CreatePhysician(ctx, createPhysicianParam(orm.Provider, msgID))
-	if err != nil {
-		return dbErr{"ordering physician", err}
-	}
-
-	// TODO: see if we can use chans to fire these off when above are finished
-	prID, err = qtx.CreateProcedure(ctx, createProcedureParam(orm.Procedure, sID, msgID))
-	if err != nil {
-		return dbErr{"procedure", err}
-	}
 	mID, err = qtx.CreateMrn(ctx, createMrnParam(orm.Visit.MRN, sID, pID, msgID))
 	if err != nil {
 		return dbErr{"MRN", err}
 	}
 	if orm.Visit.VisitNo == "" {
 		// set this equal to the accession--it's the best we can do :/
-		orm.Visit.VisitNo = orm.Exam.Accession
+		orm.Visit.VisitNo = orm.Exams[0].Accession
 	}
 	vID, err = qtx.CreateVisit(ctx, createVisitParam(orm.Visit, sID, mID, msgID))
 	if err != nil {
 		return dbErr{"visit", err}
 	}
-	if _, err = qtx.CreateExam(ctx, createExamParam(
-		orm.Exam, orm.Message.ReceivingApp,
+	for _, exam := range orm.Exams {
+		if err := h.saveOrderedExam(ctx, qtx, exam, orm.Message, sID, vID, mID, msgID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (h *HL7Repo) saveOrderedExam(ctx context.Context, qtx *database.Queries, exam Exam, msg Message, sID int32, vID, mID, msgID int64) error {
+	phID, err := qtx.CreatePhysician(ctx, createPhysicianParam(exam.Provider, msgID))
+	if err != nil {
+		return dbErr{"ordering physician", err}
+	}
+	prID, err := qtx.CreateProcedure(ctx, createProcedureParam(exam.Procedure, sID, msgID))
+	if err != nil {
+		return dbErr{"procedure", err}
+	}
+	exam.resolveStatus()
+	current, err := currentExamEvent(ctx, qtx, msg.ReceivingApp, exam.Accession)
+	if err != nil {
+		return err
+	}
+	ok, err := h.admitUpdate(ctx, qtx, exam, current, msgID)
+	if err != nil || !ok {
+		return err
+	}
+	if current == nil && exam.CurrentStatus == "" {
+		exam.CurrentStatus = ExamScheduled
+	}
+	eID, err := qtx.CreateExam(ctx, createExamParam(
+		exam, msg.ReceivingApp,
 		sID, prID,
 		vID, mID, phID, msgID,
-	)); err != nil {
+	))
+	if err != nil {
 		return dbErr{"exam", err}
 	}
-
-	return tx.Commit(ctx)
+	if err := recordStatusChange(ctx, qtx, eID, current, exam, msg, msgID); err != nil {
+		return err
+	}
+	if err := linkAppointments(ctx, qtx, msg.ReceivingApp, exam.Accession, eID); err != nil {
+		return err
+	}
+	return h.saveOrderControl(ctx, qtx, exam, eID, msg, msgID)
 }
 
 func (h *HL7Repo) SaveORU(ctx context.Context, oru *Observation) error {
+	if err := oru.validate(); err != nil {
+		return err
+	}
+	keys := oru.lockKeys(h.lockScope)
+	release, err := h.acquire(ctx, keys)
+	if err != nil {
+		return err
+	}
+	defer release()
 	tx, err := h.DB.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer func() {
 		err := tx.Rollback(ctx)
@@ -125,12 +234,21 @@ func (h *HL7Repo) SaveORU(ctx context.Context, oru *Observation) error {
 	}()
 
 	qtx := h.Queries.WithTx(tx)
+	if err := lockKeys(ctx, qtx, keys); err != nil {
+		return err
+	}
 	var sID, prID int32
 	var msgID, pID, vID, mID, phID, radID, rID int64
 	// TODO: bundle below 4 into goroutines
-	msgID, err = qtx.CreateMessage(ctx, createMessageParam(oru.Message))
+	msgID, err = h.saveMessage(ctx, qtx, oru.Message)
 	if err != nil {
-		return dbErr{"message", err}
+		return err
+	}
+	if doc := oru.Report.Document; doc.Obsolete() {
+		if err := obsoleteDocument(ctx, qtx, oru.Message.ReceivingApp, doc.ID, 0); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
 	}
 	pID, err = qtx.CreatePatient(ctx, createPatientParam(oru.Patient, msgID))
 	if err != nil {
@@ -162,44 +280,81 @@ func (h *HL7Repo) SaveORU(ctx context.Context, oru *Observation) error {
 	if err != nil {
 		return dbErr{"radiologist", err}
 	}
-	rID, err = qtx.CreateReport(ctx, createReportParam(oru.Report, radID, msgID))
+	h.sections.Apply(&oru.Report)
+	rID, err = qtx.CreateReport(ctx, createReportParam(oru.Report, oru.Message.ReceivingApp, reportAccessions(oru.Exams), radID, msgID))
 	if err != nil {
 		return dbErr{"report", err}
 	}
+	if err := h.saveAttachments(ctx, qtx, rID, oru.Report, msgID); err != nil {
+		return err
+	}
+	if err := obsoleteDocument(ctx, qtx, oru.Message.ReceivingApp, oru.Report.Document.ParentID, rID); err != nil {
+		return err
+	}
+	findings := h.critical.Detect(oru.Report)
+	var critical []int64
 	for _, exam := range oru.Exams {
-		prID, err = qtx.CreateProcedure(ctx, createProcedureParam(exam.Procedure, sID, msgID))
+		// an MDM without an OBR doesn't say what was performed
+		prID = 0
+		if exam.Procedure.Code != "" {
+			prID, err = qtx.CreateProcedure(ctx, createProcedureParam(exam.Procedure, sID, msgID))
+			if err != nil {
+				return dbErr{"procedure", err}
+			}
+		}
+		current, err := currentExamEvent(ctx, qtx, oru.Message.ReceivingApp, exam.Accession)
 		if err != nil {
-			return dbErr{"procedure", err}
+			return err
 		}
 		var eID int64
-		eID, err = qtx.GetExamIDBySendingAppAccession(ctx, getExamIDParam(exam, oru.Message.ReceivingApp))
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				eID, err = qtx.CreateExam(ctx, createExamParam(
-					exam, oru.Message.ReceivingApp,
-					sID, prID,
-					vID, mID, phID, msgID,
-				))
-				if err != nil {
-					return dbErr{"exam", err}
-				}
-			} else {
-				return fmt.Errorf("error retrieving exam ID for accession %s: %v", exam.Accession, err)
+		if current == nil {
+			if exam.CurrentStatus == "" {
+				exam.CurrentStatus = ExamScheduled
 			}
-		}
-		switch oru.Report.Status {
-		case objects.Final:
-			if _, err := qtx.UpdateExamFinalReport(ctx, updateExamFinalParam(eID, rID)); err != nil {
-				return fmt.Errorf("error updating exam with final report: %v", err)
+			// last_event_dt belongs to the order flow, so that an ORM
+			// that arrives after its result isn't taken for stale
+			ordered := exam
+			ordered.EventTime = time.Time{}
+			eID, err = qtx.CreateExam(ctx, createExamParam(
+				ordered, oru.Message.ReceivingApp,
+				sID, prID,
+				vID, mID, phID, msgID,
+			))
+			if err != nil {
+				return dbErr{"exam", err}
+			}
+			if err := recordStatusChange(ctx, qtx, eID, nil, exam, oru.Message, msgID); err != nil {
+				return err
 			}
-		case objects.Addendum:
-			if _, err := qtx.UpdateExamAddendumReport(ctx, updateExamAddendumParam(eID, rID)); err != nil {
-				return fmt.Errorf("error updating exam with addendum report: %v", err)
+			if err := linkAppointments(ctx, qtx, oru.Message.ReceivingApp, exam.Accession, eID); err != nil {
+				return err
 			}
+		} else {
+			eID = current.ID
+		}
+		if err := h.updateResultStatus(ctx, qtx, eID, exam, current, msgID); err != nil {
+			return err
+		}
+		if err := attachReport(ctx, qtx, eID, rID, oru.Report, msgID); err != nil {
+			return err
+		}
+		ids, err := saveCriticalResults(ctx, qtx, findings, eID, rID, radID, msgID)
+		if err != nil {
+			return err
 		}
+		critical = append(critical, ids...)
 	}
 
-	return tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if len(critical) > 0 {
+		select {
+		case h.criticalAdded <- struct{}{}:
+		default:
+		}
+	}
+	return nil
 }
 
 func createMessageParam(obj Message) database.CreateMessageParams {
@@ -234,6 +389,12 @@ func createPatientParam(obj Patient, msgID int64) database.CreatePatientParams {
 	if obj.SSN != "" {
 		params.Ssn.String = obj.SSN.String()
 	}
+	if obj.HomePhone != 0 {
+		params.HomePhone = pgtype.Text{String: obj.HomePhone.String(), Valid: true}
+	}
+	if obj.WorkPhone != 0 {
+		params.WorkPhone = pgtype.Text{String: obj.WorkPhone.String(), Valid: true}
+	}
 	return params
 }
 
@@ -298,7 +459,7 @@ func createExamParam(obj Exam, rapp string, siteID, procID int32, visitID, mrnID
 	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
 	params.SendingApp = rapp
 	params.SiteID = pgtype.Int4{Int32: siteID, Valid: true}
-	params.ProcedureID = pgtype.Int4{Int32: procID, Valid: true}
+	params.ProcedureID = pgtype.Int4{Int32: procID, Valid: procID != 0}
 	params.VisitID = pgtype.Int8{Int64: visitID, Valid: true}
 	params.MrnID = pgtype.Int8{Int64: mrnID, Valid: true}
 	params.OrderingPhysicianID = pgtype.Int8{Int64: physID, Valid: true}
@@ -309,7 +470,7 @@ func createExamParam(obj Exam, rapp string, siteID, procID int32, visitID, mrnID
 	return params
 }
 
-func createReportParam(obj Report, radID, msgID int64) database.CreateReportParams {
+func createReportParam(obj Report, sendingApp string, accessions []string, radID, msgID int64) database.CreateReportParams {
 	params := database.CreateReportParams{}
 	params.MessageID = pgtype.Int8{Int64: msgID, Valid: true}
 	params.RadiologistID = pgtype.Int8{Int64: radID, Valid: true}
@@ -319,26 +480,13 @@ func createReportParam(obj Report, radID, msgID int64) database.CreateReportPara
 	params.DictationStart = pgtype.Timestamp{Time: obj.DictationStart, Valid: true}
 	params.DictationEnd = pgtype.Timestamp{Time: obj.DictationEnd, Valid: true}
 	params.SubmittedDt = pgtype.Timestamp{Time: obj.SubmittedDT, Valid: true}
-	return params
-}
-
-func getExamIDParam(obj Exam, sendingApp string) database.GetExamIDBySendingAppAccessionParams {
-	params := database.GetExamIDBySendingAppAccessionParams{}
 	params.SendingApp = sendingApp
-	params.Accession = obj.Accession
-	return params
-}
-
-func updateExamFinalParam(examID, reportID int64) database.UpdateExamFinalReportParams {
-	params := database.UpdateExamFinalReportParams{}
-	params.ID = examID
-	params.FinalReportID = pgtype.Int8{Int64: reportID, Valid: true}
-	return params
-}
-
-func updateExamAddendumParam(examID, reportID int64) database.UpdateExamAddendumReportParams {
-	params := database.UpdateExamAddendumReportParams{}
-	params.ID = examID
-	params.AddendumReportID = pgtype.Int8{Int64: reportID, Valid: true}
+	params.Accessions = accessions
+	params.BodyHash = obj.BodyHash()
+	params.Findings = obj.Findings
+	params.Technique = obj.Technique
+	params.Comparison = obj.Comparison
+	params.History = obj.History
+	params.DocumentID = obj.Document.ID
 	return params
 }
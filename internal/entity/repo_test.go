@@ -23,3 +23,60 @@ func TestCreateExamParam(t *testing.T) {
 	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), params.EndExamDt.Time)
 	require.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), params.ExamCancelledDt.Time)
 }
+
+func TestLockKeys(t *testing.T) {
+	visit := Visit{Site: Site{Code: "MHS"}, MRN: MRN{Value: "123456"}}
+	orm := &Order{
+		Message: Message{ReceivingApp: "STRIC"},
+		Visit:   visit,
+		Exams:   []Exam{{Accession: "A1"}},
+	}
+	oru := &Observation{
+		Message: Message{ReceivingApp: "STRIC"},
+		Visit:   visit,
+		Exams:   []Exam{{Accession: "A1"}, {Accession: "A2"}},
+	}
+
+	require.Equal(t, []string{"exam:STRIC|A1"}, orm.lockKeys(LockAccession))
+	require.Equal(t, []string{"exam:STRIC|A1", "exam:STRIC|A2"}, oru.lockKeys(LockAccession))
+	require.Equal(t, []string{"patient:MHS|123456"}, orm.lockKeys(LockPatient))
+	require.Equal(t, orm.lockKeys(LockPatient), oru.lockKeys(LockPatient))
+	require.Empty(t, oru.lockKeys(LockOff))
+
+	// ADTs have no accession, so they always lock on the patient
+	adt := &Admission{Visit: visit}
+	require.Equal(t, []string{"patient:MHS|123456"}, adt.lockKeys(LockAccession))
+	require.Empty(t, adt.lockKeys(LockOff))
+	require.Error(t, (&Admission{}).validate())
+
+	scope, err := NewLockScope("")
+	require.NoError(t, err)
+	require.Equal(t, LockAccession, scope)
+	_, err = NewLockScope("visit")
+	require.Error(t, err)
+}
+
+func TestExam_IsStale(t *testing.T) {
+	last := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		event time.Time
+		last  time.Time
+		want  bool
+	}{
+		{"older", last.Add(-time.Minute), last, true},
+		{"same time", last, last, false},
+		{"newer", last.Add(time.Minute), last, false},
+		{"no stored event", last.Add(-time.Minute), time.Time{}, false},
+		{"no event time", time.Time{}, last, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Exam{EventTime: tt.event}.IsStale(tt.last))
+		})
+	}
+
+	params := createExamParam(Exam{Accession: "1", EventTime: last}, "STRIC", 1, 1, 1, 1, 1, 1)
+	require.True(t, params.LastEventDt.Valid)
+	require.Equal(t, last, params.LastEventDt.Time)
+}
@@ -1,8 +1,15 @@
 package entity
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/s-hammon/volta/internal/database"
 	"github.com/s-hammon/volta/internal/objects"
 )
@@ -12,10 +19,53 @@ type Report struct {
 	Radiologist    Physician
 	Body           string
 	Impression     string
+	Findings       string
+	Technique      string
+	Comparison     string
+	History        string
 	Status         objects.ReportStatus
 	DictationStart time.Time
 	DictationEnd   time.Time
 	SubmittedDT    time.Time
+	// the report this one revises, if any
+	PreviousID int
+	// the version of the report among those for the same accessions and
+	// status; 1 for the first
+	Sequence int
+	// the OBX segments the report was read from, for SectionRules
+	Lines []ReportLine
+	// encapsulated documents and reference pointers, which aren't part of
+	// the body
+	Attachments []Attachment
+	// set for reports sent as MDM documents
+	Document Document
+	Obsolete bool
+}
+
+// BodyHash is the hex SHA-256 of the report body, followed by the contents
+// or reference of each attachment. With the sending app, accessions and
+// status it identifies the report, so a resend finds the report already
+// saved and any edit to the text or attachments is a new version.
+func (r Report) BodyHash() string {
+	h := sha256.New()
+	h.Write([]byte(r.Body))
+	for _, a := range r.Attachments {
+		h.Write([]byte{0})
+		h.Write([]byte(a.Reference))
+		h.Write(a.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reportAccessions is the sorted, de-duplicated set of accessions a report
+// covers.
+func reportAccessions(exams []Exam) []string {
+	accessions := make([]string, 0, len(exams))
+	for _, exam := range exams {
+		accessions = append(accessions, exam.Accession)
+	}
+	slices.Sort(accessions)
+	return slices.Compact(accessions)
 }
 
 func DBtoReport(report database.Report) Report {
@@ -30,9 +80,201 @@ func DBtoReport(report database.Report) Report {
 		},
 		Body:           report.Body,
 		Impression:     report.Impression,
+		Findings:       report.Findings,
+		Technique:      report.Technique,
+		Comparison:     report.Comparison,
+		History:        report.History,
 		Status:         objects.NewReportStatus(report.ReportStatus),
 		DictationStart: report.DictationStart.Time,
 		DictationEnd:   report.DictationEnd.Time,
 		SubmittedDT:    report.SubmittedDt.Time,
+		PreviousID:     int(report.PreviousReportID.Int64),
+		Sequence:       int(report.Sequence),
+		Document:       Document{ID: report.DocumentID},
+		Obsolete:       report.Obsolete,
+	}
+}
+
+// ReportRole is what a report is to an exam it covers.
+type ReportRole string
+
+const (
+	RolePrelim    ReportRole = "prelim"
+	RoleFinal     ReportRole = "final"
+	RoleCorrected ReportRole = "corrected"
+	RoleAddendum  ReportRole = "addendum"
+)
+
+// ReportRoleFrom maps a report status (OBX-11) to its role; reports with
+// any other status aren't attached to exams.
+func ReportRoleFrom(status objects.ReportStatus) (ReportRole, bool) {
+	switch status {
+	case objects.Pending:
+		return RolePrelim, true
+	case objects.Final:
+		return RoleFinal, true
+	case objects.Corrected:
+		return RoleCorrected, true
+	case objects.Addendum:
+		return RoleAddendum, true
+	}
+	return "", false
+}
+
+// attachReport adds a report, saved as reportID, to the exam's reports in
+// the role its status calls for. It's first linked to the latest of the
+// exam's reports that came before it, so that each exam's reports form a
+// version chain. Attaching the same report again does nothing.
+func attachReport(ctx context.Context, qtx *database.Queries, examID, reportID int64, report Report, msgID int64) error {
+	role, ok := ReportRoleFrom(report.Status)
+	if !ok {
+		return nil
+	}
+	prevID, err := qtx.GetPreviousExamReportID(ctx, database.GetPreviousExamReportIDParams{
+		ExamID:      examID,
+		SubmittedDt: pgtype.Timestamp{Time: report.SubmittedDT, Valid: true},
+		ReportID:    reportID,
+	})
+	switch {
+	case err == nil:
+		if err := qtx.LinkPreviousReport(ctx, database.LinkPreviousReportParams{
+			PreviousReportID: pgtype.Int8{Int64: prevID, Valid: true},
+			ID:               reportID,
+		}); err != nil {
+			return dbErr{"previous report", err}
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return dbErr{"previous report", err}
+	}
+	if err := qtx.CreateExamReport(ctx, database.CreateExamReportParams{
+		ExamID:    examID,
+		ReportID:  reportID,
+		Role:      string(role),
+		MessageID: msgID,
+	}); err != nil {
+		return dbErr{"exam report", err}
+	}
+	return nil
+}
+
+// ExamReport links an exam to one of its reports. Sequence counts the
+// exam's reports in the same role, so the second addendum has Sequence 2.
+type ExamReport struct {
+	ExamID      int64      `json:"exam_id"`
+	Accession   string     `json:"accession"`
+	ReportID    int64      `json:"report_id"`
+	Role        ReportRole `json:"role"`
+	Sequence    int        `json:"sequence"`
+	Status      string     `json:"status"`
+	SubmittedDT time.Time  `json:"submitted_dt"`
+}
+
+// ExamReports returns the reports attached to an exam in submission order.
+func (h *HL7Repo) ExamReports(ctx context.Context, examID int64) ([]ExamReport, error) {
+	rows, err := h.Queries.ListExamReports(ctx, examID)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]ExamReport, len(rows))
+	for i, r := range rows {
+		links[i] = DBtoExamReport(database.ListReportExamsRow(r))
+	}
+	return links, nil
+}
+
+// ReportExams returns the exams a report covers, by accession.
+func (h *HL7Repo) ReportExams(ctx context.Context, reportID int64) ([]ExamReport, error) {
+	rows, err := h.Queries.ListReportExams(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]ExamReport, len(rows))
+	for i, r := range rows {
+		links[i] = DBtoExamReport(r)
+	}
+	return links, nil
+}
+
+func DBtoExamReport(r database.ListReportExamsRow) ExamReport {
+	return ExamReport{
+		ExamID:      r.ExamID,
+		Accession:   r.Accession,
+		ReportID:    r.ReportID,
+		Role:        ReportRole(r.Role),
+		Sequence:    int(r.Sequence),
+		Status:      r.ReportStatus,
+		SubmittedDT: r.SubmittedDt.Time,
+	}
+}
+
+// ReportVersion is one report in an exam's history. Current is false for a
+// prelim or final that a later prelim or final (or correction) superseded,
+// and for an obsolete document; other addenda are always current.
+type ReportVersion struct {
+	ID             int64      `json:"id"`
+	PreviousID     int64      `json:"previous_id,omitempty"`
+	Status         string     `json:"status"`
+	Radiologist    string     `json:"radiologist"`
+	History        string     `json:"history,omitempty"`
+	Technique      string     `json:"technique,omitempty"`
+	Comparison     string     `json:"comparison,omitempty"`
+	Findings       string     `json:"findings,omitempty"`
+	Impression     string     `json:"impression"`
+	Body           string     `json:"body"`
+	DictationStart time.Time  `json:"dictation_start"`
+	DictationEnd   time.Time  `json:"dictation_end"`
+	SubmittedDT    time.Time  `json:"submitted_dt"`
+	Role           ReportRole `json:"role"`
+	Sequence       int        `json:"sequence"`
+	Current        bool       `json:"current"`
+	Obsolete       bool       `json:"obsolete,omitempty"`
+}
+
+func DBtoReportVersion(r database.ListExamReportHistoryRow) ReportVersion {
+	v := ReportVersion{
+		ID:             r.ID,
+		PreviousID:     r.PreviousReportID.Int64,
+		Status:         r.ReportStatus,
+		History:        r.History,
+		Technique:      r.Technique,
+		Comparison:     r.Comparison,
+		Findings:       r.Findings,
+		Impression:     r.Impression,
+		Body:           r.Body,
+		DictationStart: r.DictationStart.Time,
+		DictationEnd:   r.DictationEnd.Time,
+		SubmittedDT:    r.SubmittedDt.Time,
+		Role:           ReportRole(r.Role),
+		Sequence:       int(r.Sequence),
+		Current:        r.Current,
+		Obsolete:       r.Obsolete,
+	}
+	v.Radiologist = radiologistName(r.RadiologistFirstName, r.RadiologistLastName)
+	return v
+}
+
+// radiologistName is "Last, First", or whichever of them is known.
+func radiologistName(first, last string) string {
+	switch {
+	case first == "":
+		return last
+	case last == "":
+		return first
+	default:
+		return last + ", " + first
+	}
+}
+
+// ReportHistory returns every report attached to an exam, in submission
+// order.
+func (h *HL7Repo) ReportHistory(ctx context.Context, examID int64) ([]ReportVersion, error) {
+	rows, err := h.Queries.ListExamReportHistory(ctx, examID)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]ReportVersion, len(rows))
+	for i, r := range rows {
+		versions[i] = DBtoReportVersion(r)
 	}
+	return versions, nil
 }
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+	"github.com/s-hammon/volta/internal/objects"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBtoReportVersion(t *testing.T) {
+	v := DBtoReportVersion(database.ListExamReportHistoryRow{
+		ID:                   3,
+		PreviousReportID:     pgtype.Int8{Int64: 2, Valid: true},
+		ReportStatus:         "C",
+		RadiologistFirstName: "Ray",
+		RadiologistLastName:  "Rad",
+		Role:                 "corrected",
+		Sequence:             1,
+		Current:              true,
+	})
+	require.Equal(t, int64(2), v.PreviousID)
+	require.Equal(t, "Rad, Ray", v.Radiologist)
+	require.Equal(t, RoleCorrected, v.Role)
+	require.True(t, v.Current)
+
+	v = DBtoReportVersion(database.ListExamReportHistoryRow{ID: 1, ReportStatus: "P", RadiologistLastName: "Rad"})
+	require.Zero(t, v.PreviousID)
+	require.Equal(t, "Rad", v.Radiologist)
+}
+
+func TestReportRoleFrom(t *testing.T) {
+	tests := []struct {
+		status objects.ReportStatus
+		want   ReportRole
+		ok     bool
+	}{
+		{objects.Pending, RolePrelim, true},
+		{objects.Final, RoleFinal, true},
+		{objects.Corrected, RoleCorrected, true},
+		{objects.Addendum, RoleAddendum, true},
+		{objects.ReportStatus("R"), "", false},
+	}
+	for _, tt := range tests {
+		role, ok := ReportRoleFrom(tt.status)
+		require.Equal(t, tt.want, role, tt.status)
+		require.Equal(t, tt.ok, ok, tt.status)
+	}
+}
+
+func TestReport_BodyHash(t *testing.T) {
+	r := Report{Body: "Normal.\n"}
+	require.Equal(t, "d4b23841275cee2e2db729fab75031305e57691ebadde93f085e2fcea1bf307f", r.BodyHash())
+	require.Equal(t, r.BodyHash(), Report{Body: "Normal.\n", Impression: "other"}.BodyHash())
+	require.NotEqual(t, r.BodyHash(), Report{Body: "Normal. \n"}.BodyHash())
+
+	// reports that are just a PDF differ by the PDF
+	pdf1 := Report{Attachments: []Attachment{{ValueType: EncapsulatedData, Data: []byte("%PDF-1")}}}
+	pdf2 := Report{Attachments: []Attachment{{ValueType: EncapsulatedData, Data: []byte("%PDF-2")}}}
+	require.NotEqual(t, pdf1.BodyHash(), pdf2.BodyHash())
+	require.NotEqual(t, Report{}.BodyHash(), pdf1.BodyHash())
+}
+
+func TestReportAccessions(t *testing.T) {
+	exams := []Exam{{Accession: "A2"}, {Accession: "A1"}, {Accession: "A2"}}
+	require.Equal(t, []string{"A1", "A2"}, reportAccessions(exams))
+}
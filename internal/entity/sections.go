@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Section is a part of a structured report.
+type Section string
+
+const (
+	SectionHistory    Section = "history"
+	SectionTechnique  Section = "technique"
+	SectionComparison Section = "comparison"
+	SectionFindings   Section = "findings"
+	SectionImpression Section = "impression"
+)
+
+// sections is the order rules are tried in, so that parsing doesn't depend
+// on map iteration.
+var sections = []Section{SectionHistory, SectionTechnique, SectionComparison, SectionFindings, SectionImpression}
+
+// ReportLine is one OBX of a report: its observation identifier (OBX-3),
+// text (OBX-5) and abnormal flags (OBX-8).
+type ReportLine struct {
+	ObservationID string
+	Text          string
+	AbnormalFlags []string
+}
+
+// SectionRule finds a section either by the OBX-3 identifiers of the OBX
+// segments that carry it, or by header lines in the text of the others. A
+// header's section runs until the next header.
+type SectionRule struct {
+	IDs     []string `json:"ids"`
+	Headers []string `json:"headers"`
+
+	headers []*regexp.Regexp
+}
+
+// SectionRules maps each section to how it's found. Sections without a
+// rule are left empty.
+type SectionRules map[Section]SectionRule
+
+// DefaultSectionRules reads PowerScribe's impression OBX (&IMP) and the usual
+// headers in report text, e.g. "FINDINGS:" or "CLINICAL HISTORY:".
+var DefaultSectionRules = mustSectionRules(SectionRules{
+	SectionHistory:    {Headers: []string{`(?i)^\s*(clinical\s+)?(history|indications?)\s*(:|$)`}},
+	SectionTechnique:  {Headers: []string{`(?i)^\s*technique\s*(:|$)`}},
+	SectionComparison: {Headers: []string{`(?i)^\s*comparisons?\s*(:|$)`}},
+	SectionFindings:   {Headers: []string{`(?i)^\s*findings\s*(:|$)`}},
+	SectionImpression: {IDs: []string{"&IMP"}, Headers: []string{`(?i)^\s*(impressions?|conclusions?)\s*(:|$)`}},
+})
+
+// ParseSectionRules reads section rules as JSON, e.g.
+// {"impression": {"ids": ["&IMP"], "headers": ["(?i)^impression:"]}}.
+// Headers are regular expressions matched against each line of text; the
+// rest of the line after the match starts the section.
+func ParseSectionRules(data []byte) (SectionRules, error) {
+	var r SectionRules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid section rules: %w", err)
+	}
+	if err := r.compile(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func mustSectionRules(r SectionRules) SectionRules {
+	if err := r.compile(); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r SectionRules) compile() error {
+	for name, rule := range r {
+		if !slices.Contains(sections, name) {
+			return fmt.Errorf("invalid section rules: unknown section %q", name)
+		}
+		rule.headers = make([]*regexp.Regexp, len(rule.Headers))
+		for i, h := range rule.Headers {
+			re, err := regexp.Compile(h)
+			if err != nil {
+				return fmt.Errorf("invalid section rules: %s header %q: %w", name, h, err)
+			}
+			rule.headers[i] = re
+		}
+		r[name] = rule
+	}
+	return nil
+}
+
+// WithSectionRules replaces DefaultSectionRules.
+func WithSectionRules(r SectionRules) RepoOption {
+	return func(h *HL7Repo) { h.sections = r }
+}
+
+func (r SectionRules) byID(id string) (Section, bool) {
+	for _, s := range sections {
+		for _, want := range r[s].IDs {
+			if strings.EqualFold(id, want) {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r SectionRules) byHeader(line string) (Section, string, bool) {
+	for _, s := range sections {
+		for _, re := range r[s].headers {
+			if loc := re.FindStringIndex(line); loc != nil {
+				return s, line[loc[1]:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Apply fills in the report's sections from its lines. A section carried in
+// its own OBX wins over one found by header. The impression is only
+// replaced if one is found, so a report no rule matches keeps its first
+// OBX as before.
+func (r SectionRules) Apply(report *Report) {
+	byID := map[Section][]string{}
+	byHeader := map[Section][]string{}
+	var current Section
+	for _, line := range report.Lines {
+		if s, ok := r.byID(line.ObservationID); ok {
+			byID[s] = append(byID[s], line.Text)
+			continue
+		}
+		for _, text := range strings.Split(line.Text, "\n") {
+			if s, rest, ok := r.byHeader(text); ok {
+				current, text = s, rest
+			}
+			if current != "" {
+				byHeader[current] = append(byHeader[current], text)
+			}
+		}
+	}
+	text := func(s Section) string {
+		lines, ok := byID[s]
+		if !ok {
+			lines = byHeader[s]
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	report.History = text(SectionHistory)
+	report.Technique = text(SectionTechnique)
+	report.Comparison = text(SectionComparison)
+	report.Findings = text(SectionFindings)
+	if impression := text(SectionImpression); impression != "" {
+		report.Impression = impression
+	}
+}
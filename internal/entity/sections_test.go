@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSectionRules_Headers(t *testing.T) {
+	report := Report{
+		Impression: "EXAM: CT HEAD WITHOUT CONTRAST",
+		Lines: []ReportLine{
+			{ObservationID: "&GDT", Text: "EXAM: CT HEAD WITHOUT CONTRAST"},
+			{ObservationID: "&GDT", Text: "CLINICAL HISTORY: Fall."},
+			{ObservationID: "&GDT", Text: "TECHNIQUE: Axial images without contrast."},
+			{ObservationID: "&GDT", Text: "COMPARISON: None."},
+			{ObservationID: "&GDT", Text: "FINDINGS:"},
+			{ObservationID: "&GDT", Text: "No hemorrhage."},
+			{ObservationID: "&GDT", Text: "Ventricles are normal.\nIMPRESSION: No acute intracranial abnormality."},
+		},
+	}
+	DefaultSectionRules.Apply(&report)
+	require.Equal(t, "Fall.", report.History)
+	require.Equal(t, "Axial images without contrast.", report.Technique)
+	require.Equal(t, "None.", report.Comparison)
+	require.Equal(t, "No hemorrhage.\nVentricles are normal.", report.Findings)
+	require.Equal(t, "No acute intracranial abnormality.", report.Impression)
+}
+
+func TestSectionRules_IDsWin(t *testing.T) {
+	report := Report{Lines: []ReportLine{
+		{ObservationID: "&GDT", Text: "FINDINGS: Lungs are clear."},
+		{ObservationID: "&GDT", Text: "IMPRESSION: Normal chest."},
+		{ObservationID: "&IMP", Text: "No acute disease."},
+	}}
+	DefaultSectionRules.Apply(&report)
+	require.Equal(t, "Lungs are clear.", report.Findings)
+	require.Equal(t, "No acute disease.", report.Impression)
+}
+
+func TestSectionRules_NoMatchKeepsImpression(t *testing.T) {
+	report := Report{
+		Impression: "Normal.",
+		Lines:      []ReportLine{{ObservationID: "&GDT", Text: "Normal."}},
+	}
+	DefaultSectionRules.Apply(&report)
+	require.Equal(t, "Normal.", report.Impression)
+	require.Empty(t, report.Findings)
+	require.Empty(t, report.History)
+}
+
+func TestParseSectionRules(t *testing.T) {
+	rules, err := ParseSectionRules([]byte(`{
+		"findings": {"ids": ["&GDT"]},
+		"impression": {"headers": ["(?i)^summary:"]}
+	}`))
+	require.NoError(t, err)
+	report := Report{Lines: []ReportLine{
+		{ObservationID: "&GDT", Text: "Lungs are clear."},
+		{ObservationID: "&TXT", Text: "Summary: Normal chest."},
+	}}
+	rules.Apply(&report)
+	require.Equal(t, "Lungs are clear.", report.Findings)
+	require.Equal(t, "Normal chest.", report.Impression)
+
+	_, err = ParseSectionRules([]byte(`{"addendum": {"ids": ["&ADD"]}}`))
+	require.ErrorContains(t, err, `unknown section "addendum"`)
+	_, err = ParseSectionRules([]byte(`{"findings": {"headers": ["("]}}`))
+	require.ErrorContains(t, err, "findings header")
+	_, err = ParseSectionRules([]byte(`[]`))
+	require.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+// StaleExamUpdate is an exam update that was skipped, either because the exam
+// had already been updated by a newer message or because the status change
+// wasn't an allowed transition (see Reason).
+type StaleExamUpdate struct {
+	ID              int64     `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExamID          int64     `json:"exam_id"`
+	MessageID       int64     `json:"message_id,omitempty"`
+	IncomingStatus  string    `json:"incoming_status"`
+	IncomingEventDT time.Time `json:"incoming_event_dt"`
+	CurrentStatus   string    `json:"current_status"`
+	LastEventDT     time.Time `json:"last_event_dt"`
+	Reason          string    `json:"reason"`
+}
+
+func DBtoStaleExamUpdate(s database.StaleExamUpdate) StaleExamUpdate {
+	return StaleExamUpdate{
+		ID:              s.ID,
+		CreatedAt:       s.CreatedAt.Time,
+		ExamID:          s.ExamID,
+		MessageID:       s.MessageID.Int64,
+		IncomingStatus:  s.IncomingStatus,
+		IncomingEventDT: s.IncomingEventDt.Time,
+		CurrentStatus:   s.CurrentStatus,
+		LastEventDT:     s.LastEventDt.Time,
+		Reason:          s.Reason,
+	}
+}
+
+// currentExamEvent loads the stored status and last event time for an
+// exam, or nil if it doesn't exist yet.
+func currentExamEvent(ctx context.Context, qtx *database.Queries, sendingApp, accession string) (*database.GetExamEventBySendingAppAccessionRow, error) {
+	current, err := qtx.GetExamEventBySendingAppAccession(ctx, database.GetExamEventBySendingAppAccessionParams{
+		SendingApp: sendingApp,
+		Accession:  accession,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving exam event for accession %s: %w", accession, err)
+	}
+	return &current, nil
+}
+
+// Reasons an exam update is skipped.
+const (
+	SkipStale             = "stale"
+	SkipIllegalTransition = "illegal_transition"
+)
+
+// admitUpdate reports whether exam should be written over current (nil for a
+// new exam). Updates older than the last one applied, or that would make a
+// status change the transition graph doesn't allow, are recorded in
+// stale_exam_updates and skipped.
+func (h *HL7Repo) admitUpdate(ctx context.Context, qtx *database.Queries, exam Exam, current *database.GetExamEventBySendingAppAccessionRow, msgID int64) (bool, error) {
+	if current == nil {
+		return true, nil
+	}
+	return h.admitStatus(ctx, qtx, current.ID, exam, exam.CurrentStatus, current.CurrentStatus, current.LastEventDt, msgID)
+}
+
+// admitStatus reports whether status, at exam's event time, may replace
+// stored, last applied at last. The order and result statuses each go
+// through it against their own stored status and time.
+func (h *HL7Repo) admitStatus(ctx context.Context, qtx *database.Queries, examID int64, exam Exam, status ExamStatus, stored string, last pgtype.Timestamp, msgID int64) (bool, error) {
+	reason := ""
+	switch {
+	case exam.IsStale(last.Time):
+		reason = SkipStale
+	case !h.transitions.Allowed(ExamStatus(stored), status):
+		reason = SkipIllegalTransition
+	default:
+		return true, nil
+	}
+	if err := qtx.CreateStaleExamUpdate(ctx, database.CreateStaleExamUpdateParams{
+		ExamID:          examID,
+		MessageID:       pgtype.Int8{Int64: msgID, Valid: true},
+		IncomingStatus:  status.String(),
+		IncomingEventDt: pgtype.Timestamp{Time: exam.EventTime, Valid: !exam.EventTime.IsZero()},
+		CurrentStatus:   stored,
+		LastEventDt:     last,
+		Reason:          reason,
+	}); err != nil {
+		return false, dbErr{"skipped exam update", err}
+	}
+	log.Printf("skipping %s update to exam %d (accession %s): %s at %s after %s at %s\n",
+		reason, examID, exam.Accession,
+		status, exam.EventTime.Format(time.RFC3339),
+		stored, last.Time.Format(time.RFC3339),
+	)
+	return false, nil
+}
+
+// ListStaleExamUpdates pages through skipped updates, starting after cursorID.
+func (h *HL7Repo) ListStaleExamUpdates(ctx context.Context, cursorID int64, limit int32) ([]StaleExamUpdate, error) {
+	rows, err := h.Queries.ListStaleExamUpdates(ctx, database.ListStaleExamUpdatesParams{
+		ID:    cursorID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	updates := make([]StaleExamUpdate, len(rows))
+	for i, r := range rows {
+		updates[i] = DBtoStaleExamUpdate(r)
+	}
+	return updates, nil
+}
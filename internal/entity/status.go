@@ -0,0 +1,210 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/s-hammon/volta/internal/database"
+)
+
+type ExamStatus string
+
+// Order statuses, from ORC-5 (HL7 table 0038).
+const (
+	ExamScheduled      ExamStatus = "SC"
+	ExamInProgress     ExamStatus = "IP"
+	ExamComplete       ExamStatus = "CM"
+	ExamCancelled      ExamStatus = "CA"
+	ExamOnHold         ExamStatus = "HD"
+	ExamDiscontinued   ExamStatus = "DC"
+	ExamError          ExamStatus = "ER"
+	ExamReplaced       ExamStatus = "RP"
+	ExamPartialResults ExamStatus = "A"
+)
+
+// Result statuses, from OBR-25 (HL7 table 0123). "A" (some results
+// available) is shared with ORC-5.
+const (
+	ResultOrderReceived ExamStatus = "O"
+	ResultScheduled     ExamStatus = "S"
+	ResultIncomplete    ExamStatus = "I"
+	ResultUnverified    ExamStatus = "R"
+	ResultPreliminary   ExamStatus = "P"
+	ResultFinal         ExamStatus = "F"
+	ResultCorrected     ExamStatus = "C"
+	ResultCancelled     ExamStatus = "X"
+)
+
+var examStatuses = []ExamStatus{
+	ExamScheduled, ExamInProgress, ExamComplete, ExamCancelled, ExamOnHold,
+	ExamDiscontinued, ExamError, ExamReplaced, ExamPartialResults,
+	ResultOrderReceived, ResultScheduled, ResultIncomplete, ResultUnverified,
+	ResultPreliminary, ResultFinal, ResultCorrected, ResultCancelled,
+}
+
+// NewExamStatus returns s if it's a known ORC-5 or OBR-25 code, or "" so that
+// the stored status is left alone.
+func NewExamStatus(s string) ExamStatus {
+	if status := ExamStatus(s); status.Known() {
+		return status
+	}
+	return ""
+}
+
+func (o ExamStatus) String() string {
+	return string(o)
+}
+
+func (o ExamStatus) Known() bool {
+	return slices.Contains(examStatuses, o)
+}
+
+// Transitions is the set of statuses an exam may move to from each status.
+// Staying in the same status is always allowed, as is any change from a
+// status that isn't listed.
+type Transitions map[ExamStatus][]ExamStatus
+
+var (
+	scheduledNext = []ExamStatus{
+		ExamScheduled, ResultScheduled, ResultOrderReceived, ExamInProgress, ResultIncomplete,
+		ExamOnHold, ExamComplete, ExamPartialResults, ResultUnverified, ResultPreliminary, ResultFinal,
+		ExamCancelled, ExamDiscontinued, ResultCancelled, ExamReplaced, ExamError,
+	}
+	inProgressNext = []ExamStatus{
+		ExamInProgress, ResultIncomplete, ExamOnHold,
+		ExamComplete, ExamPartialResults, ResultUnverified, ResultPreliminary, ResultFinal,
+		ExamCancelled, ExamDiscontinued, ResultCancelled, ExamError,
+	}
+	performedNext = []ExamStatus{
+		ExamComplete, ExamPartialResults, ResultUnverified, ResultPreliminary, ResultFinal, ResultCorrected, ExamError,
+	}
+	cancelledNext = []ExamStatus{ExamScheduled, ResultScheduled, ExamError}
+)
+
+// DefaultTransitions moves exams forward: scheduled, in progress, performed,
+// preliminary, final, corrected. Once performed an exam can't go back to
+// scheduled or in progress, or be cancelled, which is the rule the old
+// ensure_cm_on_end_dt trigger enforced. A cancelled exam may be reinstated.
+var DefaultTransitions = Transitions{
+	ExamScheduled:       scheduledNext,
+	ResultScheduled:     scheduledNext,
+	ResultOrderReceived: scheduledNext,
+	ExamOnHold: {
+		ExamScheduled, ResultScheduled, ExamInProgress, ResultIncomplete,
+		ExamCancelled, ExamDiscontinued, ResultCancelled, ExamReplaced, ExamError,
+	},
+	ExamInProgress:     inProgressNext,
+	ResultIncomplete:   inProgressNext,
+	ExamComplete:       performedNext,
+	ExamPartialResults: performedNext,
+	ResultUnverified:   performedNext,
+	ResultPreliminary:  {ResultPreliminary, ResultFinal, ResultCorrected, ExamError},
+	ResultFinal:        {ResultCorrected, ExamError},
+	ResultCorrected:    {ResultFinal, ExamError},
+	ExamCancelled:      cancelledNext,
+	ExamDiscontinued:   cancelledNext,
+	ResultCancelled:    cancelledNext,
+	ExamReplaced:       {ExamError},
+}
+
+// Allowed reports whether an exam in status from may move to status to.
+// A blank from (a new exam) or to (no status in the message) is allowed.
+func (t Transitions) Allowed(from, to ExamStatus) bool {
+	if from == "" || to == "" || from == to {
+		return true
+	}
+	next, ok := t[from]
+	if !ok {
+		return true
+	}
+	return slices.Contains(next, to)
+}
+
+// ParseTransitions reads a transition graph as JSON, e.g.
+// {"SC": ["IP", "CM", "CA"], "IP": ["CM"]}.
+func ParseTransitions(data []byte) (Transitions, error) {
+	var t Transitions
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid transitions: %w", err)
+	}
+	for from, next := range t {
+		if !from.Known() {
+			return nil, fmt.Errorf("invalid transitions: unknown status %q", from)
+		}
+		for _, to := range next {
+			if !to.Known() {
+				return nil, fmt.Errorf("invalid transitions: unknown status %q (from %s)", to, from)
+			}
+		}
+	}
+	return t, nil
+}
+
+// WithTransitions replaces DefaultTransitions.
+func WithTransitions(t Transitions) RepoOption {
+	return func(h *HL7Repo) { h.transitions = t }
+}
+
+// resolveStatus applies what the ensure_cm_on_end_dt trigger used to: an
+// exam with an end time that hasn't otherwise been marked performed is CM.
+func (e *Exam) resolveStatus() {
+	if e.End.IsZero() {
+		return
+	}
+	switch e.CurrentStatus {
+	case "", ExamScheduled, ResultScheduled, ResultOrderReceived, ExamInProgress, ResultIncomplete, ExamOnHold:
+		e.CurrentStatus = ExamComplete
+	}
+}
+
+// updateExamStatus applies an order status to an exam that already exists
+// outside of its upsert, e.g. when a replacement order marks it RP.
+func (h *HL7Repo) updateExamStatus(ctx context.Context, qtx *database.Queries, exam Exam, current *database.GetExamEventBySendingAppAccessionRow, msg Message, msgID int64) error {
+	if exam.CurrentStatus == "" || exam.CurrentStatus == ExamStatus(current.CurrentStatus) {
+		return nil
+	}
+	ok, err := h.admitUpdate(ctx, qtx, exam, current, msgID)
+	if err != nil || !ok {
+		return err
+	}
+	if err := qtx.UpdateExamStatus(ctx, database.UpdateExamStatusParams{
+		CurrentStatus: exam.CurrentStatus.String(),
+		EventDt:       pgtype.Timestamp{Time: exam.EventTime, Valid: !exam.EventTime.IsZero()},
+		ID:            current.ID,
+	}); err != nil {
+		return dbErr{"exam status", err}
+	}
+	return recordStatusChange(ctx, qtx, current.ID, current, exam, msg, msgID)
+}
+
+// updateResultStatus applies the result status an ORU carries to an exam.
+// It's checked against the exam's last result, not its order status, and
+// leaves current_status and last_event_dt to ORMs.
+func (h *HL7Repo) updateResultStatus(ctx context.Context, qtx *database.Queries, examID int64, exam Exam, current *database.GetExamEventBySendingAppAccessionRow, msgID int64) error {
+	if exam.ResultStatus == "" {
+		return nil
+	}
+	var stored string
+	var last pgtype.Timestamp
+	if current != nil {
+		if exam.ResultStatus == ExamStatus(current.ResultStatus) {
+			return nil
+		}
+		stored, last = current.ResultStatus, current.ResultEventDt
+	}
+	ok, err := h.admitStatus(ctx, qtx, examID, exam, exam.ResultStatus, stored, last, msgID)
+	if err != nil || !ok {
+		return err
+	}
+	if err := qtx.UpdateExamResultStatus(ctx, database.UpdateExamResultStatusParams{
+		ResultStatus: exam.ResultStatus.String(),
+		EventDt:      pgtype.Timestamp{Time: exam.EventTime, Valid: !exam.EventTime.IsZero()},
+		ID:           examID,
+	}); err != nil {
+		return dbErr{"exam result status", err}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExamStatus(t *testing.T) {
+	require.Equal(t, ExamScheduled, NewExamStatus("SC"))
+	require.Equal(t, ResultFinal, NewExamStatus("F"))
+	require.Equal(t, ExamStatus(""), NewExamStatus("ZZ"))
+	require.Equal(t, ExamStatus(""), NewExamStatus(""))
+}
+
+func TestTransitions_Allowed(t *testing.T) {
+	tests := []struct {
+		from, to ExamStatus
+		want     bool
+	}{
+		{"", ExamScheduled, true},
+		{ExamScheduled, "", true},
+		{ExamScheduled, ExamScheduled, true},
+		{ExamScheduled, ExamInProgress, true},
+		{ExamInProgress, ExamComplete, true},
+		{ExamComplete, ResultFinal, true},
+		{ResultPreliminary, ResultFinal, true},
+		{ResultFinal, ResultCorrected, true},
+		{ExamCancelled, ExamScheduled, true},
+		{ExamComplete, ExamScheduled, false},
+		{ExamComplete, ExamInProgress, false},
+		{ExamComplete, ExamCancelled, false},
+		{ResultFinal, ResultPreliminary, false},
+		{ExamCancelled, ExamComplete, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			require.Equal(t, tt.want, DefaultTransitions.Allowed(tt.from, tt.to))
+		})
+	}
+}
+
+func TestParseTransitions(t *testing.T) {
+	tr, err := ParseTransitions([]byte(`{"SC": ["IP", "CM"], "IP": ["CM"]}`))
+	require.NoError(t, err)
+	require.True(t, tr.Allowed(ExamScheduled, ExamComplete))
+	require.False(t, tr.Allowed(ExamInProgress, ExamScheduled))
+	// statuses without an entry are unrestricted
+	require.True(t, tr.Allowed(ExamComplete, ExamScheduled))
+
+	_, err = ParseTransitions([]byte(`{"SC": ["ZZ"]}`))
+	require.Error(t, err)
+	_, err = ParseTransitions([]byte(`{"ZZ": ["SC"]}`))
+	require.Error(t, err)
+	_, err = ParseTransitions([]byte(`[`))
+	require.Error(t, err)
+}
+
+func TestExam_ResolveStatus(t *testing.T) {
+	end := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		status ExamStatus
+		end    time.Time
+		want   ExamStatus
+	}{
+		{ExamScheduled, end, ExamComplete},
+		{ExamInProgress, end, ExamComplete},
+		{"", end, ExamComplete},
+		{ResultFinal, end, ResultFinal},
+		{ExamCancelled, end, ExamCancelled},
+		{ExamScheduled, time.Time{}, ExamScheduled},
+	}
+	for _, tt := range tests {
+		e := Exam{CurrentStatus: tt.status, End: tt.end}
+		e.resolveStatus()
+		require.Equal(t, tt.want, e.CurrentStatus)
+	}
+}
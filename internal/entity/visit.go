@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"time"
+
 	"github.com/s-hammon/volta/internal/database"
 	"github.com/s-hammon/volta/internal/objects"
 )
@@ -11,6 +13,18 @@ type Visit struct {
 	Site    Site
 	MRN     MRN
 	Type    objects.PatientType
+	// PV1-2 as sent; Type is what it maps to
+	Class      string
+	Location   Location
+	Admitted   time.Time
+	Discharged time.Time
+}
+
+// Location is where the patient is assigned (PV1-3).
+type Location struct {
+	PointOfCare string
+	Room        string
+	Bed         string
 }
 
 func DBtoVisit(visit database.GetVisitBySiteIdNumberRow) Visit {
@@ -31,6 +45,14 @@ func DBtoVisit(visit database.GetVisitBySiteIdNumberRow) Visit {
 			Name:    visit.SiteName.String,
 			Address: visit.SiteAddress.String,
 		},
-		Type: objects.PatientType(visit.PatientType),
+		Type:  objects.PatientType(visit.PatientType),
+		Class: visit.PatientClass.String,
+		Location: Location{
+			PointOfCare: visit.PointOfCare.String,
+			Room:        visit.Room.String,
+			Bed:         visit.Bed.String,
+		},
+		Admitted:   visit.AdmitDt.Time,
+		Discharged: visit.DischargeDt.Time,
 	}
 }
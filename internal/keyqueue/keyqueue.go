@@ -0,0 +1,110 @@
+// Package keyqueue serializes work that shares a key, while letting work on
+// different keys run concurrently.
+package keyqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Queue hands out exclusive turns per key in the order they were asked for.
+// The zero value is ready to use.
+type Queue struct {
+	mu      sync.Mutex
+	waiters map[string][]chan struct{}
+}
+
+func New() *Queue {
+	return &Queue{}
+}
+
+// Acquire waits for a turn on every key and returns a func that gives them
+// up. Keys are taken in sorted order so callers holding overlapping sets
+// can't deadlock; blank and repeated keys are ignored. If ctx ends first,
+// any turns already taken are released and ctx's error is returned.
+func (q *Queue) Acquire(ctx context.Context, keys ...string) (func(), error) {
+	keys = normalize(keys)
+	held := make([]string, 0, len(keys))
+	release := func() {
+		for _, k := range held {
+			q.done(k)
+		}
+	}
+	for _, k := range keys {
+		turn := q.enqueue(k)
+		select {
+		case <-turn:
+			held = append(held, k)
+		case <-ctx.Done():
+			q.abandon(k, turn)
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// Len is the number of keys currently held or waited on.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
+
+func (q *Queue) enqueue(key string) chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiters == nil {
+		q.waiters = map[string][]chan struct{}{}
+	}
+	turn := make(chan struct{})
+	q.waiters[key] = append(q.waiters[key], turn)
+	if len(q.waiters[key]) == 1 {
+		close(turn)
+	}
+	return turn
+}
+
+// done passes key to the next waiter.
+func (q *Queue) done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.advance(key, 0)
+}
+
+// abandon drops a waiter whose context ended. If its turn had already come,
+// it's passed along as though it were released.
+func (q *Queue) abandon(key string, turn chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.Index(q.waiters[key], turn)
+	if i < 0 {
+		return
+	}
+	q.advance(key, i)
+}
+
+func (q *Queue) advance(key string, i int) {
+	w := slices.Delete(q.waiters[key], i, i+1)
+	if len(w) == 0 {
+		delete(q.waiters, key)
+		return
+	}
+	q.waiters[key] = w
+	if i == 0 {
+		close(w[0])
+	}
+}
+
+func normalize(keys []string) []string {
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k != "" {
+			out = append(out, k)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,73 @@
+package keyqueue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_FIFOPerKey(t *testing.T) {
+	q := New()
+	first, err := q.Acquire(context.Background(), "exam:1")
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := q.Acquire(context.Background(), "exam:1")
+			require.NoError(t, err)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			release()
+		}()
+		// wait until this goroutine is queued so arrival order is known
+		require.Eventually(t, func() bool {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return len(q.waiters["exam:1"]) == i+2
+		}, time.Second, time.Millisecond)
+	}
+
+	// other keys aren't blocked
+	other, err := q.Acquire(context.Background(), "exam:2", "")
+	require.NoError(t, err)
+	other()
+
+	first()
+	wg.Wait()
+	require.Equal(t, []int{0, 1, 2, 3, 4}, order)
+	require.Zero(t, q.Len())
+}
+
+func TestQueue_MultipleKeysAndCancel(t *testing.T) {
+	q := New()
+	held, err := q.Acquire(context.Background(), "b", "a", "a")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx, "a", "c")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the cancelled waiter didn't keep "c" or its place behind "a"
+	c, err := q.Acquire(context.Background(), "c")
+	require.NoError(t, err)
+	c()
+
+	held()
+	held() // releasing twice is harmless
+	again, err := q.Acquire(context.Background(), "a", "b")
+	require.NoError(t, err)
+	again()
+	require.Zero(t, q.Len())
+}
@@ -0,0 +1,70 @@
+// Package notify passes critical results on to the people who act on them.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/s-hammon/volta/internal/entity"
+)
+
+// Log writes each critical result to the service log, as a warning.
+type Log struct{}
+
+func (Log) Name() string { return "log" }
+
+func (Log) NotifyCritical(ctx context.Context, c entity.CriticalResult) error {
+	log.Warn().
+		Int64("critical_result_id", c.ID).
+		Str("sending_app", c.SendingApp).
+		Str("accession", c.Accession).
+		Str("radiologist", c.Radiologist).
+		Str("rule", c.Rule).
+		Str("matched", c.Matched).
+		Msg("critical result")
+	return nil
+}
+
+// Webhook POSTs each critical result as JSON to a URL. Any response other
+// than a 2xx is an error.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, client *http.Client) *Webhook {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Webhook{url: url, client: client}
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) NotifyCritical(ctx context.Context, c entity.CriticalResult) error {
+	body, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding critical result: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
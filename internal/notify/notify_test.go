@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook(t *testing.T) {
+	var got entity.CriticalResult
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := entity.CriticalResult{ID: 3, ExamID: 7, Accession: "A1", Rule: "pneumothorax", Matched: "Large right pneumothorax."}
+	w := NewWebhook(srv.URL, nil)
+	require.NoError(t, w.NotifyCritical(context.Background(), c))
+	require.Equal(t, c, got)
+
+	status = http.StatusBadGateway
+	require.EqualError(t, w.NotifyCritical(context.Background(), c), "webhook returned 502 Bad Gateway")
+
+	require.NoError(t, Log{}.NotifyCritical(context.Background(), c))
+}
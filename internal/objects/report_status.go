@@ -2,10 +2,13 @@ package objects
 
 type ReportStatus string
 
+// Report statuses, from OBX-11 (HL7 table 0085). "P" is a preliminary
+// report.
 const (
-	Pending  ReportStatus = "P"
-	Final    ReportStatus = "F"
-	Addendum ReportStatus = "A"
+	Pending   ReportStatus = "P"
+	Final     ReportStatus = "F"
+	Corrected ReportStatus = "C"
+	Addendum  ReportStatus = "A"
 )
 
 func NewReportStatus(s string) ReportStatus {
@@ -14,6 +17,8 @@ func NewReportStatus(s string) ReportStatus {
 		return Pending
 	case "F":
 		return Final
+	case "C":
+		return Corrected
 	case "A":
 		return Addendum
 	default:
@@ -9,6 +9,7 @@ func TestNewReportStatus(t *testing.T) {
 	}{
 		{"P", Pending},
 		{"F", Final},
+		{"C", Corrected},
 		{"A", Addendum},
 		{"X", Pending},
 		{"", Pending},
@@ -0,0 +1,126 @@
+package reprocess
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingStore struct {
+	controlIDs []string
+}
+
+func (s *recordingStore) SaveORM(ctx context.Context, o *entity.Order) error {
+	s.controlIDs = append(s.controlIDs, o.Message.ControlID)
+	return nil
+}
+
+func (s *recordingStore) SaveORU(ctx context.Context, o *entity.Observation) error {
+	s.controlIDs = append(s.controlIDs, o.Message.ControlID)
+	return nil
+}
+
+func (s *recordingStore) SaveADT(ctx context.Context, a *entity.Admission) error {
+	s.controlIDs = append(s.controlIDs, a.Message.ControlID)
+	return nil
+}
+
+func (s *recordingStore) SaveMerge(ctx context.Context, m *entity.Merge) error {
+	s.controlIDs = append(s.controlIDs, m.Message.ControlID)
+	return nil
+}
+
+func (s *recordingStore) SaveSIU(ctx context.Context, a *entity.Appointment) error {
+	s.controlIDs = append(s.controlIDs, a.Message.ControlID)
+	return nil
+}
+
+func (s *recordingStore) GetProcedures(context.Context, int32) ([]byte, error) { return nil, nil }
+
+func (s *recordingStore) UpdateProcedures(context.Context, []byte) (int, int, error) {
+	return 0, 0, nil
+}
+
+func writeMessage(t *testing.T, dir, name, app, dt, typ, controlID string) {
+	t.Helper()
+	lines := []string{
+		"MSH|^~\\&|" + app + "|FAC|||" + dt + "||" + typ + "|" + controlID + "|P|2.3",
+		"PID|1||MRN1^^^^^SITE||Doe^Jane||19700101|F",
+		"ORC|NW|ACC" + controlID + "|||SC",
+		"OBR|1|ACC" + controlID + "||CT1^CT HEAD",
+	}
+	// exported files often use \n between segments
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+}
+
+func TestDirSource_OrderAndFilter(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+	writeMessage(t, dir, "c.hl7", "RIS", "20250103080000", "ORM^O01", "3")
+	writeMessage(t, dir, "a.hl7", "RIS", "20250102080000", "ORM^O01", "2")
+	writeMessage(t, filepath.Join(dir, "nested"), "b.hl7", "RIS", "20250101080000", "ORM^O01", "1")
+	writeMessage(t, dir, "other.hl7", "PACS", "20250101150000", "ORM^O01", "4")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignore me"), 0o600))
+
+	src, err := NewDirSource(dir, Filter{SendingApp: "RIS"})
+	require.NoError(t, err)
+	require.Equal(t, 3, src.Len())
+
+	store := &recordingStore{}
+	stats, err := Run(context.Background(), src, store, false)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Processed: 3}, stats)
+	require.Equal(t, []string{"1", "2", "3"}, store.controlIDs)
+
+	since := time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)
+	src, err = NewDirSource(dir, Filter{Since: since, MessageType: "ORM"})
+	require.NoError(t, err)
+	require.Equal(t, 2, src.Len())
+
+	store = &recordingStore{}
+	stats, err = Run(context.Background(), src, store, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Processed)
+	require.Empty(t, store.controlIDs)
+}
+
+type pagedLister struct {
+	msgs  []entity.RawMessage
+	calls int
+}
+
+func (l *pagedLister) ListRawMessages(ctx context.Context, f entity.RawMessageFilter, afterAt time.Time, afterID int64, limit int32) ([]entity.RawMessage, error) {
+	l.calls++
+	var out []entity.RawMessage
+	for _, m := range l.msgs {
+		if m.MessageID > afterID && len(out) < int(limit) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func TestDBSource_Pages(t *testing.T) {
+	lister := &pagedLister{}
+	base := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		lister.msgs = append(lister.msgs, entity.RawMessage{
+			MessageID:  int64(i + 1),
+			ReceivedAt: base.Add(time.Duration(i) * time.Minute),
+			ControlID:  string(rune('a' + i)),
+			Data:       []byte("MSH|^~\\&|RIS|FAC|||202501010000||ORM^O01|" + string(rune('a'+i)) + "|P|2.3\rORC|NW|ACC"),
+		})
+	}
+	store := &recordingStore{}
+	stats, err := Run(context.Background(), NewDBSource(lister, Filter{}, 2), store, false)
+	require.NoError(t, err)
+	require.Equal(t, 5, stats.Processed)
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, store.controlIDs)
+	require.Equal(t, 3, lister.calls)
+}
@@ -0,0 +1,203 @@
+package reprocess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/entity"
+	"github.com/s-hammon/volta/pkg/hl7"
+)
+
+// Record is one message to reprocess.
+type Record struct {
+	// Source identifies where the record came from (a file path or archived
+	// message ID) for logging.
+	Source      string
+	ReceivedAt  time.Time
+	SendingApp  string
+	MessageType string
+	ControlID   string
+	Data        []byte
+}
+
+// Source yields records in received_at order; Next returns io.EOF when done.
+type Source interface {
+	Next(ctx context.Context) (Record, error)
+}
+
+// Filter narrows which messages are reprocessed. Zero values match everything.
+type Filter struct {
+	Since       time.Time
+	Until       time.Time
+	SendingApp  string
+	MessageType string
+}
+
+func (f Filter) match(r Record) bool {
+	switch {
+	case !f.Since.IsZero() && r.ReceivedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.ReceivedAt.Before(f.Until):
+		return false
+	case f.SendingApp != "" && r.SendingApp != f.SendingApp:
+		return false
+	case f.MessageType != "" && r.MessageType != f.MessageType:
+		return false
+	}
+	return true
+}
+
+// DirSource reads .hl7 files under a directory. Headers are read up front so
+// the files can be sorted; bodies are read again as they're consumed.
+type DirSource struct {
+	records []Record
+	next    int
+}
+
+func NewDirSource(dir string, f Filter) (*DirSource, error) {
+	var records []Record
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".hl7") {
+			return nil
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- walking an operator-supplied directory
+		if err != nil {
+			return err
+		}
+		r, err := readHeader(path, data)
+		if err != nil {
+			return err
+		}
+		if f.match(r) {
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].ReceivedAt.Equal(records[j].ReceivedAt) {
+			return records[i].Source < records[j].Source
+		}
+		return records[i].ReceivedAt.Before(records[j].ReceivedAt)
+	})
+	return &DirSource{records: records}, nil
+}
+
+func (s *DirSource) Len() int { return len(s.records) }
+
+func (s *DirSource) Next(ctx context.Context) (Record, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, err
+	}
+	if s.next >= len(s.records) {
+		return Record{}, io.EOF
+	}
+	r := s.records[s.next]
+	s.next++
+	data, err := os.ReadFile(r.Source) // #nosec G304 -- found by NewDirSource
+	if err != nil {
+		return Record{}, err
+	}
+	r.Data = normalizeSegments(data)
+	return r, nil
+}
+
+func readHeader(path string, data []byte) (Record, error) {
+	msh := &api.Message{}
+	if err := hl7.NewDecoder(normalizeSegments(data)).Decode(msh); err != nil {
+		return Record{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return Record{
+		Source:      path,
+		ReceivedAt:  msh.ReceivedAt(),
+		SendingApp:  msh.SendingApp,
+		MessageType: msh.MsgType.Name,
+		ControlID:   msh.ControlID,
+	}, nil
+}
+
+// normalizeSegments turns exported files with \n or \r\n line endings back
+// into \r-separated segments.
+func normalizeSegments(data []byte) []byte {
+	s := strings.ReplaceAll(string(data), "\r\n", "\r")
+	s = strings.ReplaceAll(s, "\n", "\r")
+	return []byte(strings.TrimRight(s, "\r"))
+}
+
+// RawLister pages through the raw_messages archive.
+type RawLister interface {
+	ListRawMessages(ctx context.Context, f entity.RawMessageFilter, afterReceivedAt time.Time, afterID int64, limit int32) ([]entity.RawMessage, error)
+}
+
+// DBSource reads archived messages from raw_messages, a page at a time.
+type DBSource struct {
+	lister   RawLister
+	filter   entity.RawMessageFilter
+	pageSize int32
+
+	page    []entity.RawMessage
+	next    int
+	afterAt time.Time
+	afterID int64
+	done    bool
+}
+
+func NewDBSource(lister RawLister, f Filter, pageSize int32) *DBSource {
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+	return &DBSource{
+		lister: lister,
+		filter: entity.RawMessageFilter{
+			Since:       f.Since,
+			Until:       f.Until,
+			SendingApp:  f.SendingApp,
+			MessageType: f.MessageType,
+		},
+		pageSize: pageSize,
+	}
+}
+
+func (s *DBSource) Next(ctx context.Context) (Record, error) {
+	if s.next >= len(s.page) {
+		if s.done {
+			return Record{}, io.EOF
+		}
+		page, err := s.lister.ListRawMessages(ctx, s.filter, s.afterAt, s.afterID, s.pageSize)
+		if err != nil {
+			return Record{}, err
+		}
+		if len(page) < int(s.pageSize) {
+			s.done = true
+		}
+		if len(page) == 0 {
+			return Record{}, io.EOF
+		}
+		s.page, s.next = page, 0
+		last := page[len(page)-1]
+		s.afterAt, s.afterID = last.ReceivedAt, last.MessageID
+	}
+	m := s.page[s.next]
+	s.next++
+	return Record{
+		Source:      fmt.Sprintf("raw_messages:%d", m.MessageID),
+		ReceivedAt:  m.ReceivedAt,
+		SendingApp:  m.SendingApp,
+		MessageType: m.MessageType,
+		ControlID:   m.ControlID,
+		Data:        m.Data,
+	}, nil
+}
@@ -0,0 +1,294 @@
+// Package spool is a durable, append-only FIFO on local disk. Volta uses it
+// to accept messages while the database is unavailable and save them, in
+// order, once it's back.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	logName    = "spool.log"
+	offsetName = "spool.offset"
+	headerSize = 8
+	maxRecord  = 64 << 20
+)
+
+var ErrEmpty = errors.New("spool is empty")
+
+type Entry struct {
+	HL7Path   string    `json:"hl7_path,omitempty"`
+	Raw       []byte    `json:"raw"`
+	SpooledAt time.Time `json:"spooled_at"`
+}
+
+// Spool stores entries as length- and CRC-prefixed records in spool.log.
+// spool.offset holds the position of the oldest unacknowledged record; once
+// everything is acknowledged the log is truncated.
+type Spool struct {
+	dir string
+
+	mu     sync.Mutex
+	f      *os.File
+	offset int64 // start of the oldest pending record
+	size   int64 // end of the last complete record
+	depth  int
+	notify chan struct{}
+
+	appended int64
+	drained  int64
+}
+
+// Open opens (or creates) the spool in dir. A torn record at the end of the
+// log, left by a crash mid-append, is discarded.
+func Open(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating spool dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening spool: %w", err)
+	}
+	s := &Spool{dir: dir, f: f, notify: make(chan struct{}, 1)}
+	if s.offset, err = readOffset(dir); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := s.scan(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan counts pending records and finds the end of the last good one.
+func (s *Spool) scan() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.offset > info.Size() {
+		s.offset = 0
+	}
+	pos := s.offset
+	for {
+		_, n, err := s.readAt(pos)
+		if err != nil {
+			break
+		}
+		pos += n
+		s.depth++
+	}
+	s.size = pos
+	if pos < info.Size() {
+		if err := s.f.Truncate(pos); err != nil {
+			return fmt.Errorf("error truncating torn spool record: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Spool) readAt(pos int64) (Entry, int64, error) {
+	var hdr [headerSize]byte
+	if _, err := s.f.ReadAt(hdr[:], pos); err != nil {
+		return Entry{}, 0, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:4])
+	sum := binary.BigEndian.Uint32(hdr[4:])
+	if n > maxRecord {
+		return Entry{}, 0, fmt.Errorf("spool record at %d is too large", pos)
+	}
+	payload := make([]byte, n)
+	if _, err := s.f.ReadAt(payload, pos+headerSize); err != nil {
+		return Entry{}, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return Entry{}, 0, fmt.Errorf("spool record at %d is corrupt", pos)
+	}
+	var e Entry
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Entry{}, 0, err
+	}
+	return e, headerSize + int64(n), nil
+}
+
+// Append durably adds e to the end of the spool.
+func (s *Spool) Append(e Entry) error {
+	if e.SpooledAt.IsZero() {
+		e.SpooledAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.WriteAt(buf, s.size); err != nil {
+		return fmt.Errorf("error writing to spool: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("error syncing spool: %w", err)
+	}
+	s.size += int64(len(buf))
+	s.depth++
+	s.appended++
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Peek returns the oldest pending entry without removing it.
+func (s *Spool) Peek() (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.depth == 0 {
+		return Entry{}, ErrEmpty
+	}
+	e, _, err := s.readAt(s.offset)
+	return e, err
+}
+
+// Ack removes the oldest pending entry.
+func (s *Spool) Ack() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.depth == 0 {
+		return ErrEmpty
+	}
+	_, n, err := s.readAt(s.offset)
+	if err != nil {
+		return err
+	}
+	s.offset += n
+	s.depth--
+	s.drained++
+	if s.depth == 0 {
+		// drained: start the log over rather than let it grow forever
+		if err := s.f.Truncate(0); err != nil {
+			return err
+		}
+		s.offset, s.size = 0, 0
+	}
+	return writeOffset(s.dir, s.offset)
+}
+
+// Depth is the number of pending entries.
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+// Stats are counts since the spool was opened.
+type Stats struct {
+	Depth    int
+	Appended int64
+	Drained  int64
+}
+
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{Depth: s.depth, Appended: s.appended, Drained: s.drained}
+}
+
+// Drain passes pending entries to fn oldest first, acking each one fn returns
+// nil for. An error from fn leaves the entry in place and pauses the drain
+// until retry has passed or another entry is appended. Drain returns when
+// ctx is done.
+func (s *Spool) Drain(ctx context.Context, retry time.Duration, fn func(context.Context, Entry) error) {
+	for {
+		if err := s.drainPending(ctx, fn); err != nil {
+			log.Warn().Err(err).Int("depth", s.Depth()).Msg("spool drain paused")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (s *Spool) drainPending(ctx context.Context, fn func(context.Context, Entry) error) error {
+	for s.Depth() > 0 && ctx.Err() == nil {
+		e, err := s.Peek()
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, e); err != nil {
+			return err
+		}
+		if err := s.Ack(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+func readOffset(dir string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, offsetName)) // #nosec G304 -- spool dir from operator config
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading spool offset: %w", err)
+	}
+	off, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid spool offset: %w", err)
+	}
+	return off, nil
+}
+
+func writeOffset(dir string, off int64) error {
+	tmp := filepath.Join(dir, offsetName+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) // #nosec G304 -- spool dir from operator config
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(strconv.FormatInt(off, 10)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, offsetName))
+}
@@ -0,0 +1,123 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool_AppendAckReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+
+	_, err = s.Peek()
+	require.ErrorIs(t, err, ErrEmpty)
+
+	for _, raw := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Append(Entry{HL7Path: "path/" + raw, Raw: []byte(raw)}))
+	}
+	require.Equal(t, 3, s.Depth())
+
+	e, err := s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, "one", string(e.Raw))
+	require.Equal(t, "path/one", e.HL7Path)
+	require.False(t, e.SpooledAt.IsZero())
+	require.NoError(t, s.Ack())
+	require.NoError(t, s.Close())
+
+	// pending entries and progress survive a restart
+	s, err = Open(dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Depth())
+	e, err = s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, "two", string(e.Raw))
+
+	require.NoError(t, s.Ack())
+	require.NoError(t, s.Ack())
+	require.Equal(t, Stats{Depth: 0, Drained: 2}, s.Stats())
+	require.ErrorIs(t, s.Ack(), ErrEmpty)
+
+	info, err := os.Stat(filepath.Join(dir, logName))
+	require.NoError(t, err)
+	require.Zero(t, info.Size(), "log should be truncated once drained")
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, s.Append(Entry{Raw: []byte("one")}))
+	require.NoError(t, s.Append(Entry{Raw: []byte("two")}))
+	require.NoError(t, s.Close())
+
+	// simulate a crash partway through the second append
+	path := filepath.Join(dir, logName)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	s, err = Open(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, s.Depth())
+	require.NoError(t, s.Append(Entry{Raw: []byte("three")}))
+	require.NoError(t, s.Close())
+
+	s, err = Open(dir)
+	require.NoError(t, err)
+	var got []string
+	for s.Depth() > 0 {
+		e, err := s.Peek()
+		require.NoError(t, err)
+		got = append(got, string(e.Raw))
+		require.NoError(t, s.Ack())
+	}
+	require.Equal(t, []string{"one", "three"}, got)
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_Drain(t *testing.T) {
+	s, err := Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	for _, raw := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Append(Entry{Raw: []byte(raw)}))
+	}
+
+	down := errors.New("database unavailable")
+	calls := 0
+	var saved []string
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Drain(ctx, time.Millisecond, func(ctx context.Context, e Entry) error {
+			calls++
+			// fail the first attempt at "two"; it must be retried before "three"
+			if string(e.Raw) == "two" && calls == 2 {
+				return down
+			}
+			saved = append(saved, string(e.Raw))
+			if len(saved) == 3 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		cancel()
+		t.Fatal("drain didn't finish")
+	}
+	require.Equal(t, []string{"one", "two", "three"}, saved)
+	require.Equal(t, 0, s.Depth())
+}
@@ -0,0 +1,191 @@
+// Package fakehealthcare is a minimal stand-in for the Cloud Healthcare API's
+// HL7v2 messages.get and messages.list endpoints, for tests and local runs.
+package fakehealthcare
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	Name        string
+	Data        []byte
+	MessageType string
+	SendTime    time.Time
+}
+
+// Server serves messages under /v1/. Point a client at it with
+// option.WithEndpoint(s.URL()) and option.WithoutAuthentication().
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	messages map[string][]Message // keyed by store path
+	gets     map[string]int
+}
+
+func New() *Server {
+	s := &Server{messages: map[string][]Message{}, gets: map[string]int{}}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *Server) URL() string { return s.srv.URL + "/" }
+
+func (s *Server) Close() { s.srv.Close() }
+
+// Add stores m in store, naming it <store>/messages/<n> if Name is empty.
+func (s *Server) Add(store string, m Message) Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.Name == "" {
+		m.Name = store + "/messages/" + strconv.Itoa(len(s.messages[store])+1)
+	}
+	s.messages[store] = append(s.messages[store], m)
+	sort.SliceStable(s.messages[store], func(i, j int) bool {
+		return s.messages[store][i].SendTime.Before(s.messages[store][j].SendTime)
+	})
+	return m
+}
+
+// Gets reports how many times a message was fetched.
+func (s *Server) Gets(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets[name]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if store, ok := strings.CutSuffix(path, "/messages"); ok {
+		s.list(w, r, store)
+		return
+	}
+	s.get(w, path)
+}
+
+func (s *Server) get(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	store, _, _ := strings.Cut(name, "/messages/")
+	for _, m := range s.messages[store] {
+		if m.Name == name {
+			s.gets[name]++
+			writeJSON(w, map[string]any{
+				"name":        m.Name,
+				"data":        base64.StdEncoding.EncodeToString(m.Data),
+				"messageType": m.MessageType,
+				"sendTime":    m.SendTime.Format(time.RFC3339Nano),
+			})
+			return
+		}
+	}
+	http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, store string) {
+	q := r.URL.Query()
+	match, err := parseFilter(q.Get("filter"))
+	if err != nil {
+		http.Error(w, `{"error":{"code":400,"message":"invalid filter"}}`, http.StatusBadRequest)
+		return
+	}
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	start, _ := strconv.Atoi(q.Get("pageToken"))
+
+	s.mu.Lock()
+	var matched []Message
+	for _, m := range s.messages[store] {
+		if match(m) {
+			matched = append(matched, m)
+		}
+	}
+	s.mu.Unlock()
+
+	resp := map[string]any{}
+	end := min(start+pageSize, len(matched))
+	msgs := []map[string]string{}
+	for _, m := range matched[min(start, end):end] {
+		msgs = append(msgs, map[string]string{"name": m.Name})
+	}
+	resp["hl7V2Messages"] = msgs
+	if end < len(matched) {
+		resp["nextPageToken"] = strconv.Itoa(end)
+	}
+	writeJSON(w, resp)
+}
+
+// parseFilter understands the subset of the messages.list filter syntax used
+// by backfill: `messageType = "X"` and `sendTime <op> "RFC3339"` joined by AND.
+func parseFilter(filter string) (func(Message) bool, error) {
+	var preds []func(Message) bool
+	for _, clause := range strings.Split(filter, " AND ") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		fields := strings.Fields(clause)
+		if len(fields) != 3 {
+			return nil, errInvalidFilter
+		}
+		field, op, value := fields[0], fields[1], strings.Trim(fields[2], `"`)
+		switch field {
+		case "messageType":
+			if op != "=" {
+				return nil, errInvalidFilter
+			}
+			preds = append(preds, func(m Message) bool { return m.MessageType == value })
+		case "sendTime":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, err
+			}
+			cmp := map[string]func(time.Time) bool{
+				"=":  func(x time.Time) bool { return x.Equal(t) },
+				">":  func(x time.Time) bool { return x.After(t) },
+				">=": func(x time.Time) bool { return !x.Before(t) },
+				"<":  func(x time.Time) bool { return x.Before(t) },
+				"<=": func(x time.Time) bool { return !x.After(t) },
+			}[op]
+			if cmp == nil {
+				return nil, errInvalidFilter
+			}
+			preds = append(preds, func(m Message) bool { return cmp(m.SendTime) })
+		default:
+			return nil, errInvalidFilter
+		}
+	}
+	return func(m Message) bool {
+		for _, p := range preds {
+			if !p(m) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+type filterError string
+
+func (e filterError) Error() string { return string(e) }
+
+const errInvalidFilter = filterError("unsupported filter")
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -3,6 +3,7 @@ package integration
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"io/fs"
 	"os"
@@ -11,10 +12,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pressly/goose/v3"
 	goosedb "github.com/pressly/goose/v3/database"
 	"github.com/s-hammon/volta/internal/api"
+	"github.com/s-hammon/volta/internal/blob"
 	"github.com/s-hammon/volta/internal/database"
 	"github.com/s-hammon/volta/internal/entity"
 	"github.com/s-hammon/volta/internal/testing/testdb"
@@ -53,7 +56,9 @@ func TestHL7Upserts(t *testing.T) {
 			case "ORU":
 				testUpsertORU(t, ctx, repo, d)
 			case "ADT":
-				t.Logf("skipping ADT message: %s", hl7Msg.Name())
+				testUpsertADT(t, ctx, repo, d)
+			case "SIU":
+				testUpsertSIU(t, ctx, repo, d)
 			default:
 				t.Fatalf("unsupported message type: %s", msg.MsgType.Name)
 			}
@@ -100,11 +105,8 @@ func TestORMVisit(t *testing.T) {
 	require.NoError(t, err, "couldn't read test file at 5.hl7: %v", err)
 	require.Greater(t, len(data), 0, "file is empty")
 
-	orm := &api.ORM{}
-	d := hl7.NewDecoder(data)
-	err = d.Decode(orm)
-	require.NoError(t, err)
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	orm, exams := decodeORM(t, hl7.NewDecoder(data))
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	v, err := repo.Queries.GetVisitById(ctx, 1)
@@ -112,6 +114,610 @@ func TestORMVisit(t *testing.T) {
 	require.Equal(t, int16(1), v.PatientType)
 }
 
+func TestAppointmentLinksToExam(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	// booked before the order arrives, then linked when the ORM creates the exam
+	for _, name := range []string{"10.hl7", "5.hl7", "8.hl7"} {
+		data, err := hl7.HL7.ReadFile("test_hl7/" + name)
+		require.NoError(t, err)
+		d := hl7.NewDecoder(data)
+		msg := &api.Message{}
+		require.NoError(t, d.Decode(msg))
+		switch msg.MsgType.Name {
+		case "SIU":
+			testUpsertSIU(t, ctx, repo, d)
+		case "ORM":
+			testUpsertORM(t, ctx, repo, d)
+		}
+	}
+
+	appts, err := repo.Queries.GetAppointmentsBySendingAppAccession(ctx, database.GetAppointmentsBySendingAppAccessionParams{
+		SendingApp: "STRIC",
+		Accession:  "29737914",
+	})
+	require.NoError(t, err)
+	require.Len(t, appts, 1)
+	examID, err := repo.ExamID(ctx, "STRIC", "29737914")
+	require.NoError(t, err)
+	require.Equal(t, examID, appts[0].ExamID.Int64)
+	require.Equal(t, int32(20), appts[0].DurationMinutes.Int32)
+
+	metrics, err := repo.AppointmentMetrics(ctx,
+		time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "BMCNE", metrics[0].Site)
+	require.Equal(t, int64(1), metrics[0].Scheduled)
+	require.Equal(t, int64(1), metrics[0].Performed)
+	require.Zero(t, metrics[0].NoShows)
+}
+
+func TestAppointmentUpdates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	for _, msg := range []string{
+		"MSH|^~\\&|RIS|BMCNE|STRIC||20250404101000||SIU^S12|S1|P|2.3\r" +
+			"SCH|1|A1|||||||20|MIN|^^^20250404165000||||||||||||||Booked|ACC1\r" +
+			"PID|1||100^^^BMCNE",
+		// a modification without PID-3 keeps the patient
+		"MSH|^~\\&|RIS|BMCNE|STRIC||20250404111000||SIU^S14|S2|P|2.3\r" +
+			"SCH|1|A1|||||||30|MIN|^^^20250404165000||||||||||||||Deleted|ACC1",
+	} {
+		testUpsertSIU(t, ctx, repo, hl7.NewDecoder([]byte(msg)))
+	}
+
+	appts, err := repo.Queries.GetAppointmentsBySendingAppAccession(ctx, database.GetAppointmentsBySendingAppAccessionParams{
+		SendingApp: "STRIC",
+		Accession:  "ACC1",
+	})
+	require.NoError(t, err)
+	require.Len(t, appts, 1)
+	require.True(t, appts[0].MrnID.Valid)
+	require.Equal(t, int32(30), appts[0].DurationMinutes.Int32)
+
+	// a deleted appointment isn't scheduled
+	metrics, err := repo.AppointmentMetrics(ctx,
+		time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Zero(t, metrics[0].Scheduled)
+}
+
+func TestOrderControl(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(msg string) {
+		t.Helper()
+		orm, exams := decodeORM(t, hl7.NewDecoder([]byte(msg)))
+		require.NoError(t, repo.SaveORM(ctx, orm.ToOrder(exams...)))
+	}
+	status := func(accession string) database.GetExamBySendingAppAccessionRow {
+		t.Helper()
+		exam, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
+			SendingApp: "STRIC",
+			Accession:  accession,
+		})
+		require.NoError(t, err)
+		return exam
+	}
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||ORM^O01|O1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|NW|A1|||SC||||20250501120000\r" +
+		"OBR|1|A1||CT123^CT Head")
+	// the replacement's ORC-8 names the order it replaces
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501130000||ORM^O01|O2|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|RP|A2|||SC|||A1|20250501130000\r" +
+		"OBR|1|A2||CT124^CT Head w/ contrast")
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501140000||ORM^O01|O3|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|CA|A2|||SC||||20250501140000\r" +
+		"OBR|1|A2||CT124^CT Head w/ contrast")
+
+	require.Equal(t, "RP", status("A1").CurrentStatus)
+	replacement := status("A2")
+	require.Equal(t, "A1", replacement.ReplacedAccession.String)
+	require.Equal(t, "CA", replacement.CurrentStatus)
+	require.True(t, replacement.ExamCancelledDt.Valid)
+}
+
+func TestMultipleOrders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	// one requisition for a CT chest/abdomen/pelvis: an exam per ORC/OBR group
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||ORM^O01|O1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"PV1|1|O|||||||||||||||||V1\r" +
+		"ORC|NW|A1|||SC||||20250501120000\r" +
+		"OBR|1|A1||CT100^CT Chest\r" +
+		"ORC|NW|A2|||SC||||20250501120000\r" +
+		"OBR|2|A2||CT200^CT Abdomen\r" +
+		"ORC|NW|A3|||SC||||20250501120000\r" +
+		"OBR|3|A3||CT300^CT Pelvis")
+	orm, exams := decodeORM(t, hl7.NewDecoder(data))
+	require.Len(t, exams, 3)
+	require.NoError(t, repo.SaveORM(ctx, orm.ToOrder(exams...)))
+
+	var visitID int64
+	for _, want := range []struct{ accession, code string }{
+		{"A1", "CT100"},
+		{"A2", "CT200"},
+		{"A3", "CT300"},
+	} {
+		exam, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
+			SendingApp: "STRIC",
+			Accession:  want.accession,
+		})
+		require.NoError(t, err)
+		require.Equal(t, want.code, exam.ProcedureCode.String)
+		require.Equal(t, "SC", exam.CurrentStatus)
+		if visitID == 0 {
+			visitID = exam.VisitID.Int64
+		}
+		require.Equal(t, visitID, exam.VisitID.Int64)
+	}
+}
+
+func TestReportVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(controlID, status, submitted, impression string) {
+		t.Helper()
+		msg := fmt.Sprintf("MSH|^~\\&|PS|MHS|STRIC|MHS|%s||ORU^R01|%s|P|2.3\r"+
+			"PID|1||100^^^MHS^MR||Doe^John\r"+
+			"ORC|RE|A1|||CM\r"+
+			"OBR|1|A1||CT123^CT Head|||||||||||||||||||||%s|||||||1234&Rad&Ray\r"+
+			"OBX|1|TX|IMP||%s||||||%s|||%s",
+			submitted, controlID, status, impression, status, submitted)
+		testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(msg)))
+	}
+	save("R1", "P", "20250501120000", "No acute findings.")
+	save("R2", "F", "20250501130000", "No acute findings.")
+	save("R3", "C", "20250501140000", "Small nodule, right upper lobe.")
+	// a late resend of the original final doesn't undo the correction
+	save("R4", "F", "20250501130000", "No acute findings.")
+
+	exam, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
+		SendingApp: "STRIC",
+		Accession:  "A1",
+	})
+	require.NoError(t, err)
+	versions, err := repo.ReportHistory(ctx, exam.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+
+	prelim, final, corrected := versions[0], versions[1], versions[2]
+	require.Equal(t, "P", prelim.Status)
+	require.Zero(t, prelim.PreviousID)
+	require.True(t, prelim.Current)
+	require.Equal(t, "F", final.Status)
+	require.Equal(t, prelim.ID, final.PreviousID)
+	require.False(t, final.Current)
+	require.Equal(t, "C", corrected.Status)
+	require.Equal(t, final.ID, corrected.PreviousID)
+	require.True(t, corrected.Current)
+	require.Equal(t, "Rad, Ray", corrected.Radiologist)
+
+	require.Equal(t, entity.RolePrelim, prelim.Role)
+	require.Equal(t, entity.RoleFinal, final.Role)
+	require.Equal(t, entity.RoleCorrected, corrected.Role)
+}
+
+func TestExamReports(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(controlID, status, submitted, impression string, accessions ...string) {
+		t.Helper()
+		testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage(controlID, status, submitted, impression, accessions...))))
+	}
+	// one dictation covering both accessions, then two addenda on the first
+	save("R1", "F", "20250501120000", "No acute findings.", "A1", "A2")
+	save("R2", "A", "20250501130000", "Addendum: prior comparison reviewed.", "A1")
+	save("R3", "A", "20250501140000", "Addendum: discussed with Dr. Smith.", "A1")
+	save("R3", "A", "20250501140000", "Addendum: discussed with Dr. Smith.", "A1")
+
+	examID := func(accession string) int64 {
+		t.Helper()
+		id, err := repo.ExamID(ctx, "STRIC", accession)
+		require.NoError(t, err)
+		return id
+	}
+	reports, err := repo.ExamReports(ctx, examID("A1"))
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	require.Equal(t, entity.RoleFinal, reports[0].Role)
+	require.Equal(t, entity.RoleAddendum, reports[1].Role)
+	require.Equal(t, 1, reports[1].Sequence)
+	require.Equal(t, entity.RoleAddendum, reports[2].Role)
+	require.Equal(t, 2, reports[2].Sequence)
+
+	exams, err := repo.ReportExams(ctx, reports[0].ReportID)
+	require.NoError(t, err)
+	require.Len(t, exams, 2)
+	require.Equal(t, "A1", exams[0].Accession)
+	require.Equal(t, "A2", exams[1].Accession)
+	require.Equal(t, examID("A2"), exams[1].ExamID)
+}
+
+func TestReportIdentity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(controlID, status, submitted, impression string, accessions ...string) {
+		t.Helper()
+		testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage(controlID, status, submitted, impression, accessions...))))
+	}
+	save("R1", "F", "20250501120000", "Normal.", "A1")
+	// a resend is the same report, even with a new OBX-14
+	save("R1", "F", "20250501120000", "Normal.", "A1")
+	save("R2", "F", "20250501120500", "Normal.", "A1")
+	// the same short impression from the same radiologist at the same
+	// second, for another accession, is another report
+	save("R3", "F", "20250501120000", "Normal.", "A2")
+	// an edit is a new version
+	save("R4", "F", "20250501130000", "Normal. No acute findings.", "A1")
+
+	reports, err := repo.Queries.GetAllReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	byKey := map[string]database.Report{}
+	for _, r := range reports {
+		byKey[fmt.Sprintf("%v/%d", r.Accessions, r.Sequence)] = r
+	}
+	require.Contains(t, byKey, "[A1]/1")
+	require.Contains(t, byKey, "[A1]/2")
+	require.Contains(t, byKey, "[A2]/1")
+	require.Equal(t, "STRIC", byKey["[A1]/2"].SendingApp)
+	require.Equal(t, byKey["[A1]/1"].ID, byKey["[A1]/2"].PreviousReportID.Int64)
+}
+
+func TestReportSections(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	msg := "MSH|^~\\&|PS|MHS|STRIC|MHS|20250501120000||ORU^R01|S1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|RE|A1|||CM\r" +
+		"OBR|1|A1||CT1^CT|||||||||||||||||||||F|||||||1234&Rad&Ray\r" +
+		"OBX|1|TX|&GDT||HISTORY: Fall.||||||F|||20250501120000\r" +
+		"OBX|2|TX|&GDT||FINDINGS: No hemorrhage.||||||F|||20250501120000\r" +
+		"OBX|3|TX|&IMP||No acute intracranial abnormality.||||||F|||20250501120000"
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(msg)))
+
+	reports, err := repo.Queries.GetAllReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, "Fall.", reports[0].History)
+	require.Equal(t, "No hemorrhage.", reports[0].Findings)
+	require.Equal(t, "No acute intracranial abnormality.", reports[0].Impression)
+	require.Empty(t, reports[0].Technique)
+}
+
+func TestReportAttachments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+	blobs, err := blob.NewDir(t.TempDir())
+	require.NoError(t, err)
+	blobRepo := entity.NewRepo(repo.DB, entity.WithBlobStore(blobs))
+
+	oru := func(controlID, accession, pdf string) string {
+		return "MSH|^~\\&|PS|MHS|STRIC|MHS|20250501120000||ORU^R01|" + controlID + "|P|2.3\r" +
+			"PID|1||100^^^MHS^MR||Doe^John\r" +
+			"ORC|RE|" + accession + "|||CM\r" +
+			"OBR|1|" + accession + "||CT1^CT|||||||||||||||||||||F|||||||1234&Rad&Ray\r" +
+			"OBX|1|TX|&IMP||No acute disease.||||||F|||20250501120000\r" +
+			"OBX|2|ED|PDF||PS^Application^PDF^Base64^" + base64.StdEncoding.EncodeToString([]byte(pdf)) + "||||||F|||20250501120000"
+	}
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oru("P1", "A1", "%PDF-1"))))
+	// a resend doesn't add the attachment again
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oru("P1", "A1", "%PDF-1"))))
+	testUpsertORU(t, ctx, blobRepo, hl7.NewDecoder([]byte(oru("P2", "A2", "%PDF-2"))))
+
+	reports, err := repo.Queries.GetAllReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	for _, r := range reports {
+		require.Equal(t, "No acute disease.\n", r.Body, "the PDF isn't in the body")
+		attachments, err := repo.ReportAttachments(ctx, r.ID)
+		require.NoError(t, err)
+		require.Len(t, attachments, 1)
+		require.Equal(t, "application/pdf", attachments[0].MIMEType)
+
+		want := "%PDF-1"
+		if r.Accessions[0] == "A2" {
+			want = "%PDF-2"
+			// only the repo with the blob store can read it
+			_, err := repo.ReportAttachment(ctx, attachments[0].ID)
+			require.Error(t, err)
+			a, err := blobRepo.ReportAttachment(ctx, attachments[0].ID)
+			require.NoError(t, err)
+			require.Equal(t, want, string(a.Data))
+			continue
+		}
+		a, err := repo.ReportAttachment(ctx, attachments[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, want, string(a.Data))
+	}
+}
+
+func TestMDMDocuments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(controlID, event, dt, txa, text string) {
+		t.Helper()
+		msg := "MSH|^~\\&|DICT|MHS|STRIC|MHS|" + dt + "||MDM^" + event + "|" + controlID + "|P|2.3\r" +
+			"PID|1||100^^^MHS^MR||Doe^John\r" +
+			"TXA|1|RAD|TX|" + dt + "|1234^Rad^Ray|||||||" + txa + "\r" +
+			"OBX|1|TX|&IMP||" + text + "||||||F"
+		_, _, err := api.HandleByMsgType(repo, []byte(msg))
+		require.NoError(t, err)
+	}
+	// TXA-12 through TXA-19
+	save("M1", "T02", "20250501120000", "DOC1||A1|||DI", "Normal.")
+	save("M2", "T04", "20250501130000", "DOC1||A1|||AU||AV", "Normal.")
+	save("M3", "T10", "20250501140000", "DOC2|DOC1|A1|||AU||AV", "Small nodule.")
+
+	examID, err := repo.ExamID(ctx, "STRIC", "A1")
+	require.NoError(t, err)
+	history, err := repo.ReportHistory(ctx, examID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, []string{"P", "F", "C"}, []string{history[0].Status, history[1].Status, history[2].Status})
+	require.Equal(t, history[1].ID, history[2].PreviousID)
+	// the replaced document's versions are obsolete
+	require.True(t, history[0].Obsolete)
+	require.True(t, history[1].Obsolete)
+	require.False(t, history[1].Current)
+	require.True(t, history[2].Current)
+
+	// and a document made obsolete isn't current any more
+	save("M4", "T04", "20250501150000", "DOC2||A1|||AU||OB", "Small nodule.")
+	history, err = repo.ReportHistory(ctx, examID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.True(t, history[2].Obsolete)
+	require.False(t, history[2].Current)
+}
+
+func TestADTVisit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(msg string) {
+		t.Helper()
+		adt := &api.ADT{}
+		require.NoError(t, hl7.NewDecoder([]byte(msg)).Decode(adt))
+		require.NoError(t, repo.SaveADT(ctx, adt.ToAdmission()))
+	}
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ADT^A01|ADT1|P|2.3\r" +
+		"EVN|A01|20250402115900\r" +
+		"PID|1||123456^^^MHS^MR||Doe^John||19800101|M\r" +
+		"PV1|1|I|2000^2012^01||||1234^Doctor^Bob|2345^Nurse^Mary|||||||||||V100")
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402130000||ADT^A08|ADT2|P|2.3\r" +
+		"PID|1||123456^^^MHS^MR||Doe^Jonathan||19800101|M\r" +
+		"PV1|1||3000^3010^02|||||||||||||||||V100")
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250403090000||ADT^A03|ADT3|P|2.3\r" +
+		"EVN|A03|20250403085500\r" +
+		"PID|1||123456^^^MHS^MR||Doe^Jonathan||19800101|M\r" +
+		"PV1|1|I|3000^3010^02||||||||||||||||V100")
+
+	site, err := repo.Queries.GetSiteById(ctx, 1)
+	require.NoError(t, err)
+	v, err := repo.Queries.GetVisitBySiteIdNumber(ctx, database.GetVisitBySiteIdNumberParams{
+		SiteID: pgtype.Int4{Int32: site.ID, Valid: true},
+		Number: "V100",
+	})
+	require.NoError(t, err)
+	visit := entity.DBtoVisit(v)
+	require.Equal(t, "I", visit.Class)
+	require.Equal(t, entity.Location{PointOfCare: "3000", Room: "3010", Bed: "02"}, visit.Location)
+	require.Equal(t, time.Date(2025, time.April, 2, 16, 59, 0, 0, time.UTC), visit.Admitted)
+	require.Equal(t, time.Date(2025, time.April, 3, 13, 55, 0, 0, time.UTC), visit.Discharged)
+	require.True(t, v.AttendingPhysicianID.Valid)
+	require.True(t, v.ReferringPhysicianID.Valid)
+
+	// the A08 updated the patient the MRN already pointed to
+	patient, err := repo.Queries.GetPatientById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, "Jonathan", patient.FirstName)
+	_, err = repo.Queries.GetPatientById(ctx, 2)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestPatientMerge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	for _, msg := range []string{
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O1|P|2.3\r" +
+			"PID|1||100^^^MHS^MR||Doe^John||19800101|M\r" +
+			"ORC|NW|ACC1|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O2|P|2.3\r" +
+			"PID|1||200^^^MHS^MR||Doe^Johnny||19800101|M\r" +
+			"ORC|NW|ACC2|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+	} {
+		orm, exams := decodeORM(t, hl7.NewDecoder([]byte(msg)))
+		require.NoError(t, repo.SaveORM(ctx, orm.ToOrder(exams...)))
+	}
+
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402130000||ADT^A40|M1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"MRG|200^^^MHS^MR")
+	d := hl7.NewDecoder(data)
+	adt := &api.ADT{}
+	require.NoError(t, d.Decode(adt))
+	groups := []api.MergeGroup{}
+	require.NoError(t, d.Decode(&groups))
+	require.NoError(t, repo.SaveMerge(ctx, adt.ToMerge(groups...)))
+
+	exam1, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC1"})
+	require.NoError(t, err)
+	exam2, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	require.Equal(t, exam1.MrnID, exam2.MrnID)
+
+	merges, err := repo.ListPatientMerges(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	require.Equal(t, []int64{exam2.ID}, merges[0].ExamIDs)
+
+	m, err := repo.UnmergePatients(ctx, merges[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, m.UnmergedAt)
+	exam2, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	require.NotEqual(t, exam1.MrnID, exam2.MrnID)
+
+	_, err = repo.UnmergePatients(ctx, merges[0].ID)
+	require.ErrorIs(t, err, entity.ErrUnmergeUnsafe)
+}
+
+func TestPatientMerge_SharedVisit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	// both MRNs have visit V1, so the prior's visit stays put and its exam
+	// moves to the survivor's V1
+	for _, msg := range []string{
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O1|P|2.3\r" +
+			"PID|1||100^^^MHS^MR||Doe^John||19800101|M\r" +
+			"PV1|1|O|||||||||||||||||V1\r" +
+			"ORC|NW|ACC1|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O2|P|2.3\r" +
+			"PID|1||200^^^MHS^MR||Doe^Johnny||19800101|M\r" +
+			"PV1|1|O|||||||||||||||||V1\r" +
+			"ORC|NW|ACC2|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+	} {
+		orm, exams := decodeORM(t, hl7.NewDecoder([]byte(msg)))
+		require.NoError(t, repo.SaveORM(ctx, orm.ToOrder(exams...)))
+	}
+	exam2, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	priorVisitID, priorMrnID := exam2.VisitID, exam2.MrnID
+
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402130000||ADT^A40|M1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"MRG|200^^^MHS^MR")
+	d := hl7.NewDecoder(data)
+	adt := &api.ADT{}
+	require.NoError(t, d.Decode(adt))
+	groups := []api.MergeGroup{}
+	require.NoError(t, d.Decode(&groups))
+	require.NoError(t, repo.SaveMerge(ctx, adt.ToMerge(groups...)))
+
+	exam1, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC1"})
+	require.NoError(t, err)
+	exam2, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	require.Equal(t, exam1.MrnID, exam2.MrnID)
+	require.Equal(t, exam1.VisitID, exam2.VisitID)
+
+	merges, err := repo.ListPatientMerges(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	require.Empty(t, merges[0].ExamIDs)
+	require.Empty(t, merges[0].VisitIDs)
+	require.Equal(t, []int64{exam2.ID}, merges[0].RepointedExamIDs)
+	require.Equal(t, []int64{priorVisitID.Int64}, merges[0].RepointedVisitIDs)
+
+	_, err = repo.UnmergePatients(ctx, merges[0].ID)
+	require.NoError(t, err)
+	exam2, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	require.Equal(t, priorMrnID, exam2.MrnID)
+	require.Equal(t, priorVisitID, exam2.VisitID)
+}
+
+func TestPatientLink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	for _, msg := range []string{
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O1|P|2.3\r" +
+			"PID|1||100^^^MHS^MR||Doe^John||19800101|M\r" +
+			"ORC|NW|ACC1|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+		"MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402120000||ORM^O01|O2|P|2.3\r" +
+			"PID|1||200^^^MHS^MR||Doe^Johnny||19800101|M\r" +
+			"ORC|NW|ACC2|||||||20250402120000\r" +
+			"OBR|1|||CT1^CT HEAD",
+	} {
+		orm, exams := decodeORM(t, hl7.NewDecoder([]byte(msg)))
+		require.NoError(t, repo.SaveORM(ctx, orm.ToOrder(exams...)))
+	}
+
+	data := []byte("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250402130000||ADT^A24|L1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"PID|2||200^^^MHS^MR||Doe^Johnny")
+	d := hl7.NewDecoder(data)
+	adt := &api.ADT{}
+	require.NoError(t, d.Decode(adt))
+	groups := []api.MergeGroup{}
+	require.NoError(t, d.Decode(&groups))
+	require.NoError(t, repo.SaveMerge(ctx, adt.ToMerge(groups...)))
+
+	// a link moves nothing
+	exam1, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC1"})
+	require.NoError(t, err)
+	exam2, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{SendingApp: "STRIC", Accession: "ACC2"})
+	require.NoError(t, err)
+	require.NotEqual(t, exam1.MrnID, exam2.MrnID)
+
+	merges, err := repo.ListPatientMerges(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	require.Equal(t, entity.LinkEvent, merges[0].Event)
+	require.Equal(t, exam1.MrnID.Int64, merges[0].SurvivorMrnID)
+	require.Equal(t, exam2.MrnID.Int64, merges[0].PriorMrnID)
+	require.Empty(t, merges[0].ExamIDs)
+	require.Empty(t, merges[0].VisitIDs)
+	require.Empty(t, merges[0].MrnIDs)
+}
+
 func TestORMProcedure(t *testing.T) {
 	t.Parallel()
 
@@ -122,11 +728,8 @@ func TestORMProcedure(t *testing.T) {
 	require.NoError(t, err, "couldn't read test file at 5.hl7: %v", err)
 	require.Greater(t, len(data), 0, "file is empty")
 
-	orm := &api.ORM{}
-	d := hl7.NewDecoder(data)
-	err = d.Decode(orm)
-	require.NoError(t, err)
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	orm, exams := decodeORM(t, hl7.NewDecoder(data))
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	msg, err := repo.Queries.GetMessageByID(ctx, 1)
@@ -163,11 +766,8 @@ func TestExamTimestampSequence(t *testing.T) {
 	require.NoError(t, err, "couldn't read test file at 5.hl7: %v", err)
 	require.Greater(t, len(data), 0, "file is empty")
 
-	orm := &api.ORM{}
-	d := hl7.NewDecoder(data)
-	err = d.Decode(orm)
-	require.NoError(t, err)
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	orm, exams := decodeORM(t, hl7.NewDecoder(data))
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	fmt.Printf("finding exam with %s and '29737914'\n", orm.ReceivingApp)
@@ -189,9 +789,9 @@ func TestExamTimestampSequence(t *testing.T) {
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.End)
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.Cancelled)
 
-	orm.OrderDT = "20250404103000"
-	orm.OrderStatus = "IP"
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	exams[0].OrderDT = "20250404103000"
+	exams[0].OrderStatus = "IP"
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	res, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
@@ -212,9 +812,9 @@ func TestExamTimestampSequence(t *testing.T) {
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.End)
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.Cancelled)
 
-	orm.OrderDT = "20250404110000"
-	orm.OrderStatus = "CM"
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	exams[0].OrderDT = "20250404110000"
+	exams[0].OrderStatus = "CM"
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	res, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
@@ -235,9 +835,9 @@ func TestExamTimestampSequence(t *testing.T) {
 	assert.Equal(t, time.Date(2025, time.April, 4, 16, 0, 0, 0, time.UTC), exam.End)
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.Cancelled)
 
-	orm.OrderDT = "20250404100951"
-	orm.OrderStatus = "SC"
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	exams[0].OrderDT = "20250404100951"
+	exams[0].OrderStatus = "SC"
+	err = repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 
 	res, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
@@ -257,12 +857,32 @@ func TestExamTimestampSequence(t *testing.T) {
 	assert.Equal(t, time.Date(2025, time.April, 4, 15, 30, 0, 0, time.UTC), exam.Begin)
 	assert.Equal(t, time.Date(2025, time.April, 4, 16, 0, 0, 0, time.UTC), exam.End)
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.Cancelled)
+	assert.Equal(t, time.Date(2025, time.April, 4, 16, 0, 0, 0, time.UTC), res.LastEventDt.Time)
+
+	// the late SC was skipped, not applied
+	stale, err := repo.ListStaleExamUpdates(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, int64(1), stale[0].ExamID)
+	assert.Equal(t, "SC", stale[0].IncomingStatus)
+	assert.Equal(t, "CM", stale[0].CurrentStatus)
+	assert.Equal(t, time.Date(2025, time.April, 4, 15, 9, 51, 0, time.UTC), stale[0].IncomingEventDT)
+
+	history, err := repo.ExamStatusHistory(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, want := range []struct{ old, new string }{{"", "SC"}, {"SC", "IP"}, {"IP", "CM"}} {
+		assert.Equal(t, want.old, history[i].OldStatus)
+		assert.Equal(t, want.new, history[i].NewStatus)
+		assert.Equal(t, orm.SendingApp, history[i].SourceApp)
+	}
+	assert.Equal(t, time.Date(2025, time.April, 4, 15, 30, 0, 0, time.UTC), history[1].EventDT)
 
 	data, err = hl7.HL7.ReadFile("test_hl7/9.hl7")
 	require.NoError(t, err, "couldn't read test file at 9.hl7: %v", err)
 	require.Greater(t, len(data), 0, "file is empty")
 
-	d = hl7.NewDecoder(data)
+	d := hl7.NewDecoder(data)
 	testUpsertORU(t, ctx, repo, d)
 
 	res, err = repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
@@ -274,15 +894,22 @@ func TestExamTimestampSequence(t *testing.T) {
 	require.True(t, res.BeginExamDt.Valid)
 	require.True(t, res.EndExamDt.Valid)
 	require.False(t, res.ExamCancelledDt.Valid)
-	require.Equal(t, int64(1), res.FinalReportID.Int64)
+	reports, err := repo.ExamReports(ctx, res.ID)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, int64(1), reports[0].ReportID)
+	require.Equal(t, entity.RoleFinal, reports[0].Role)
 	exam = entity.DBtoExam(res)
 	require.Equal(t, 1, exam.ID)
 
 	assert.Equal(t, "CM", exam.CurrentStatus.String())
+	assert.Equal(t, "F", exam.ResultStatus.String())
 	assert.Equal(t, time.Date(2025, time.April, 4, 15, 9, 51, 0, time.UTC), exam.Scheduled)
 	assert.Equal(t, time.Date(2025, time.April, 4, 15, 30, 0, 0, time.UTC), exam.Begin)
 	assert.Equal(t, time.Date(2025, time.April, 4, 16, 0, 0, 0, time.UTC), exam.End)
 	assert.Equal(t, time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), exam.Cancelled)
+	// the result doesn't move the order's last event
+	assert.Equal(t, time.Date(2025, time.April, 4, 16, 0, 0, 0, time.UTC), res.LastEventDt.Time)
 
 	report, err := repo.Queries.GetAllReports(ctx)
 	require.NoError(t, err)
@@ -291,16 +918,95 @@ func TestExamTimestampSequence(t *testing.T) {
 	require.Equal(t, time.Date(2025, time.April, 4, 20, 25, 35, 0, time.UTC), report[0].DictationEnd.Time)
 }
 
+func TestResultBeforeOrderComplete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+
+	save := func(data string) {
+		t.Helper()
+		testUpsertORM(t, ctx, repo, hl7.NewDecoder([]byte(data)))
+	}
+	exam := func() database.GetExamBySendingAppAccessionRow {
+		t.Helper()
+		res, err := repo.Queries.GetExamBySendingAppAccession(ctx, database.GetExamBySendingAppAccessionParams{
+			SendingApp: "STRIC",
+			Accession:  "A1",
+		})
+		require.NoError(t, err)
+		return res
+	}
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501120000||ORM^O01|O1|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|NW|A1|||SC||||20250501120000\r" +
+		"OBR|1|A1||CT1^CT")
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501123000||ORM^O01|O2|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|SC|A1|||IP||||20250501123000\r" +
+		"OBR|1|A1||CT1^CT")
+	// the final report arrives before the ORM that completes the exam
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("R1", "F", "20250501140000", "Normal.", "A1"))))
+
+	res := exam()
+	assert.Equal(t, "IP", res.CurrentStatus)
+	assert.Equal(t, "F", res.ResultStatus)
+	assert.Equal(t, time.Date(2025, time.May, 1, 17, 30, 0, 0, time.UTC), res.LastEventDt.Time)
+	assert.Equal(t, time.Date(2025, time.May, 1, 19, 0, 0, 0, time.UTC), res.ResultEventDt.Time)
+
+	// the exam ended at 13:00, before the report, but the ORM was held up
+	save("MSH|^~\\&|EPIC|MHS|STRIC|MHS|20250501140500||ORM^O01|O3|P|2.3\r" +
+		"PID|1||100^^^MHS^MR||Doe^John\r" +
+		"ORC|SC|A1|||CM||||20250501130000\r" +
+		"OBR|1|A1||CT1^CT")
+
+	res = exam()
+	assert.Equal(t, "CM", res.CurrentStatus)
+	assert.Equal(t, "F", res.ResultStatus)
+	require.True(t, res.EndExamDt.Valid)
+	assert.Equal(t, time.Date(2025, time.May, 1, 18, 0, 0, 0, time.UTC), res.EndExamDt.Time)
+	assert.Equal(t, time.Date(2025, time.May, 1, 18, 0, 0, 0, time.UTC), res.LastEventDt.Time)
+
+	stale, err := repo.ListStaleExamUpdates(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	// a prelim after the final is an illegal result transition
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("R2", "P", "20250501150000", "Normal.", "A1"))))
+	res = exam()
+	assert.Equal(t, "CM", res.CurrentStatus)
+	assert.Equal(t, "F", res.ResultStatus)
+	stale, err = repo.ListStaleExamUpdates(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "P", stale[0].IncomingStatus)
+	assert.Equal(t, "F", stale[0].CurrentStatus)
+	assert.Equal(t, entity.SkipIllegalTransition, stale[0].Reason)
+
+	history, err := repo.ExamStatusHistory(ctx, res.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "CM", history[2].NewStatus)
+}
+
 func testUpsertORM(t *testing.T, ctx context.Context, repo *entity.HL7Repo, d *hl7.Decoder) {
 	t.Helper()
 
-	orm := &api.ORM{}
-	err := d.Decode(orm)
-	require.NoError(t, err)
-	err = repo.SaveORM(ctx, orm.ToOrder())
+	orm, exams := decodeORM(t, d)
+	err := repo.SaveORM(ctx, orm.ToOrder(exams...))
 	require.NoError(t, err)
 }
 
+func decodeORM(t *testing.T, d *hl7.Decoder) (*api.ORM, []api.Exam) {
+	t.Helper()
+
+	orm := &api.ORM{}
+	require.NoError(t, d.Decode(orm))
+	exams := []api.Exam{}
+	require.NoError(t, d.Decode(&exams))
+	return orm, exams
+}
+
 func testUpsertORU(t *testing.T, ctx context.Context, repo *entity.HL7Repo, d *hl7.Decoder) {
 	t.Helper()
 
@@ -317,6 +1023,120 @@ func testUpsertORU(t *testing.T, ctx context.Context, repo *entity.HL7Repo, d *h
 	require.NoError(t, err)
 }
 
+func testUpsertADT(t *testing.T, ctx context.Context, repo *entity.HL7Repo, d *hl7.Decoder) {
+	t.Helper()
+
+	adt := &api.ADT{}
+	err := d.Decode(adt)
+	require.NoError(t, err)
+	err = repo.SaveADT(ctx, adt.ToAdmission())
+	require.NoError(t, err)
+}
+
+func testUpsertSIU(t *testing.T, ctx context.Context, repo *entity.HL7Repo, d *hl7.Decoder) {
+	t.Helper()
+
+	siu := &api.SIU{}
+	err := d.Decode(siu)
+	require.NoError(t, err)
+	err = repo.SaveSIU(ctx, siu.ToAppointment())
+	require.NoError(t, err)
+}
+
+type capturingNotifier struct {
+	name string
+	got  []entity.CriticalResult
+	err  error
+}
+
+func (n *capturingNotifier) Name() string { return n.name }
+
+func (n *capturingNotifier) NotifyCritical(ctx context.Context, c entity.CriticalResult) error {
+	n.got = append(n.got, c)
+	return n.err
+}
+
+func TestCriticalResults(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _ := setupDB(t, ctx)
+	notifier := &capturingNotifier{name: "log"}
+	pager := &capturingNotifier{name: "pager"}
+	repo = entity.NewRepo(repo.DB, entity.WithCriticalNotifiers(notifier, pager))
+
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("C1", "P", "20250501120000", "Large right pneumothorax. No free air.", "A1"))))
+	// the final says the same thing, so there's nothing new to notify
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("C2", "F", "20250501130000", "Large right pneumothorax. No free air.", "A1"))))
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("C3", "F", "20250501140000", "No acute disease.", "A2"))))
+
+	// nothing is sent while the reports are saved
+	require.Empty(t, notifier.got)
+	sent, err := repo.NotifyPendingCritical(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	sent, err = repo.NotifyPendingCritical(ctx)
+	require.NoError(t, err)
+	require.Zero(t, sent)
+
+	require.Len(t, notifier.got, 1)
+	c := notifier.got[0]
+	examID, err := repo.ExamID(ctx, "STRIC", "A1")
+	require.NoError(t, err)
+	require.Equal(t, examID, c.ExamID)
+	require.Equal(t, "A1", c.Accession)
+	require.Equal(t, "pneumothorax", c.Rule)
+	require.Equal(t, "Large right pneumothorax.", c.Matched)
+	require.Equal(t, "Rad, Ray", c.Radiologist)
+
+	open, err := repo.ListCriticalResults(ctx, "open", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	require.NotNil(t, open[0].NotifiedAt)
+	require.Equal(t, []string{"log", "pager"}, open[0].NotifiedBy)
+
+	acked, err := repo.AcknowledgeCriticalResult(ctx, c.ID, "dr.jones")
+	require.NoError(t, err)
+	require.Equal(t, "dr.jones", acked.AcknowledgedBy)
+	require.NotNil(t, acked.AcknowledgedAt)
+	acked, err = repo.AcknowledgeCriticalResult(ctx, c.ID, "dr.lee")
+	require.ErrorIs(t, err, entity.ErrAlreadyAcknowledged)
+	require.Equal(t, "dr.jones", acked.AcknowledgedBy)
+	_, err = repo.AcknowledgeCriticalResult(ctx, c.ID+100, "dr.lee")
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	open, err = repo.ListCriticalResults(ctx, "open", 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, open)
+
+	// a notifier that fails keeps its error, and the result is sent to it
+	// again, but not to the notifier that already has it
+	pager.err = fmt.Errorf("pager down")
+	testUpsertORU(t, ctx, repo, hl7.NewDecoder([]byte(oruMessage("C4", "F", "20250501150000", "Acute aortic dissection.", "A3"))))
+	sent, err = repo.NotifyPendingCritical(ctx)
+	require.NoError(t, err)
+	require.Zero(t, sent)
+	open, err = repo.ListCriticalResults(ctx, "open", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	require.Equal(t, "aortic_dissection", open[0].Rule)
+	require.Nil(t, open[0].NotifiedAt)
+	require.Equal(t, "pager: pager down", open[0].NotifyError)
+	require.Equal(t, []string{"log"}, open[0].NotifiedBy)
+
+	pager.err = nil
+	sent, err = repo.NotifyPendingCritical(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.Len(t, notifier.got, 2)
+	require.Len(t, pager.got, 3)
+	open, err = repo.ListCriticalResults(ctx, "open", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	require.NotNil(t, open[0].NotifiedAt)
+	require.Empty(t, open[0].NotifyError)
+}
+
 func setupDB(t *testing.T, ctx context.Context) (*entity.HL7Repo, []fs.DirEntry) {
 	t.Helper()
 
@@ -364,3 +1184,15 @@ func setupDB(t *testing.T, ctx context.Context) (*entity.HL7Repo, []fs.DirEntry)
 
 	return entity.NewRepo(pool), hl7Messages
 }
+
+// oruMessage is a one-OBX ORU for a report covering the accessions.
+func oruMessage(controlID, status, submitted, impression string, accessions ...string) string {
+	msg := fmt.Sprintf("MSH|^~\\&|PS|MHS|STRIC|MHS|%s||ORU^R01|%s|P|2.3\r"+
+		"PID|1||100^^^MHS^MR||Doe^John\r", submitted, controlID)
+	for i, accession := range accessions {
+		msg += fmt.Sprintf("ORC|RE|%s|||CM\r"+
+			"OBR|%d|%s||CT%d^CT|||||||||||||||||||||%s|||||||1234&Rad&Ray\r",
+			accession, i+1, accession, i+1, status)
+	}
+	return msg + fmt.Sprintf("OBX|1|TX|IMP||%s||||||%s|||%s", impression, status, submitted)
+}
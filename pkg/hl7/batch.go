@@ -0,0 +1,44 @@
+package hl7
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SplitBatch splits an HL7 batch file into its messages. The FHS/BHS/BTS/FTS
+// envelope is dropped, so a bare message (or several concatenated) works as
+// well. Segments may end in \r, \n or \r\n; returned messages use \r.
+// Blank lines are skipped, but other segments are kept byte for byte, so
+// trailing spaces in a field survive.
+func SplitBatch(data []byte) ([][]byte, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\r"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r"))
+
+	var msgs [][]byte
+	var cur []byte
+	for _, seg := range bytes.Split(data, []byte("\r")) {
+		trimmed := bytes.TrimSpace(seg)
+		if len(trimmed) == 0 {
+			continue
+		}
+		switch name := string(trimmed[:min(3, len(trimmed))]); name {
+		case "FHS", "BHS", "BTS", "FTS":
+			continue
+		case "MSH":
+			if cur != nil {
+				msgs = append(msgs, cur)
+			}
+			cur = append([]byte{}, seg...)
+		default:
+			if cur == nil {
+				return nil, &ParseError{Segment: name, Err: fmt.Errorf("segment before first MSH")}
+			}
+			cur = append(cur, '\r')
+			cur = append(cur, seg...)
+		}
+	}
+	if cur != nil {
+		msgs = append(msgs, cur)
+	}
+	return msgs, nil
+}
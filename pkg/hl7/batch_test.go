@@ -0,0 +1,39 @@
+package hl7
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBatch(t *testing.T) {
+	batch := "FHS|^~\\&|RIS\r\n" +
+		"BHS|^~\\&|RIS\r\n" +
+		"MSH|^~\\&|RIS|FAC|||20250401||ORM^O01|1|P|2.3\r\n" +
+		"PID|1||MRN1\r\n" +
+		"MSH|^~\\&|RIS|FAC|||20250401||ORU^R01|2|P|2.3\n" +
+		"PID|1||MRN2\n" +
+		"OBX|1|TX|||text\n" +
+		"BTS|2\r\n" +
+		"FTS|1\r\n"
+	msgs, err := SplitBatch([]byte(batch))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{
+		[]byte("MSH|^~\\&|RIS|FAC|||20250401||ORM^O01|1|P|2.3\rPID|1||MRN1"),
+		[]byte("MSH|^~\\&|RIS|FAC|||20250401||ORU^R01|2|P|2.3\rPID|1||MRN2\rOBX|1|TX|||text"),
+	}, msgs)
+
+	msgs, err = SplitBatch([]byte("MSH|^~\\&|RIS\rPID|1"))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	// blank lines go, but whitespace at the end of a field stays
+	msgs, err = SplitBatch([]byte("MSH|^~\\&|RIS\r\n  \r\nOBX|1|TX|||Impression:  \t\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("MSH|^~\\&|RIS\rOBX|1|TX|||Impression:  \t")}, msgs)
+
+	_, err = SplitBatch([]byte("PID|1||MRN1\rMSH|^~\\&|RIS"))
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+}
@@ -11,6 +11,23 @@ const messageHeader = "MSH"
 
 var DefaultSegDelim = byte('\r')
 
+// ParseError is returned when data can't be read as an HL7 message.
+type ParseError struct {
+	Segment string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Segment == "" {
+		return "hl7: " + e.Err.Error()
+	}
+	return fmt.Sprintf("hl7: segment %q: %v", e.Segment, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 type Decoder struct {
 	data     []byte
 	segments []*segment // key is zero-based idx of segment
@@ -25,7 +42,7 @@ func NewDecoder(data []byte) *Decoder {
 
 func (d *Decoder) init(data []byte, segDelim byte) {
 	if len(data) < 8 {
-		d.savedErr = fmt.Errorf("message is too short (length: %d)", len(data))
+		d.savedErr = &ParseError{Err: fmt.Errorf("message is too short (length: %d)", len(data))}
 		return
 	}
 	d.data = data
@@ -47,6 +64,9 @@ func Unmarshal(data []byte, v any) error {
 }
 
 func (d *Decoder) Decode(v any) error {
+	if d.savedErr != nil {
+		return d.savedErr
+	}
 	val := reflect.ValueOf(v)
 	if val.Kind() != reflect.Pointer || val.IsNil() {
 		return fmt.Errorf("hl7: Decode(non-pointer)")
@@ -96,6 +116,25 @@ func (d *Decoder) decodeStruct(val reflect.Value, repeatIdx int) error {
 	return nil
 }
 
+// Groups splits the message at each name segment, e.g. "ORC" for the order
+// groups of an ORM or OMI. A group's decoder sees that segment and the ones
+// after it up to the next, so repeats are counted within the group and an
+// optional segment missing from one group isn't taken from the next.
+// Segments before the first name segment are in no group.
+func (d *Decoder) Groups(name string) []*Decoder {
+	var groups []*Decoder
+	for _, seg := range d.segments {
+		if seg.name == name {
+			groups = append(groups, &Decoder{data: d.data})
+		}
+		if len(groups) > 0 {
+			g := groups[len(groups)-1]
+			g.segments = append(g.segments, seg)
+		}
+	}
+	return groups
+}
+
 // n is the "nth" segment repeat
 func (d *Decoder) getFieldValue(seg string, idx, rep int) string {
 	if seg == messageHeader {
@@ -140,6 +140,41 @@ func TestNewDecoder(t *testing.T) {
 	require.Equal(t, wantOrders, orders)
 }
 
+func TestDecoder_Groups(t *testing.T) {
+	data := []byte("MSH|^~\\&|RIS|MHS\r" +
+		"PID|1||100\r" +
+		"ORC|NW|A1\r" +
+		"OBR|1|A1\r" +
+		"ORC|NW|A2\r" +
+		"TQ1|1||||||20250502083000\r" +
+		"OBR|2|A2")
+	type timing struct {
+		Placer  string `hl7:"ORC.2"`
+		StartDT string `hl7:"TQ1.7"`
+	}
+
+	groups := NewDecoder(data).Groups("ORC")
+	require.Len(t, groups, 2)
+	got := make([]timing, len(groups))
+	for i, g := range groups {
+		require.NoError(t, g.Decode(&got[i]))
+	}
+	// the first group has no TQ1, so it doesn't get the second's
+	require.Equal(t, []timing{{Placer: "A1"}, {Placer: "A2", StartDT: "20250502083000"}}, got)
+}
+
+func TestDecode_ParseError(t *testing.T) {
+	var pe *ParseError
+
+	err := NewDecoder([]byte("MSH|^~")).Decode(&orderGroup{})
+	require.ErrorAs(t, err, &pe)
+	require.Equal(t, "", pe.Segment)
+
+	err = Unmarshal([]byte("MSH|^~\\&|LabSystem\rOBXX|1|FT"), &mockObservation{})
+	require.ErrorAs(t, err, &pe)
+	require.Equal(t, "OBXX", pe.Segment)
+}
+
 func BenchmarkDecoderEach(b *testing.B) {
 	entries, err := HL7.ReadDir("test_hl7")
 	if err != nil {
@@ -1,6 +1,9 @@
 package hl7
 
-import "strings"
+import (
+	"encoding/hex"
+	"strings"
+)
 
 var escMap = map[string]string{
 	"\\.br\\": "\r",
@@ -43,3 +46,31 @@ func escaped(s string) string {
 	}
 	return esc
 }
+
+// UnescapeHex replaces each \Xhh...\ escape in s with the bytes it encodes.
+// The decoder leaves these as they are (apart from line breaks), since the
+// bytes needn't be valid text; use it only on fields that hold binary data,
+// like an ED's data. Odd-length or non-hex runs are left alone.
+func UnescapeHex(s string) []byte {
+	var ret []byte
+	for len(s) > 0 {
+		startIdx := strings.Index(s, "\\X")
+		if startIdx == -1 {
+			break
+		}
+		endIdx := strings.Index(s[startIdx+2:], "\\")
+		if endIdx == -1 {
+			break
+		}
+		endIdx += startIdx + 2
+
+		ret = append(ret, s[:startIdx]...)
+		if b, err := hex.DecodeString(s[startIdx+2 : endIdx]); err == nil && endIdx > startIdx+2 {
+			ret = append(ret, b...)
+		} else {
+			ret = append(ret, s[startIdx:endIdx+1]...)
+		}
+		s = s[endIdx+1:]
+	}
+	return append(ret, s...)
+}
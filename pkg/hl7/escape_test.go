@@ -23,3 +23,19 @@ func TestReplaceEscapes(t *testing.T) {
 	got = replaceEscapes(s)
 	require.Equal(t, "unrecognized escape \\O\\", got)
 }
+
+func TestReplaceEscapes_Hex(t *testing.T) {
+	// only line breaks are decoded; other bytes needn't be valid text
+	require.Equal(t, "line\r\n", replaceEscapes("line\\X0D\\\\X0A\\"))
+	require.Equal(t, "caf\\XE9\\", replaceEscapes("caf\\XE9\\"))
+	require.Equal(t, "nul \\X00\\", replaceEscapes("nul \\X00\\"))
+}
+
+func TestUnescapeHex(t *testing.T) {
+	require.Equal(t, []byte("{\\rtf1}\r\n"), UnescapeHex("{\\X5C\\rtf1}\\X0D0A\\"))
+	require.Equal(t, []byte("caf\xe9"), UnescapeHex("caf\\XE9\\"))
+	require.Equal(t, []byte("no escapes"), UnescapeHex("no escapes"))
+	// odd-length or non-hex runs are left alone
+	require.Equal(t, []byte("\\XABC\\"), UnescapeHex("\\XABC\\"))
+	require.Equal(t, []byte("\\XZZ\\ and A"), UnescapeHex("\\XZZ\\ and \\X41\\"))
+}
@@ -24,7 +24,7 @@ func FastScan(data []byte, segDelim, fldDelim byte) ([]*segment, error) {
 		line := data[start:end]
 		fields := bytes.Split(line, []byte{fldDelim})
 		if len(fields) == 0 || len(fields[0]) != 3 {
-			return nil, fmt.Errorf("invalid segment: %s", fields[0])
+			return nil, &ParseError{Segment: string(fields[0]), Err: fmt.Errorf("invalid segment name")}
 		}
 		seg := &segment{name: string(fields[0]), endIdx: end}
 		offset := start + len(fields[0]) + 1
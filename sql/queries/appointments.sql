@@ -0,0 +1,87 @@
+-- name: CreateAppointment :one
+WITH upsert AS (
+    INSERT INTO appointments (
+        message_id, -- $1
+        site_id, -- $2
+        mrn_id, -- $3
+        exam_id, -- $4
+        sending_app, -- $5
+        appointment_id, -- $6
+        accession, -- $7
+        start_dt, -- $8
+        duration_minutes, -- $9
+        resource, -- $10
+        room, -- $11
+        status, -- $12
+        no_show, -- $13
+        last_event, -- $14
+        last_event_dt -- $15
+    )
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+    ON CONFLICT (sending_app, appointment_id) DO UPDATE
+    SET
+        updated_at = CURRENT_TIMESTAMP,
+        message_id = EXCLUDED.message_id,
+        site_id = EXCLUDED.site_id,
+        mrn_id = COALESCE(EXCLUDED.mrn_id, appointments.mrn_id),
+        exam_id = COALESCE(EXCLUDED.exam_id, appointments.exam_id),
+        accession = COALESCE(NULLIF(EXCLUDED.accession, ''), appointments.accession),
+        start_dt = COALESCE(EXCLUDED.start_dt, appointments.start_dt),
+        duration_minutes = COALESCE(EXCLUDED.duration_minutes, appointments.duration_minutes),
+        resource = COALESCE(NULLIF(EXCLUDED.resource, ''), appointments.resource),
+        room = COALESCE(NULLIF(EXCLUDED.room, ''), appointments.room),
+        status = EXCLUDED.status,
+        no_show = EXCLUDED.no_show,
+        last_event = EXCLUDED.last_event,
+        last_event_dt = EXCLUDED.last_event_dt
+    WHERE
+        appointments.last_event_dt IS NULL
+        OR EXCLUDED.last_event_dt IS NULL
+        OR EXCLUDED.last_event_dt >= appointments.last_event_dt
+    RETURNING id
+)
+SELECT id FROM upsert
+UNION ALL
+SELECT id FROM appointments
+WHERE
+    sending_app = $5
+    AND appointment_id = $6
+    AND NOT EXISTS (SELECT 1 FROM upsert);
+
+-- name: GetAppointmentMetricsBySite :many
+SELECT
+    s.code AS site_code,
+    COUNT(*) FILTER (WHERE a.status NOT IN ('Cancelled', 'Deleted')) AS scheduled,
+    COUNT(*) FILTER (
+        WHERE e.end_exam_dt IS NOT NULL
+        OR e.current_status IN ('CM', 'A')
+        OR e.result_status IN ('A', 'R', 'P', 'F', 'C')
+    ) AS performed,
+    COUNT(*) FILTER (WHERE a.no_show) AS no_shows,
+    COUNT(*) FILTER (WHERE a.status = 'Cancelled') AS cancelled
+FROM appointments AS a
+JOIN sites AS s ON a.site_id = s.id
+LEFT JOIN exams AS e ON a.exam_id = e.id
+WHERE
+    a.start_dt >= @start_from
+    AND a.start_dt < @start_to
+GROUP BY s.code
+ORDER BY s.code;
+
+-- name: GetAppointmentsBySendingAppAccession :many
+SELECT *
+FROM appointments
+WHERE
+    sending_app = $1
+    AND accession = $2
+ORDER BY id;
+
+-- name: LinkAppointmentsToExam :exec
+UPDATE appointments
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    exam_id = @exam_id
+WHERE
+    sending_app = @sending_app
+    AND accession = @accession
+    AND exam_id IS NULL;
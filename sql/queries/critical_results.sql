@@ -0,0 +1,97 @@
+-- name: CreateCriticalResult :one
+INSERT INTO critical_results (
+    report_id,
+    exam_id,
+    radiologist_id,
+    rule,
+    matched,
+    message_id
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (exam_id, rule) DO NOTHING
+RETURNING id;
+
+-- name: ClaimCriticalResults :many
+WITH claimed AS (
+    SELECT id
+    FROM critical_results
+    WHERE
+        id > @cursor_id::bigint
+        AND notified_at IS NULL
+        AND acknowledged_at IS NULL
+        AND (notify_claimed_until IS NULL OR notify_claimed_until < CURRENT_TIMESTAMP)
+    ORDER BY id
+    LIMIT @page_size::int
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE critical_results AS cr
+SET notify_claimed_until = CURRENT_TIMESTAMP + make_interval(secs => @claim_seconds::int)
+FROM claimed
+WHERE cr.id = claimed.id
+RETURNING cr.id, cr.notified_by;
+
+-- name: SetCriticalResultNotified :exec
+UPDATE critical_results
+SET
+    notified_by = @notified_by::text[],
+    notified_at = CASE WHEN @notify_error::text = '' THEN CURRENT_TIMESTAMP END,
+    notify_error = @notify_error,
+    notify_claimed_until = NULL
+WHERE id = @id;
+
+-- name: AcknowledgeCriticalResult :execrows
+UPDATE critical_results
+SET
+    acknowledged_at = CURRENT_TIMESTAMP,
+    acknowledged_by = @acknowledged_by
+WHERE
+    id = @id
+    AND acknowledged_at IS NULL;
+
+-- name: GetCriticalResult :one
+SELECT
+    cr.id,
+    cr.created_at,
+    cr.report_id,
+    cr.exam_id,
+    e.sending_app,
+    e.accession,
+    COALESCE(p.first_name, '')::text AS radiologist_first_name,
+    COALESCE(p.last_name, '')::text AS radiologist_last_name,
+    cr.rule,
+    cr.matched,
+    cr.notified_at,
+    cr.notify_error,
+    cr.notified_by,
+    cr.acknowledged_at,
+    cr.acknowledged_by
+FROM critical_results cr
+JOIN exams e ON e.id = cr.exam_id
+LEFT JOIN physicians p ON p.id = cr.radiologist_id
+WHERE cr.id = @id;
+
+-- name: ListCriticalResults :many
+SELECT
+    cr.id,
+    cr.created_at,
+    cr.report_id,
+    cr.exam_id,
+    e.sending_app,
+    e.accession,
+    COALESCE(p.first_name, '')::text AS radiologist_first_name,
+    COALESCE(p.last_name, '')::text AS radiologist_last_name,
+    cr.rule,
+    cr.matched,
+    cr.notified_at,
+    cr.notify_error,
+    cr.notified_by,
+    cr.acknowledged_at,
+    cr.acknowledged_by
+FROM critical_results cr
+JOIN exams e ON e.id = cr.exam_id
+LEFT JOIN physicians p ON p.id = cr.radiologist_id
+WHERE
+    cr.id > @cursor_id::bigint
+    AND (@status::text = '' OR (cr.acknowledged_at IS NOT NULL) = (@status::text = 'acknowledged'))
+ORDER BY cr.id
+LIMIT @page_size::int;
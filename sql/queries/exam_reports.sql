@@ -0,0 +1,44 @@
+-- name: CreateExamReport :exec
+INSERT INTO exam_reports (
+    exam_id,
+    report_id,
+    role,
+    sequence,
+    message_id
+)
+SELECT @exam_id::bigint, @report_id::bigint, @role::text, COUNT(*) + 1, @message_id::bigint
+FROM exam_reports
+WHERE
+    exam_id = @exam_id
+    AND role = @role
+ON CONFLICT (exam_id, report_id) DO NOTHING;
+
+-- name: ListExamReports :many
+SELECT
+    er.exam_id,
+    e.accession,
+    er.report_id,
+    er.role,
+    er.sequence,
+    r.report_status,
+    r.submitted_dt
+FROM exam_reports er
+JOIN exams e ON e.id = er.exam_id
+JOIN reports r ON r.id = er.report_id
+WHERE er.exam_id = @exam_id
+ORDER BY r.submitted_dt, r.id;
+
+-- name: ListReportExams :many
+SELECT
+    er.exam_id,
+    e.accession,
+    er.report_id,
+    er.role,
+    er.sequence,
+    r.report_status,
+    r.submitted_dt
+FROM exam_reports er
+JOIN exams e ON e.id = er.exam_id
+JOIN reports r ON r.id = er.report_id
+WHERE er.report_id = @report_id
+ORDER BY e.accession;
@@ -0,0 +1,16 @@
+-- name: CreateExamStatusHistory :exec
+INSERT INTO exam_status_history (
+    exam_id,
+    old_status,
+    new_status,
+    event_dt,
+    message_id,
+    source_app
+)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListExamStatusHistory :many
+SELECT *
+FROM exam_status_history
+WHERE exam_id = $1
+ORDER BY event_dt, id;
@@ -14,9 +14,10 @@ WITH upsert as (
         exam_cancelled_dt, -- $11
         message_id, -- $12
 	sending_app, -- $13
-	priority -- $14
+	priority, -- $14
+        last_event_dt -- $15
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
     ON CONFLICT (sending_app, accession) DO UPDATE
     SET
         updated_at = CURRENT_TIMESTAMP,
@@ -30,18 +31,28 @@ WITH upsert as (
         begin_exam_dt = COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt),
         end_exam_dt = COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt),
         exam_cancelled_dt = COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt),
-        message_id = EXCLUDED.message_id
+        message_id = EXCLUDED.message_id,
+        last_event_dt = GREATEST(EXCLUDED.last_event_dt, exams.last_event_dt)
     WHERE
-        exams.visit_id IS DISTINCT FROM EXCLUDED.visit_id
-        OR exams.mrn_id IS DISTINCT FROM EXCLUDED.mrn_id
-        OR exams.site_id IS DISTINCT FROM EXCLUDED.site_id
-        OR exams.procedure_id IS DISTINCT FROM EXCLUDED.procedure_id
-        OR exams.ordering_physician_id IS DISTINCT FROM EXCLUDED.ordering_physician_id
-        OR COALESCE(NULLIF(EXCLUDED.current_status, ''), exams.current_status) IS DISTINCT FROM exams.current_status
-        OR COALESCE(EXCLUDED.schedule_dt, exams.schedule_dt) IS DISTINCT FROM exams.schedule_dt
-        OR COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt) IS DISTINCT FROM exams.begin_exam_dt
-        OR COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt) IS DISTINCT FROM exams.end_exam_dt
-        OR COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt) IS DISTINCT FROM exams.exam_cancelled_dt
+        -- never let an older message overwrite a newer one
+        (
+            exams.last_event_dt IS NULL
+            OR EXCLUDED.last_event_dt IS NULL
+            OR EXCLUDED.last_event_dt >= exams.last_event_dt
+        )
+        AND (
+            exams.visit_id IS DISTINCT FROM EXCLUDED.visit_id
+            OR exams.mrn_id IS DISTINCT FROM EXCLUDED.mrn_id
+            OR exams.site_id IS DISTINCT FROM EXCLUDED.site_id
+            OR exams.procedure_id IS DISTINCT FROM EXCLUDED.procedure_id
+            OR exams.ordering_physician_id IS DISTINCT FROM EXCLUDED.ordering_physician_id
+            OR COALESCE(NULLIF(EXCLUDED.current_status, ''), exams.current_status) IS DISTINCT FROM exams.current_status
+            OR COALESCE(EXCLUDED.schedule_dt, exams.schedule_dt) IS DISTINCT FROM exams.schedule_dt
+            OR COALESCE(EXCLUDED.begin_exam_dt, exams.begin_exam_dt) IS DISTINCT FROM exams.begin_exam_dt
+            OR COALESCE(EXCLUDED.end_exam_dt, exams.end_exam_dt) IS DISTINCT FROM exams.end_exam_dt
+            OR COALESCE(EXCLUDED.exam_cancelled_dt, exams.exam_cancelled_dt) IS DISTINCT FROM exams.exam_cancelled_dt
+            OR EXCLUDED.last_event_dt IS DISTINCT FROM exams.last_event_dt
+        )
     RETURNING id
 )
 SELECT id FROM upsert
@@ -67,6 +78,13 @@ WHERE
     sending_app = $1
     AND accession = $2;
 
+-- name: GetExamEventBySendingAppAccession :one
+SELECT id, current_status, last_event_dt, result_status, result_event_dt
+FROM exams
+WHERE
+    sending_app = $1
+    AND accession = $2;
+
 -- name: GetExamBySendingAppAccession :one
 SELECT
     e.*,
@@ -121,26 +139,70 @@ SET
 WHERE id = $1
 RETURNING *;
 
--- name: UpdateExamFinalReport :one
+-- name: UpdateExamResultStatus :exec
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    final_report_id = $2
-WHERE id = $1
-RETURNING *;
+    result_status = @result_status,
+    result_event_dt = GREATEST(@event_dt::timestamp, result_event_dt)
+WHERE id = @id;
 
--- name: UpdateExamAddendumReport :one
+-- name: UpdateExamStatus :exec
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    addendum_report_id = $2
-WHERE id = $1
-RETURNING *;
+    current_status = @current_status,
+    last_event_dt = GREATEST(@event_dt::timestamp, last_event_dt)
+WHERE id = @id;
 
--- name: UpdateExamPrelimReport :one
+-- name: MoveExamsToMrn :many
 UPDATE exams
 SET
     updated_at = CURRENT_TIMESTAMP,
-    prelim_report_id = $2
-WHERE id = $1
-RETURNING *;
+    mrn_id = @to_mrn_id
+WHERE mrn_id = @from_mrn_id
+RETURNING id;
+
+-- name: MoveExamsToMrnByIds :execrows
+UPDATE exams
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = @to_mrn_id
+WHERE
+    id = ANY(@ids::bigint[])
+    AND mrn_id = @from_mrn_id;
+
+-- name: MoveExamsToMrnVisits :many
+UPDATE exams AS e
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = @to_mrn_id,
+    visit_id = w.id
+FROM visits AS v, visits AS w
+WHERE
+    e.mrn_id = @from_mrn_id
+    AND e.visit_id = v.id
+    AND v.mrn_id = @from_mrn_id
+    AND w.site_id = v.site_id
+    AND w.mrn_id = @to_mrn_id
+    AND w.number = v.number
+RETURNING e.id, v.id AS prior_visit_id;
+
+-- name: MoveExamsToVisitsByIds :execrows
+UPDATE exams AS e
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = @to_mrn_id,
+    visit_id = r.visit_id
+FROM unnest(@ids::bigint[], @visit_ids::bigint[]) AS r (id, visit_id)
+WHERE
+    e.id = r.id
+    AND e.mrn_id = @from_mrn_id;
+
+-- name: UpdateExamOrderDetails :exec
+UPDATE exams
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    replaced_accession = COALESCE(NULLIF(@replaced_accession::text, ''), replaced_accession),
+    study_instance_uid = COALESCE(NULLIF(@study_instance_uid::text, ''), study_instance_uid)
+WHERE id = @id;
@@ -0,0 +1,58 @@
+-- name: CreateFailedMessage :one
+INSERT INTO failed_messages (
+    store_path,
+    raw,
+    raw_hash,
+    control_id,
+    error_category,
+    error_message
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (raw_hash) WHERE status = 'pending' DO UPDATE
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    store_path = COALESCE(NULLIF(EXCLUDED.store_path, ''), failed_messages.store_path),
+    error_category = EXCLUDED.error_category,
+    error_message = EXCLUDED.error_message,
+    attempts = failed_messages.attempts + 1,
+    last_failed_at = CURRENT_TIMESTAMP
+RETURNING id;
+
+-- name: DiscardFailedMessage :execrows
+UPDATE failed_messages
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    status = 'discarded'
+WHERE
+    id = $1
+    AND status = 'pending';
+
+-- name: GetFailedMessageByID :one
+SELECT *
+FROM failed_messages
+WHERE id = $1;
+
+-- name: ListFailedMessages :many
+SELECT *
+FROM failed_messages
+WHERE
+    status = $1
+    AND id > $2
+ORDER BY id
+LIMIT $3;
+
+-- name: ResolveFailedMessage :execrows
+UPDATE failed_messages
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    status = 'replayed'
+WHERE
+    raw_hash = $1
+    AND status = 'pending';
+
+-- name: UpdateFailedMessageStatus :exec
+UPDATE failed_messages
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    status = $2
+WHERE id = $1;
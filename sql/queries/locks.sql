@@ -0,0 +1,2 @@
+-- name: AcquireAdvisoryXactLock :exec
+SELECT pg_advisory_xact_lock(hashtextextended(@key::text, 0));
@@ -28,3 +28,46 @@ FROM mrns
 WHERE
     site_id = $1
     AND patient_id = $2;
+
+-- name: GetMrnBySiteValue :one
+SELECT *
+FROM mrns
+WHERE
+    site_id = $1
+    AND mrn = $2
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: MoveMrnsToPatient :many
+UPDATE mrns AS m
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_id = @to_patient_id
+WHERE
+    m.patient_id = @from_patient_id
+    AND m.id <> @keep_id
+    AND NOT EXISTS (
+        SELECT 1 FROM mrns AS x
+        WHERE
+            x.site_id = m.site_id
+            AND x.patient_id = @to_patient_id
+    )
+RETURNING m.id;
+
+-- name: MoveMrnsToPatientByIds :execrows
+UPDATE mrns
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_id = @to_patient_id
+WHERE
+    id = ANY(@ids::bigint[])
+    AND patient_id = @from_patient_id;
+
+-- name: RenameMrn :execrows
+UPDATE mrns
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn = @new_mrn
+WHERE
+    id = @id
+    AND mrn = @old_mrn;
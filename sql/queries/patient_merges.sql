@@ -0,0 +1,38 @@
+-- name: CreatePatientMerge :one
+INSERT INTO patient_merges (
+    message_id,
+    event,
+    survivor_mrn,
+    prior_mrn,
+    survivor_patient_id,
+    survivor_mrn_id,
+    prior_patient_id,
+    prior_mrn_id,
+    renamed,
+    exam_ids,
+    visit_ids,
+    mrn_ids,
+    repointed_exam_ids,
+    repointed_visit_ids
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING id;
+
+-- name: GetPatientMerge :one
+SELECT *
+FROM patient_merges
+WHERE id = $1;
+
+-- name: ListPatientMerges :many
+SELECT *
+FROM patient_merges
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: SetPatientMergeUnmerged :execrows
+UPDATE patient_merges
+SET unmerged_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND unmerged_at IS NULL;
@@ -98,3 +98,20 @@ SET
     cell_phone = $13
 WHERE id = $1
 RETURNING *;
+
+-- name: UpdatePatientDemographics :exec
+UPDATE patients
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    first_name = COALESCE(NULLIF(@first_name::text, ''), first_name),
+    last_name = COALESCE(NULLIF(@last_name::text, ''), last_name),
+    middle_name = COALESCE(NULLIF(@middle_name::text, ''), middle_name),
+    suffix = COALESCE(NULLIF(@suffix::text, ''), suffix),
+    prefix = COALESCE(NULLIF(@prefix::text, ''), prefix),
+    degree = COALESCE(NULLIF(@degree::text, ''), degree),
+    dob = COALESCE(@dob::date, dob),
+    sex = COALESCE(NULLIF(@sex::text, ''), sex),
+    home_phone = COALESCE(NULLIF(@home_phone::text, ''), home_phone),
+    work_phone = COALESCE(NULLIF(@work_phone::text, ''), work_phone),
+    message_id = @message_id
+WHERE id = @id;
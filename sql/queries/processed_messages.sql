@@ -0,0 +1,41 @@
+-- name: CreateProcessedMessages :exec
+INSERT INTO processed_messages (
+    dedupe_key,
+    pubsub_message_id,
+    store_path,
+    control_id,
+    status_code,
+    error_category,
+    response
+)
+SELECT
+    unnest(@dedupe_keys::text[]),
+    @pubsub_message_id::text,
+    @store_path::text,
+    @control_id::text,
+    @status_code::int,
+    @error_category::text,
+    @response::bytea
+ON CONFLICT (dedupe_key) DO UPDATE
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    pubsub_message_id = EXCLUDED.pubsub_message_id,
+    store_path = EXCLUDED.store_path,
+    control_id = EXCLUDED.control_id,
+    status_code = EXCLUDED.status_code,
+    error_category = EXCLUDED.error_category,
+    response = EXCLUDED.response,
+    processed_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteProcessedMessagesBefore :execrows
+DELETE FROM processed_messages
+WHERE processed_at < CURRENT_TIMESTAMP - (@window_seconds::bigint * INTERVAL '1 second');
+
+-- name: GetProcessedMessage :one
+SELECT *
+FROM processed_messages
+WHERE
+    dedupe_key = ANY(@dedupe_keys::text[])
+    AND processed_at >= CURRENT_TIMESTAMP - (@window_seconds::bigint * INTERVAL '1 second')
+ORDER BY processed_at DESC
+LIMIT 1;
@@ -0,0 +1,30 @@
+-- name: CreateRawMessage :exec
+INSERT INTO raw_messages (message_id, content_hash, encoding, size, data)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (message_id) DO NOTHING;
+
+-- name: GetRawMessageByMessageID :one
+SELECT *
+FROM raw_messages
+WHERE message_id = $1;
+
+-- name: ListRawMessagesForReprocess :many
+SELECT
+    r.message_id,
+    COALESCE(m.received_at, m.created_at)::timestamp AS received_at,
+    m.sending_application,
+    m.message_type,
+    m.control_id,
+    r.content_hash,
+    r.encoding,
+    r.data
+FROM raw_messages r
+JOIN messages m ON m.id = r.message_id
+WHERE
+    (COALESCE(m.received_at, m.created_at), r.message_id) > (@after_received_at::timestamp, @after_id::bigint)
+    AND COALESCE(m.received_at, m.created_at) >= @since::timestamp
+    AND COALESCE(m.received_at, m.created_at) < @until::timestamp
+    AND (@sending_app::text = '' OR m.sending_application = @sending_app::text)
+    AND (@message_type::text = '' OR m.message_type = @message_type::text)
+ORDER BY COALESCE(m.received_at, m.created_at), r.message_id
+LIMIT @page_size::int;
@@ -0,0 +1,34 @@
+-- name: CreateReportAttachment :exec
+INSERT INTO report_attachments (
+    report_id,
+    sequence,
+    value_type,
+    mime_type,
+    size,
+    content_hash,
+    blob_key,
+    data,
+    reference,
+    message_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (report_id, sequence) DO NOTHING;
+
+-- name: ListReportAttachments :many
+SELECT
+    id,
+    report_id,
+    sequence,
+    value_type,
+    mime_type,
+    size,
+    content_hash,
+    reference,
+    created_at
+FROM report_attachments
+WHERE report_id = @report_id
+ORDER BY sequence;
+
+-- name: GetReportAttachment :one
+SELECT * FROM report_attachments
+WHERE id = @id;
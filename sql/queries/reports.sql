@@ -6,17 +6,31 @@ WITH upsert as (
         impression,
         report_status,
         submitted_dt,
-	dictation_start,
-	dictation_end,
-        message_id
+        dictation_start,
+        dictation_end,
+        message_id,
+        sending_app,
+        accessions,
+        body_hash,
+        findings,
+        technique,
+        comparison,
+        history,
+        document_id,
+        sequence
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    ON CONFLICT (radiologist_id, impression, report_status, submitted_dt) DO UPDATE
-    SET
-        updated_at = CURRENT_TIMESTAMP,
-        body = COALESCE(EXCLUDED.body, reports.body)
-    WHERE
-        COALESCE(EXCLUDED.body, reports.body) IS DISTINCT FROM EXCLUDED.body
+    VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+        (
+            SELECT COALESCE(MAX(sequence), 0) + 1
+            FROM reports
+            WHERE
+                sending_app = $9
+                AND accessions = $10
+                AND report_status = $4
+        )
+    )
+    ON CONFLICT (sending_app, accessions, report_status, body_hash) DO NOTHING
     RETURNING id
 )
 
@@ -24,10 +38,10 @@ SELECT id FROM upsert
 UNION ALL
 SELECT id FROM reports
 WHERE
-    radiologist_id = $1
-    AND impression = $3
+    sending_app = $9
+    AND accessions = $10
     AND report_status = $4
-    AND submitted_dt = $5;
+    AND body_hash = $11;
 
 -- name: GetReportById :one
 SELECT * FROM reports
@@ -45,7 +59,77 @@ FROM reports;
 SELECT *
 FROM reports
 WHERE
-    radiologist_id = $1
-    AND impression = $2
+    sending_app = $1
+    AND accessions = $2
     AND report_status = $3
-    AND submitted_dt = $4;
+    AND body_hash = $4;
+
+-- name: GetPreviousExamReportID :one
+SELECT r.id
+FROM exam_reports er
+JOIN reports r ON r.id = er.report_id
+WHERE
+    er.exam_id = @exam_id
+    AND (r.submitted_dt, r.id) < (@submitted_dt::timestamp, @report_id::bigint)
+ORDER BY r.submitted_dt DESC, r.id DESC
+LIMIT 1;
+
+-- name: LinkPreviousReport :exec
+UPDATE reports
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    previous_report_id = @previous_report_id
+WHERE
+    id = @id
+    AND previous_report_id IS NULL;
+
+-- name: ListExamReportHistory :many
+SELECT
+    r.id,
+    r.previous_report_id,
+    r.report_status,
+    r.submitted_dt,
+    r.dictation_start,
+    r.dictation_end,
+    r.impression,
+    r.findings,
+    r.technique,
+    r.comparison,
+    r.history,
+    r.body,
+    r.obsolete,
+    COALESCE(p.first_name, '')::text AS radiologist_first_name,
+    COALESCE(p.last_name, '')::text AS radiologist_last_name,
+    er.role,
+    er.sequence,
+    (
+        NOT r.obsolete
+        AND (
+            er.role = 'addendum'
+            OR NOT EXISTS (
+                SELECT 1
+                FROM exam_reports later
+                JOIN reports lr ON lr.id = later.report_id
+                WHERE
+                    later.exam_id = er.exam_id
+                    AND (later.role = 'prelim') = (er.role = 'prelim')
+                    AND later.role <> 'addendum'
+                    AND (lr.submitted_dt, lr.id) > (r.submitted_dt, r.id)
+            )
+        )
+    )::boolean AS current
+FROM exam_reports er
+JOIN reports r ON r.id = er.report_id
+LEFT JOIN physicians p ON p.id = r.radiologist_id
+WHERE er.exam_id = @exam_id
+ORDER BY r.submitted_dt, r.id;
+
+-- name: MarkDocumentObsolete :exec
+UPDATE reports
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    obsolete = true
+WHERE
+    sending_app = @sending_app
+    AND document_id = @document_id
+    AND id <> @except_id;
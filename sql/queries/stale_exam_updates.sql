@@ -0,0 +1,18 @@
+-- name: CreateStaleExamUpdate :exec
+INSERT INTO stale_exam_updates (
+    exam_id,
+    message_id,
+    incoming_status,
+    incoming_event_dt,
+    current_status,
+    last_event_dt,
+    reason
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListStaleExamUpdates :many
+SELECT *
+FROM stale_exam_updates
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
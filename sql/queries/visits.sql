@@ -1,3 +1,51 @@
+-- name: CreateAdmission :one
+INSERT INTO visits (
+    site_id, -- $1
+    mrn_id, -- $2
+    number, -- $3
+    patient_type, -- $4
+    patient_class, -- $5
+    point_of_care, -- $6
+    room, -- $7
+    bed, -- $8
+    admit_dt, -- $9
+    discharge_dt, -- $10
+    attending_physician_id, -- $11
+    referring_physician_id, -- $12
+    message_id -- $13
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
+)
+ON CONFLICT (site_id, mrn_id, number) DO UPDATE
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    patient_type = CASE
+        WHEN COALESCE(EXCLUDED.patient_class, '') = '' THEN visits.patient_type
+        ELSE EXCLUDED.patient_type
+    END,
+    patient_class = COALESCE(NULLIF(EXCLUDED.patient_class, ''), visits.patient_class),
+    point_of_care = COALESCE(NULLIF(EXCLUDED.point_of_care, ''), visits.point_of_care),
+    room = COALESCE(NULLIF(EXCLUDED.room, ''), visits.room),
+    bed = COALESCE(NULLIF(EXCLUDED.bed, ''), visits.bed),
+    admit_dt = COALESCE(EXCLUDED.admit_dt, visits.admit_dt),
+    discharge_dt = COALESCE(EXCLUDED.discharge_dt, visits.discharge_dt),
+    attending_physician_id = COALESCE(EXCLUDED.attending_physician_id, visits.attending_physician_id),
+    referring_physician_id = COALESCE(EXCLUDED.referring_physician_id, visits.referring_physician_id)
+RETURNING id;
+
 -- name: CreateVisit :one
 WITH upsert AS (
     INSERT INTO visits (
@@ -77,3 +125,28 @@ SET
     patient_type = $5
 WHERE id = $1
 RETURNING *;
+
+-- name: MoveVisitsToMrn :many
+UPDATE visits AS v
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = @to_mrn_id
+WHERE
+    v.mrn_id = @from_mrn_id
+    AND NOT EXISTS (
+        SELECT 1 FROM visits AS w
+        WHERE
+            w.site_id = v.site_id
+            AND w.mrn_id = @to_mrn_id
+            AND w.number = v.number
+    )
+RETURNING v.id;
+
+-- name: MoveVisitsToMrnByIds :execrows
+UPDATE visits
+SET
+    updated_at = CURRENT_TIMESTAMP,
+    mrn_id = @to_mrn_id
+WHERE
+    id = ANY(@ids::bigint[])
+    AND mrn_id = @from_mrn_id;
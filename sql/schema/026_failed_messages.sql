@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS failed_messages (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    store_path TEXT NOT NULL,
+    raw BYTEA NOT NULL,
+    raw_hash TEXT NOT NULL,
+    control_id TEXT NOT NULL,
+    error_category TEXT NOT NULL,
+    error_message TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'pending'
+);
+
+-- a message that keeps failing bumps its pending entry instead of adding rows
+CREATE UNIQUE INDEX failed_messages_raw_hash_pending_idx ON failed_messages(raw_hash) WHERE status = 'pending';
+CREATE INDEX failed_messages_status_idx ON failed_messages(status ASC);
+CREATE INDEX failed_messages_control_id_idx ON failed_messages(control_id ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS failed_messages;
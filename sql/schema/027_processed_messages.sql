@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS processed_messages (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dedupe_key TEXT NOT NULL UNIQUE,
+    pubsub_message_id TEXT NOT NULL,
+    store_path TEXT NOT NULL,
+    control_id TEXT NOT NULL,
+    status_code INT NOT NULL,
+    error_category TEXT NOT NULL,
+    response BYTEA NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX processed_messages_processed_at_idx ON processed_messages(processed_at ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS processed_messages;
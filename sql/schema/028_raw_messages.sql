@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS raw_messages (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    message_id BIGINT NOT NULL UNIQUE REFERENCES messages(id) ON DELETE CASCADE,
+    content_hash TEXT NOT NULL,
+    encoding TEXT NOT NULL,
+    size INT NOT NULL,
+    data BYTEA NOT NULL
+);
+
+CREATE INDEX raw_messages_content_hash_idx ON raw_messages(content_hash ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS raw_messages;
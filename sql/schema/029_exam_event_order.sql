@@ -0,0 +1,20 @@
+-- +goose Up
+-- event time (ORC-9, else MSH-7) of the last message applied to the exam
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS last_event_dt TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS stale_exam_updates (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    exam_id BIGINT NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    incoming_status TEXT NOT NULL,
+    incoming_event_dt TIMESTAMP NOT NULL,
+    current_status TEXT NOT NULL,
+    last_event_dt TIMESTAMP NOT NULL
+);
+
+CREATE INDEX stale_exam_updates_exam_id_idx ON stale_exam_updates(exam_id ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS stale_exam_updates;
+ALTER TABLE exams DROP COLUMN IF EXISTS last_event_dt;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS exam_status_history (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    exam_id BIGINT NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+    old_status TEXT,
+    new_status TEXT NOT NULL,
+    event_dt TIMESTAMP,
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    source_app TEXT NOT NULL
+);
+
+CREATE INDEX exam_status_history_exam_id_idx ON exam_status_history(exam_id ASC, event_dt ASC);
+
+-- seed each existing exam with the status it has now
+INSERT INTO exam_status_history (exam_id, new_status, event_dt, message_id, source_app)
+SELECT
+    e.id,
+    e.current_status,
+    COALESCE(e.last_event_dt, e.updated_at),
+    e.message_id,
+    COALESCE(m.sending_application, '')
+FROM exams AS e
+LEFT JOIN messages AS m ON e.message_id = m.id;
+
+-- +goose Down
+DROP TABLE IF EXISTS exam_status_history;
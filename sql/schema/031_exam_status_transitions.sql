@@ -0,0 +1,36 @@
+-- +goose Up
+-- forcing CM from end_exam_dt now happens in the application, along with the
+-- rest of the status transition rules
+DROP TRIGGER IF EXISTS ensure_cm_on_end_dt ON exams;
+DROP FUNCTION IF EXISTS set_current_status_cm();
+
+-- stale_exam_updates now also holds status changes rejected as illegal
+ALTER TABLE stale_exam_updates ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT 'stale';
+-- either may be unknown for an illegal transition on an older exam
+ALTER TABLE stale_exam_updates ALTER COLUMN incoming_event_dt DROP NOT NULL;
+ALTER TABLE stale_exam_updates ALTER COLUMN last_event_dt DROP NOT NULL;
+
+-- +goose Down
+DELETE FROM stale_exam_updates WHERE incoming_event_dt IS NULL OR last_event_dt IS NULL;
+ALTER TABLE stale_exam_updates ALTER COLUMN last_event_dt SET NOT NULL;
+ALTER TABLE stale_exam_updates ALTER COLUMN incoming_event_dt SET NOT NULL;
+ALTER TABLE stale_exam_updates DROP COLUMN IF EXISTS reason;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION set_current_status_cm()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.end_exam_dt IS NOT NULL THEN
+    NEW.current_status := 'CM';
+  ELSIF (TG_OP = 'UPDATE' AND OLD.end_exam_dt IS NOT NULL) THEN
+    NEW.current_status := 'CM';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER ensure_cm_on_end_dt
+BEFORE UPDATE ON exams
+FOR EACH ROW
+EXECUTE FUNCTION set_current_status_cm();
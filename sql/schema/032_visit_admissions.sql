@@ -0,0 +1,23 @@
+-- +goose Up
+-- details kept current by ADT A01/A03/A04/A08
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS patient_class TEXT;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS point_of_care TEXT;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS room TEXT;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS bed TEXT;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS admit_dt TIMESTAMP;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS discharge_dt TIMESTAMP;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS attending_physician_id BIGINT REFERENCES physicians(id) ON DELETE SET NULL;
+ALTER TABLE visits ADD COLUMN IF NOT EXISTS referring_physician_id BIGINT REFERENCES physicians(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS mrns_site_id_mrn_idx ON mrns(site_id ASC, mrn ASC);
+
+-- +goose Down
+DROP INDEX IF EXISTS mrns_site_id_mrn_idx;
+ALTER TABLE visits DROP COLUMN IF EXISTS referring_physician_id;
+ALTER TABLE visits DROP COLUMN IF EXISTS attending_physician_id;
+ALTER TABLE visits DROP COLUMN IF EXISTS discharge_dt;
+ALTER TABLE visits DROP COLUMN IF EXISTS admit_dt;
+ALTER TABLE visits DROP COLUMN IF EXISTS bed;
+ALTER TABLE visits DROP COLUMN IF EXISTS room;
+ALTER TABLE visits DROP COLUMN IF EXISTS point_of_care;
+ALTER TABLE visits DROP COLUMN IF EXISTS patient_class;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- merge/link events (ADT A18/A24/A34/A40) and what they moved, so they can
+-- be audited and undone
+CREATE TABLE IF NOT EXISTS patient_merges (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    event TEXT NOT NULL,
+    survivor_mrn TEXT NOT NULL,
+    prior_mrn TEXT NOT NULL,
+    survivor_patient_id BIGINT REFERENCES patients(id) ON DELETE SET NULL,
+    survivor_mrn_id BIGINT REFERENCES mrns(id) ON DELETE SET NULL,
+    prior_patient_id BIGINT REFERENCES patients(id) ON DELETE SET NULL,
+    prior_mrn_id BIGINT REFERENCES mrns(id) ON DELETE SET NULL,
+    renamed BOOLEAN NOT NULL DEFAULT FALSE,
+    exam_ids BIGINT[] NOT NULL DEFAULT '{}',
+    visit_ids BIGINT[] NOT NULL DEFAULT '{}',
+    mrn_ids BIGINT[] NOT NULL DEFAULT '{}',
+    unmerged_at TIMESTAMP
+);
+
+CREATE INDEX patient_merges_survivor_patient_id_idx ON patient_merges(survivor_patient_id ASC);
+CREATE INDEX patient_merges_prior_patient_id_idx ON patient_merges(prior_patient_id ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_merges;
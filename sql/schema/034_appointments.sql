@@ -0,0 +1,30 @@
+-- +goose Up
+-- appointments from SIU S12-S15/S26; sending_app is MSH-5, as for exams
+CREATE TABLE IF NOT EXISTS appointments (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    site_id INT REFERENCES sites(id) ON DELETE CASCADE,
+    mrn_id BIGINT REFERENCES mrns(id) ON DELETE SET NULL,
+    exam_id BIGINT REFERENCES exams(id) ON DELETE SET NULL,
+    sending_app TEXT NOT NULL,
+    appointment_id TEXT NOT NULL,
+    accession TEXT NOT NULL DEFAULT '',
+    start_dt TIMESTAMP,
+    duration_minutes INT,
+    resource TEXT,
+    room TEXT,
+    status TEXT NOT NULL,
+    no_show BOOLEAN NOT NULL DEFAULT FALSE,
+    last_event TEXT NOT NULL,
+    last_event_dt TIMESTAMP
+);
+
+ALTER TABLE appointments ADD CONSTRAINT appointments_sending_app_appointment_id_unique UNIQUE (sending_app, appointment_id);
+CREATE INDEX appointments_sending_app_accession_idx ON appointments(sending_app ASC, accession ASC);
+CREATE INDEX appointments_start_dt_idx ON appointments(start_dt ASC);
+CREATE INDEX appointments_exam_id_idx ON appointments(exam_id ASC);
+
+-- +goose Down
+DROP TABLE IF EXISTS appointments;
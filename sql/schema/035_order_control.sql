@@ -0,0 +1,9 @@
+-- +goose Up
+-- the accession an RP/RU order replaced (ORC-8), and the study instance UID
+-- from a v2.5 OMI's IPC segment
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS replaced_accession TEXT;
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS study_instance_uid TEXT;
+
+-- +goose Down
+ALTER TABLE exams DROP COLUMN IF EXISTS study_instance_uid;
+ALTER TABLE exams DROP COLUMN IF EXISTS replaced_accession;
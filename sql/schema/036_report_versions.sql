@@ -0,0 +1,10 @@
+-- +goose Up
+-- each report points at the one it revises (a final its prelim, a corrected
+-- final the final, an addendum whatever came before it) so an exam's reports
+-- can be walked as a version chain
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS previous_report_id BIGINT REFERENCES reports(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS reports_previous_report_id_idx ON reports(previous_report_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS reports_previous_report_id_idx;
+ALTER TABLE reports DROP COLUMN IF EXISTS previous_report_id;
@@ -0,0 +1,85 @@
+-- +goose Up
+-- which reports belong to which exams: a report can cover several
+-- accessions, and an exam collects a prelim, finals, corrections and any
+-- number of addenda. sequence counts an exam's reports in each role, so the
+-- second addendum is (addendum, 2).
+CREATE TABLE IF NOT EXISTS exam_reports (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    exam_id BIGINT NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+    report_id BIGINT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    sequence INT NOT NULL,
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL
+);
+
+ALTER TABLE exam_reports ADD CONSTRAINT exam_reports_exam_id_report_id_unique UNIQUE (exam_id, report_id);
+ALTER TABLE exam_reports ADD CONSTRAINT exam_reports_exam_id_role_sequence_unique UNIQUE (exam_id, role, sequence);
+CREATE INDEX exam_reports_report_id_idx ON exam_reports(report_id ASC);
+
+-- backfill from the report columns, and the reports those revised
+WITH RECURSIVE attached AS (
+    SELECT id AS exam_id, prelim_report_id AS report_id FROM exams WHERE prelim_report_id IS NOT NULL
+    UNION
+    SELECT id, final_report_id FROM exams WHERE final_report_id IS NOT NULL
+    UNION
+    SELECT id, addendum_report_id FROM exams WHERE addendum_report_id IS NOT NULL
+    UNION
+    SELECT a.exam_id, r.previous_report_id
+    FROM attached a
+    JOIN reports r ON r.id = a.report_id
+    WHERE r.previous_report_id IS NOT NULL
+),
+roles AS (
+    SELECT
+        a.exam_id,
+        a.report_id,
+        r.message_id,
+        r.submitted_dt,
+        CASE r.report_status
+            WHEN 'P' THEN 'prelim'
+            WHEN 'C' THEN 'corrected'
+            WHEN 'A' THEN 'addendum'
+            ELSE 'final'
+        END AS role
+    FROM attached a
+    JOIN reports r ON r.id = a.report_id
+)
+INSERT INTO exam_reports (exam_id, report_id, role, sequence, message_id)
+SELECT
+    exam_id,
+    report_id,
+    role,
+    ROW_NUMBER() OVER (PARTITION BY exam_id, role ORDER BY submitted_dt, report_id),
+    message_id
+FROM roles;
+
+ALTER TABLE exams DROP COLUMN IF EXISTS final_report_id;
+ALTER TABLE exams DROP COLUMN IF EXISTS addendum_report_id;
+ALTER TABLE exams DROP COLUMN IF EXISTS prelim_report_id;
+
+-- +goose Down
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS final_report_id BIGINT REFERENCES reports(id) ON DELETE CASCADE;
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS addendum_report_id BIGINT REFERENCES reports(id) ON DELETE CASCADE;
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS prelim_report_id BIGINT REFERENCES reports(id) ON DELETE CASCADE;
+
+UPDATE exams e
+SET
+    prelim_report_id = (
+        SELECT er.report_id FROM exam_reports er
+        WHERE er.exam_id = e.id AND er.role = 'prelim'
+        ORDER BY er.sequence DESC LIMIT 1
+    ),
+    final_report_id = (
+        SELECT er.report_id FROM exam_reports er
+        JOIN reports r ON r.id = er.report_id
+        WHERE er.exam_id = e.id AND er.role IN ('final', 'corrected')
+        ORDER BY r.submitted_dt DESC, r.id DESC LIMIT 1
+    ),
+    addendum_report_id = (
+        SELECT er.report_id FROM exam_reports er
+        WHERE er.exam_id = e.id AND er.role = 'addendum'
+        ORDER BY er.sequence DESC LIMIT 1
+    );
+
+DROP TABLE IF EXISTS exam_reports;
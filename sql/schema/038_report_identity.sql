@@ -0,0 +1,99 @@
+-- +goose Up
+-- a report is identified by the application it's for (MSH-5, as for exams),
+-- the accessions it covers, its result status and a hash of its body.
+-- sequence numbers the versions with the same app, accessions and status, so
+-- a resend finds the report it already saved and a revised body is the next
+-- version. This replaces the unique key on radiologist, impression, status
+-- and submitted time.
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS sending_app TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS accessions TEXT[];
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS body_hash TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS sequence INT;
+
+UPDATE reports r
+SET
+    sending_app = COALESCE(k.sending_app, m.receiving_application, ''),
+    accessions = COALESCE(k.accessions, '{}'),
+    body_hash = encode(sha256(convert_to(r.body, 'UTF8')), 'hex')
+FROM reports r2
+LEFT JOIN (
+    SELECT
+        er.report_id,
+        MIN(e.sending_app) AS sending_app,
+        array_agg(DISTINCT e.accession ORDER BY e.accession) AS accessions
+    FROM exam_reports er
+    JOIN exams e ON e.id = er.exam_id
+    GROUP BY er.report_id
+) k ON k.report_id = r2.id
+LEFT JOIN messages m ON m.id = r2.message_id
+WHERE r2.id = r.id;
+
+-- rows that are now the same report are merged into the oldest
+CREATE TEMPORARY TABLE report_duplicates AS
+SELECT id, survivor_id
+FROM (
+    SELECT
+        id,
+        MIN(id) OVER (PARTITION BY sending_app, accessions, report_status, body_hash) AS survivor_id
+    FROM reports
+) d
+WHERE id <> survivor_id;
+
+-- an exam keeps one link to the survivor: its own, else its first to a
+-- duplicate
+DELETE FROM exam_reports er
+USING report_duplicates d
+WHERE
+    er.report_id = d.id
+    AND EXISTS (
+        SELECT 1
+        FROM exam_reports s
+        LEFT JOIN report_duplicates sd ON sd.id = s.report_id
+        WHERE
+            s.exam_id = er.exam_id
+            AND COALESCE(sd.survivor_id, s.report_id) = d.survivor_id
+            AND (s.report_id = d.survivor_id OR s.id < er.id)
+    );
+UPDATE exam_reports er
+SET report_id = d.survivor_id
+FROM report_duplicates d
+WHERE er.report_id = d.id;
+UPDATE reports r
+SET previous_report_id = d.survivor_id
+FROM report_duplicates d
+WHERE r.previous_report_id = d.id;
+UPDATE reports
+SET previous_report_id = NULL
+WHERE previous_report_id = id;
+DELETE FROM reports r
+USING report_duplicates d
+WHERE r.id = d.id;
+DROP TABLE report_duplicates;
+
+UPDATE reports r
+SET sequence = v.sequence
+FROM (
+    SELECT
+        id,
+        ROW_NUMBER() OVER (PARTITION BY sending_app, accessions, report_status ORDER BY submitted_dt, id) AS sequence
+    FROM reports
+) v
+WHERE v.id = r.id;
+
+ALTER TABLE reports ALTER COLUMN sending_app SET NOT NULL;
+ALTER TABLE reports ALTER COLUMN accessions SET NOT NULL;
+ALTER TABLE reports ALTER COLUMN body_hash SET NOT NULL;
+ALTER TABLE reports ALTER COLUMN sequence SET NOT NULL;
+
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_radiologist_id_impression_status_submitted_unique;
+ALTER TABLE reports ADD CONSTRAINT reports_identity_unique UNIQUE (sending_app, accessions, report_status, body_hash);
+ALTER TABLE reports ADD CONSTRAINT reports_version_unique UNIQUE (sending_app, accessions, report_status, sequence);
+
+-- +goose Down
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_version_unique;
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_identity_unique;
+ALTER TABLE reports ADD CONSTRAINT reports_radiologist_id_impression_status_submitted_unique UNIQUE (radiologist_id, impression, report_status, submitted_dt);
+ALTER TABLE reports DROP COLUMN IF EXISTS sequence;
+ALTER TABLE reports DROP COLUMN IF EXISTS body_hash;
+ALTER TABLE reports DROP COLUMN IF EXISTS accessions;
+ALTER TABLE reports DROP COLUMN IF EXISTS sending_app;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- report sections parsed from OBX-3 identifiers or headers in the text;
+-- impression is already a column
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS findings TEXT NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS technique TEXT NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS comparison TEXT NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS history TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE reports DROP COLUMN IF EXISTS history;
+ALTER TABLE reports DROP COLUMN IF EXISTS comparison;
+ALTER TABLE reports DROP COLUMN IF EXISTS technique;
+ALTER TABLE reports DROP COLUMN IF EXISTS findings;
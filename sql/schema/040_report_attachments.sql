@@ -0,0 +1,23 @@
+-- +goose Up
+-- encapsulated documents (OBX ED, e.g. a PDF) and references to documents
+-- stored elsewhere (OBX RP) sent with a report. The contents are in data,
+-- or in the blob store under blob_key when one is configured.
+CREATE TABLE IF NOT EXISTS report_attachments (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    report_id BIGINT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    sequence INT NOT NULL,
+    value_type TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    content_hash TEXT NOT NULL DEFAULT '',
+    blob_key TEXT NOT NULL DEFAULT '',
+    data BYTEA,
+    reference TEXT NOT NULL DEFAULT '',
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL
+);
+
+ALTER TABLE report_attachments ADD CONSTRAINT report_attachments_report_id_sequence_unique UNIQUE (report_id, sequence);
+
+-- +goose Down
+DROP TABLE IF EXISTS report_attachments;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- reports sent as MDM documents: document_id is the sender's unique
+-- document number (TXA-12), and obsolete is set when the document is made
+-- obsolete or cancelled (TXA-19), or replaced by another (TXA-13)
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS document_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS obsolete BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS reports_sending_app_document_id_idx ON reports(sending_app, document_id) WHERE document_id <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS reports_sending_app_document_id_idx;
+ALTER TABLE reports DROP COLUMN IF EXISTS obsolete;
+ALTER TABLE reports DROP COLUMN IF EXISTS document_id;
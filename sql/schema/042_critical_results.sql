@@ -0,0 +1,23 @@
+-- +goose Up
+-- critical or unexpected findings detected in reports, one per exam and
+-- rule, with whether they've been notified and acknowledged
+CREATE TABLE IF NOT EXISTS critical_results (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    report_id BIGINT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    exam_id BIGINT NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+    radiologist_id BIGINT REFERENCES physicians(id) ON DELETE SET NULL,
+    rule TEXT NOT NULL,
+    matched TEXT NOT NULL,
+    notified_at TIMESTAMP,
+    notify_error TEXT NOT NULL DEFAULT '',
+    acknowledged_at TIMESTAMP,
+    acknowledged_by TEXT NOT NULL DEFAULT '',
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL
+);
+
+ALTER TABLE critical_results ADD CONSTRAINT critical_results_exam_id_rule_unique UNIQUE (exam_id, rule);
+CREATE INDEX critical_results_unacknowledged_idx ON critical_results(id) WHERE acknowledged_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS critical_results;
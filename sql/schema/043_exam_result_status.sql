@@ -0,0 +1,24 @@
+-- +goose Up
+-- the result status (OBR-25) an ORU carries is kept apart from the order
+-- status, so that results never move current_status or last_event_dt
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS result_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE exams ADD COLUMN IF NOT EXISTS result_event_dt TIMESTAMP;
+
+-- move result statuses already written over current_status to their own
+-- column, and put the order status they imply back
+UPDATE exams
+SET
+    result_status = current_status,
+    result_event_dt = last_event_dt,
+    current_status = CASE
+        WHEN end_exam_dt IS NOT NULL THEN 'CM'
+        WHEN current_status IN ('O', 'S') THEN 'SC'
+        WHEN current_status = 'I' THEN 'IP'
+        WHEN current_status = 'X' THEN 'CA'
+        ELSE 'CM'
+    END
+WHERE current_status IN ('O', 'S', 'I', 'R', 'P', 'F', 'C', 'X');
+
+-- +goose Down
+ALTER TABLE exams DROP COLUMN IF EXISTS result_event_dt;
+ALTER TABLE exams DROP COLUMN IF EXISTS result_status;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- exams a merge moved from a prior visit to the survivor's visit with the
+-- same number, and the visit each was on, so unmerge can put them back
+ALTER TABLE patient_merges ADD COLUMN IF NOT EXISTS repointed_exam_ids BIGINT[] NOT NULL DEFAULT '{}';
+ALTER TABLE patient_merges ADD COLUMN IF NOT EXISTS repointed_visit_ids BIGINT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE patient_merges DROP COLUMN IF EXISTS repointed_visit_ids;
+ALTER TABLE patient_merges DROP COLUMN IF EXISTS repointed_exam_ids;
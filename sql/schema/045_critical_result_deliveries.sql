@@ -0,0 +1,9 @@
+-- +goose Up
+-- which notifiers have a critical result, so a retry only goes to those that
+-- failed, and until when a worker has claimed it to send
+ALTER TABLE critical_results ADD COLUMN IF NOT EXISTS notified_by TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE critical_results ADD COLUMN IF NOT EXISTS notify_claimed_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE critical_results DROP COLUMN IF EXISTS notify_claimed_until;
+ALTER TABLE critical_results DROP COLUMN IF EXISTS notified_by;